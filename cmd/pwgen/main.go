@@ -0,0 +1,630 @@
+// Command pwgen gera os structs de protocolo do pacote pwapi a partir de protocol.yaml
+//
+// Para cada struct do esquema são gerados o tipo com as tags `pw` (utilizadas pelo decode) e os métodos
+// AppendPW e UnpackPW, que empacotam e desempacotam o struct sem reflexão. Os opcodes e o registro das
+// chamadas com RegisterProtocol e os perfis de versão do servidor também são gerados.
+//
+// Uso:
+//
+//	go run ./cmd/pwgen -in pwapi/protocol.yaml -out pwapi/protocol_gen.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Schema é o conteúdo de protocol.yaml
+type Schema struct {
+	Types     []NamedType `yaml:"types"`
+	Opcodes   []Opcode    `yaml:"opcodes"`
+	Structs   []Struct    `yaml:"structs"`
+	Protocols []Protocol  `yaml:"protocols"`
+	Profiles  []Profile   `yaml:"profiles"`
+}
+
+// NamedType é um tipo nomeado sobre um inteiro, ex: type UserID int
+type NamedType struct {
+	Name string `yaml:"name"`
+	Go   string `yaml:"go"`
+}
+
+type Opcode struct {
+	Name  string `yaml:"name"`
+	Value uint32 `yaml:"value"`
+}
+
+type Struct struct {
+	Name   string  `yaml:"name"`
+	Doc    string  `yaml:"doc"`
+	Prefix string  `yaml:"prefix"`
+	Fields []Field `yaml:"fields"`
+}
+
+type Field struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"`
+	Go      string `yaml:"go"`
+	Len     string `yaml:"len"`
+	Fixed   int    `yaml:"fixed"`
+	Comment string `yaml:"comment"`
+}
+
+type Protocol struct {
+	Name       string `yaml:"name"`
+	Opcode     string `yaml:"opcode"`
+	Backend    string `yaml:"backend"`
+	Request    string `yaml:"request"`
+	Response   string `yaml:"response"`
+	RespName   string `yaml:"respName"`
+	RespOpcode string `yaml:"respOpcode"`
+	RPC        bool   `yaml:"rpc"`
+}
+
+// Profile é um perfil de versão do servidor, com o byte de versão e os campos ausentes de cada struct
+type Profile struct {
+	Name     string              `yaml:"name"`
+	Versions map[string]int      `yaml:"versions"`
+	Omit     map[string][]string `yaml:"omit"`
+}
+
+// scalarWires são as representações de inteiros, com o tipo Go padrão e o método do encoder/decoder
+var scalarWires = map[string]struct {
+	goType string
+	method string
+	signed string // conversão para preservar o sinal ao atribuir em campos com sinal
+}{
+	"int8":    {"int8", "uint8", "int8"},
+	"uint8":   {"byte", "uint8", ""},
+	"byte":    {"byte", "uint8", ""},
+	"int16":   {"int16", "uint16", "int16"},
+	"uint16":  {"uint16", "uint16", ""},
+	"int32":   {"int", "uint32", "int32"},
+	"uint32":  {"uint32", "uint32", ""},
+	"int64":   {"int64", "uint64", "int64"},
+	"uint64":  {"uint64", "uint64", ""},
+	"float32": {"float32", "float32", ""},
+	"float64": {"float64", "float64", ""},
+	"cuint":   {"Cuint", "cuint", ""},
+}
+
+// defaultWires é a representação que o codec por reflexão utiliza para cada tipo Go sem tag
+var defaultWires = map[string]string{
+	"int8": "int8", "uint8": "uint8", "byte": "uint8", "int16": "int16", "uint16": "uint16",
+	"int": "int32", "int32": "int32", "uint": "uint32", "uint32": "uint32", "int64": "int64", "uint64": "uint64",
+	"float32": "float32", "float64": "float64", "Cuint": "cuint",
+}
+
+var unsignedGo = map[string]bool{"byte": true, "uint8": true, "uint16": true, "uint": true, "uint32": true,
+	"uint64": true, "Cuint": true}
+
+func main() {
+	in := flag.String("in", "protocol.yaml", "esquema do protocolo")
+	out := flag.String("out", "protocol_gen.go", "arquivo Go gerado")
+	flag.Parse()
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	code, err := Generate(data, *in)
+	if err != nil {
+		log.Fatalf("pwgen: %v", err)
+	}
+	if err := os.WriteFile(*out, code, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Generate gera o código Go do pacote pwapi a partir do conteúdo de protocol.yaml
+//
+// Parâmetros:
+//
+//	data: []byte - Conteúdo do esquema
+//	source: string - Nome do esquema, citado no cabeçalho do arquivo gerado
+//
+// Retorno:
+//
+//	[]byte - Código formatado com gofmt
+//	error - Erro no esquema, como tipos desconhecidos ou campos len inválidos
+func Generate(data []byte, source string) ([]byte, error) {
+	var s Schema
+	if err := yaml.UnmarshalStrict(data, &s); err != nil {
+		return nil, err
+	}
+
+	g := &generator{schema: s, named: map[string]string{}, structs: map[string]bool{}}
+	for _, t := range s.Types {
+		g.named[t.Name] = t.Go
+	}
+	for _, st := range s.Structs {
+		g.structs[st.Name] = true
+	}
+
+	g.printf("// Opcodes dos pacotes utilizados pelo pacote\n//\n// Observações:\n//\n")
+	g.printf("//\tMais informações sobre cada opcode e o formato dos pacotes em: http://pwdev.ru/index.php/Protocols\n")
+	g.printf("const (\n")
+	for _, op := range s.Opcodes {
+		g.printf("%s uint32 = 0x%X\n", op.Name, op.Value)
+	}
+	g.printf(")\n\n")
+
+	for _, t := range s.Types {
+		g.printf("type %s %s\n\n", t.Name, t.Go)
+	}
+
+	for _, st := range s.Structs {
+		if err := g.genStruct(st); err != nil {
+			return nil, fmt.Errorf("%s: %w", st.Name, err)
+		}
+	}
+
+	if err := g.genProtocols(); err != nil {
+		return nil, err
+	}
+	if err := g.genPrefixes(); err != nil {
+		return nil, err
+	}
+	if err := g.genProfiles(); err != nil {
+		return nil, err
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by pwgen from %s. DO NOT EDIT.\n\npackage pwapi\n\nimport (\n", baseName(source))
+	if g.usesFmt {
+		src.WriteString("\"fmt\"\n")
+	}
+	src.WriteString("\"reflect\"\n)\n\n")
+	src.Write(g.buf.Bytes())
+
+	code, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("código gerado inválido: %w\n%s", err, src.Bytes())
+	}
+	return code, nil
+}
+
+type generator struct {
+	schema  Schema
+	named   map[string]string // tipos nomeados e o seu tipo base
+	structs map[string]bool
+	usesFmt bool
+	buf     bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// goType retorna o tipo Go de um campo, informado em go: ou derivado da representação
+func (g *generator) goType(f Field) (string, error) {
+	if f.Go != "" {
+		return f.Go, nil
+	}
+	if strings.HasPrefix(f.Type, "[]") {
+		if strings.HasPrefix(f.Type[2:], "[]") {
+			return "", fmt.Errorf("campo %s: listas de listas não são suportadas", f.Name)
+		}
+		elem, err := g.goType(Field{Type: f.Type[2:]})
+		return "[]" + elem, err
+	}
+	if w, ok := scalarWires[f.Type]; ok {
+		return w.goType, nil
+	}
+	switch f.Type {
+	case "octets":
+		return "[]byte", nil
+	case "utf16", "string", "gbk":
+		return "string", nil
+	}
+	if g.structs[f.Type] {
+		return f.Type, nil
+	}
+	return "", fmt.Errorf("campo %s: tipo %q desconhecido", f.Name, f.Type)
+}
+
+// baseType retorna o tipo base de um tipo nomeado do esquema
+func (g *generator) baseType(t string) string {
+	if base, ok := g.named[t]; ok {
+		return base
+	}
+	return t
+}
+
+// tag retorna a tag `pw` necessária para o codec por reflexão ler o campo como o esquema descreve
+func (g *generator) tag(f Field, goType string) string {
+	var opts []string
+	switch {
+	case strings.HasPrefix(f.Type, "[]"):
+		if f.Len != "" {
+			opts = append(opts, "array", "len="+f.Len)
+		}
+	case f.Type == "utf16" || f.Type == "string" || f.Type == "gbk" || f.Type == "cuint":
+		opts = append(opts, strings.Replace(f.Type, "string", "utf16", 1))
+	case f.Type == "octets":
+		if goType == "string" {
+			opts = append(opts, "octets")
+		}
+	default:
+		if _, ok := scalarWires[f.Type]; ok && defaultWires[g.baseType(goType)] != strings.Replace(f.Type, "byte", "uint8", 1) {
+			opts = append(opts, f.Type)
+		}
+	}
+	if f.Fixed > 0 {
+		opts = append(opts, fmt.Sprintf("fixed=%d", f.Fixed))
+	}
+	if len(opts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("`pw:\"%s\"`", strings.Join(opts, ","))
+}
+
+func (g *generator) genStruct(st Struct) error {
+	lenFor := map[string]string{} // campo de tamanho -> slice
+	index := map[string]int{}
+	for i, f := range st.Fields {
+		index[f.Name] = i
+		if f.Len == "" {
+			continue
+		}
+		j, ok := index[f.Len]
+		if !ok {
+			return fmt.Errorf("campo %s: len=%s precisa ser um campo anterior", f.Name, f.Len)
+		}
+		if _, ok := scalarWires[st.Fields[j].Type]; !ok || !strings.HasPrefix(f.Type, "[]") {
+			return fmt.Errorf("campo %s: len=%s precisa ser inteiro e o campo uma lista", f.Name, f.Len)
+		}
+		lenFor[f.Len] = f.Name
+	}
+
+	goTypes := make([]string, len(st.Fields))
+	for i, f := range st.Fields {
+		t, err := g.goType(f)
+		if err != nil {
+			return err
+		}
+		goTypes[i] = t
+	}
+
+	if st.Doc != "" {
+		for _, line := range strings.Split(strings.TrimSpace(st.Doc), "\n") {
+			g.printf("// %s\n", line)
+		}
+	}
+	g.printf("type %s struct {\n", st.Name)
+	for i, f := range st.Fields {
+		g.printf("%s %s %s", f.Name, goTypes[i], g.tag(f, goTypes[i]))
+		if f.Comment != "" {
+			g.printf(" // %s", f.Comment)
+		}
+		g.printf("\n")
+	}
+	g.printf("}\n\n")
+
+	// Empacotamento
+	g.printf("// AppendPW acrescenta %s empacotado em b, implementando Packer\n", st.Name)
+	g.printf("func (v %s) AppendPW(b []byte) ([]byte, error) {\n", st.Name)
+	g.printf("e := encoder{buf: b}\nif err := (&v).encodePW(&e); err != nil {\nreturn b, prefixField(err, %q)\n}\n", st.Name)
+	g.printf("return e.buf, nil\n}\n\n")
+
+	g.printf("func (v *%s) encodePW(e *encoder) error {\n", st.Name)
+	for i, f := range st.Fields {
+		expr := "v." + f.Name
+		if slice, ok := lenFor[f.Name]; ok {
+			// Campos que carregam a quantidade de elementos de uma lista são preenchidos automaticamente
+			expr = "len(v." + slice + ")"
+		}
+		code, err := g.encodeField(f, goTypes[i], expr)
+		if err != nil {
+			return fmt.Errorf("campo %s: %w", f.Name, err)
+		}
+		g.printf("%s", code)
+	}
+	g.printf("return nil\n}\n\n")
+
+	// Desempacotamento
+	g.printf("// UnpackPW desempacota %s do início de data, implementando Unpacker\n", st.Name)
+	g.printf("func (v *%s) UnpackPW(data []byte) (int, error) {\n", st.Name)
+	g.printf("d := decoder{data: data}\nif err := v.decodePW(&d); err != nil {\nreturn d.off, prefixField(err, %q)\n}\n", st.Name)
+	g.printf("return d.off, nil\n}\n\n")
+
+	g.printf("func (v *%s) decodePW(d *decoder) error {\n", st.Name)
+	for i, f := range st.Fields {
+		code, err := g.decodeField(f, goTypes[i], "v."+f.Name)
+		if err != nil {
+			return fmt.Errorf("campo %s: %w", f.Name, err)
+		}
+		g.printf("%s", code)
+	}
+	g.printf("return nil\n}\n\n")
+	return nil
+}
+
+// encodeField gera o código que grava o campo expr, listas são gravadas elemento a elemento
+func (g *generator) encodeField(f Field, goType, expr string) (string, error) {
+	path := strconvQuote("." + f.Name)
+	if !strings.HasPrefix(f.Type, "[]") {
+		return g.encodeValue(f.Type, goType, expr, f.Fixed, path)
+	}
+
+	var b strings.Builder
+	if f.Len == "" {
+		fmt.Fprintf(&b, "e.cuint(uint32(len(%s)))\n", expr)
+	}
+	g.usesFmt = true
+	elem, err := g.encodeValue(f.Type[2:], goType[2:], expr+"[i]", 0, g.elemPath(f.Name))
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&b, "for i := range %s {\n%s}\n", expr, elem)
+	return b.String(), nil
+}
+
+// encodeValue gera o código que grava expr com a representação wire, path é a expressão do caminho nos erros
+func (g *generator) encodeValue(wire, goType, expr string, fixed int, path string) (string, error) {
+	if w, ok := scalarWires[wire]; ok {
+		arg := fmt.Sprintf("%s(%s)", w.method, expr)
+		if w.method == "cuint" {
+			arg = fmt.Sprintf("uint32(%s)", expr)
+		}
+		return fmt.Sprintf("e.%s(%s)\n", w.method, arg), nil
+	}
+
+	switch wire {
+	case "octets":
+		if goType == "string" {
+			expr = "[]byte(" + expr + ")"
+		}
+		return fmt.Sprintf("if err := e.octets(%s, %d); err != nil {\nreturn prefixField(err, %s)\n}\n", expr, fixed, path), nil
+	case "utf16", "string", "gbk":
+		return fmt.Sprintf("if err := e.text(%s, %s, %d); err != nil {\nreturn prefixField(err, %s)\n}\n",
+			expr, textWire(wire), fixed, path), nil
+	}
+	if g.structs[wire] {
+		return fmt.Sprintf("if err := %s.encodePW(e); err != nil {\nreturn prefixField(err, %s)\n}\n", expr, path), nil
+	}
+	return "", fmt.Errorf("tipo %q não suportado", wire)
+}
+
+// decodeField gera o código que lê o campo target, listas com len utilizam o campo de tamanho já lido
+func (g *generator) decodeField(f Field, goType, target string) (string, error) {
+	path := strconvQuote("." + f.Name)
+	if !strings.HasPrefix(f.Type, "[]") {
+		return g.decodeValue(f.Type, goType, target, f.Fixed, path)
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	if f.Len != "" {
+		fmt.Fprintf(&b, "n, err := d.count(int(v.%s))\n", f.Len)
+	} else {
+		fmt.Fprintf(&b, "n, err := d.cuint()\nif err == nil {\nn, err = d.count(n)\n}\n")
+	}
+	fmt.Fprintf(&b, "if err != nil {\nreturn prefixField(err, %s)\n}\n", path)
+	fmt.Fprintf(&b, "%s = make(%s, n)\n", target, goType)
+	g.usesFmt = true
+	elem, err := g.decodeValue(f.Type[2:], goType[2:], target+"[i]", 0, g.elemPath(f.Name))
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&b, "for i := range %s {\n%s}\n}\n", target, elem)
+	return b.String(), nil
+}
+
+// decodeValue gera o código que lê target com a representação wire, veja encodeValue
+//
+// Observações:
+//
+//	Inteiros seguem a conversão do codec por reflexão: representações com sinal são estendidas em campos com sinal
+func (g *generator) decodeValue(wire, goType, target string, fixed int, path string) (string, error) {
+	check := fmt.Sprintf("if err != nil {\nreturn prefixField(err, %s)\n}\n", path)
+
+	if w, ok := scalarWires[wire]; ok {
+		value := fmt.Sprintf("%s(x)", goType)
+		if w.signed != "" && !unsignedGo[g.baseType(goType)] {
+			value = fmt.Sprintf("%s(%s(x))", goType, w.signed)
+		}
+		return fmt.Sprintf("{\nx, err := d.%s()\n%s%s = %s\n}\n", w.method, check, target, value), nil
+	}
+
+	switch wire {
+	case "octets":
+		value := "x"
+		if goType == "string" {
+			value = "string(x)"
+		}
+		return fmt.Sprintf("{\nx, err := d.octets(%d)\n%s%s = %s\n}\n", fixed, check, target, value), nil
+	case "utf16", "string", "gbk":
+		return fmt.Sprintf("{\nx, err := d.text(%s, %d)\n%s%s = x\n}\n", textWire(wire), fixed, check, target), nil
+	}
+	if g.structs[wire] {
+		return fmt.Sprintf("if err := %s.decodePW(d); err != nil {\nreturn prefixField(err, %s)\n}\n", target, path), nil
+	}
+	return "", fmt.Errorf("tipo %q não suportado", wire)
+}
+
+// elemPath retorna a expressão do caminho de um elemento de lista nos erros, ex: .Forbid[0]
+func (g *generator) elemPath(name string) string {
+	return fmt.Sprintf("fmt.Sprintf(%s, i)", strconvQuote("."+name+"[%d]"))
+}
+
+func textWire(wire string) string {
+	if wire == "gbk" {
+		return "wireGBK"
+	}
+	return "wireUTF16"
+}
+
+func (g *generator) genProtocols() error {
+	opcodes := map[string]bool{}
+	for _, op := range g.schema.Opcodes {
+		opcodes[op.Name] = true
+	}
+
+	g.printf("func init() {\nfor _, p := range []Protocol{\n")
+	for _, p := range g.schema.Protocols {
+		if !g.structs[p.Request] || (p.Response != "" && !g.structs[p.Response]) {
+			return fmt.Errorf("protocolo %s: request e response precisam ser structs do esquema", p.Name)
+		}
+		if !opcodes[p.Opcode] || (p.RespOpcode != "" && !opcodes[p.RespOpcode]) {
+			return fmt.Errorf("protocolo %s: opcode desconhecido", p.Name)
+		}
+
+		g.printf("{Name: %q, Opcode: %s, Backend: %q, Request: reflect.TypeOf(%s{})", p.Name, p.Opcode, p.Backend, p.Request)
+		if p.Response != "" {
+			g.printf(",\nResponse: reflect.TypeOf(%s{}), RespName: %q, RespOpcode: %s", p.Response, p.RespName, p.RespOpcode)
+		}
+		if p.RPC {
+			g.printf(", RPC: true")
+		}
+		g.printf("},\n")
+	}
+	g.printf("} {\nRegisterProtocol(p)\n}\n}\n")
+	return nil
+}
+
+func (g *generator) genPrefixes() error {
+	byName := map[string]Struct{}
+	for _, st := range g.schema.Structs {
+		byName[st.Name] = st
+	}
+
+	g.printf("\n// prefixes associa os structs declarados com prefix ao struct completo\n")
+	g.printf("var prefixes = map[reflect.Type]reflect.Type{\n")
+	for _, st := range g.schema.Structs {
+		if st.Prefix == "" {
+			continue
+		}
+		base, ok := byName[st.Prefix]
+		if !ok || base.Prefix != "" || len(st.Fields) > len(base.Fields) {
+			return fmt.Errorf("%s: prefix %s precisa ser um struct do esquema com mais campos", st.Name, st.Prefix)
+		}
+		for i, f := range st.Fields {
+			b := base.Fields[i]
+			f.Comment, b.Comment = "", ""
+			if f != b {
+				return fmt.Errorf("%s: campo %s difere do campo %d de %s", st.Name, f.Name, i, st.Prefix)
+			}
+		}
+		g.printf("reflect.TypeOf(%s{}): reflect.TypeOf(%s{}),\n", st.Name, st.Prefix)
+	}
+	g.printf("}\n")
+	return nil
+}
+
+func (g *generator) genProfiles() error {
+	if len(g.schema.Profiles) == 0 {
+		g.printf("\nvar profiles []Profile\n")
+		return nil
+	}
+
+	byName := map[string]Struct{}
+	for _, st := range g.schema.Structs {
+		byName[st.Name] = st
+	}
+
+	g.printf("\nvar profiles = []Profile{\n")
+	for _, p := range g.schema.Profiles {
+		if p.Name == "" || len(p.Versions) == 0 {
+			return fmt.Errorf("perfil %q: name e versions são obrigatórios", p.Name)
+		}
+		for name, version := range p.Versions {
+			st, ok := byName[name]
+			if !ok || len(st.Fields) == 0 || (st.Fields[0].Type != "byte" && st.Fields[0].Type != "uint8") {
+				return fmt.Errorf("perfil %s: %s precisa ser um struct do esquema começando pelo byte de versão", p.Name, name)
+			}
+			if version < 0 || version > 0xFF {
+				return fmt.Errorf("perfil %s: versão %d de %s não cabe em um byte", p.Name, version, name)
+			}
+		}
+		for name, omit := range p.Omit {
+			if _, ok := p.Versions[name]; !ok {
+				return fmt.Errorf("perfil %s: omit de %s sem versão em versions", p.Name, name)
+			}
+			for _, field := range omit {
+				if err := checkOmit(byName[name], field, omit); err != nil {
+					return fmt.Errorf("perfil %s: %w", p.Name, err)
+				}
+				// Os prefixos são lidos da mesma forma em todas as versões
+				for _, st := range g.schema.Structs {
+					for _, f := range st.Fields {
+						if st.Prefix == name && f.Name == field {
+							return fmt.Errorf("perfil %s: %s.%s faz parte do prefixo %s", p.Name, name, field, st.Name)
+						}
+					}
+				}
+			}
+		}
+
+		g.printf("{Name: %q, Versions: map[string]byte{", p.Name)
+		for _, name := range sortedKeys(p.Versions) {
+			g.printf("%q: %d, ", name, p.Versions[name])
+		}
+		g.printf("}")
+		if len(p.Omit) > 0 {
+			g.printf(",\nOmit: map[string][]string{\n")
+			for _, name := range sortedKeys(p.Omit) {
+				g.printf("%q: {", name)
+				for _, field := range p.Omit[name] {
+					g.printf("%q, ", field)
+				}
+				g.printf("},\n")
+			}
+			g.printf("}")
+		}
+		g.printf("},\n")
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// checkOmit verifica se field existe no struct e se não separa uma lista do seu campo de tamanho
+func checkOmit(st Struct, field string, omit []string) error {
+	omitted := map[string]bool{}
+	for _, f := range omit {
+		omitted[f] = true
+	}
+	for i, f := range st.Fields {
+		if f.Name != field {
+			continue
+		}
+		if i == 0 {
+			return fmt.Errorf("%s.%s é o byte de versão e não pode ser omitido", st.Name, field)
+		}
+		for _, other := range st.Fields {
+			if (other.Len == field && !omitted[other.Name]) || (f.Len != "" && !omitted[f.Len]) {
+				return fmt.Errorf("%s.%s precisa ser omitido junto com o seu campo len", st.Name, field)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("campo %s.%s não existe", st.Name, field)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func strconvQuote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGeneratedFileUpToDate(t *testing.T) {
+	schema, err := os.ReadFile("../../pwapi/protocol.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Generate(schema, "protocol.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../../pwapi/protocol_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("pwapi/protocol_gen.go desatualizado, execute go generate ./pwapi")
+	}
+}
+
+func TestGenerateRejectsInvalidSchema(t *testing.T) {
+	for schema, want := range map[string]string{
+		"structs: [{name: A, fields: [{name: X, type: int33}]}]":                                                         `tipo "int33" desconhecido`,
+		"structs: [{name: A, fields: [{name: L, type: '[]A', len: N}, {name: N, type: cuint}]}]":                         "precisa ser um campo anterior",
+		"structs: [{name: A, fields: [{name: X, typ: int32}]}]":                                                          "typ",
+		"structs: [{name: A, fields: [{name: V, type: byte}]}]\nprofiles: [{name: x, versions: {A: 1}, omit: {A: [Y]}}]": "A.Y não existe",
+		"structs: [{name: A, fields: [{name: V, type: int32}]}]\nprofiles: [{name: x, versions: {A: 1}}]":                "byte de versão",
+		"structs: [{name: A, fields: [{name: V, type: byte}]}, {name: B, prefix: A, fields: [{name: V, type: int8}]}]":   "difere",
+		"protocols: [{name: P, opcode: Op, request: B}]":                                                                 "precisam ser structs",
+	} {
+		if _, err := Generate([]byte(schema), "teste.yaml"); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Generate(%s) = %v, esperado erro com %q", schema, err, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"pwapi/pwapi"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// consultas são os subcomandos que consultam o servidor configurado em config.yaml sem realizar sorteio
+var consultas = map[string]func(ctx context.Context, client *pwapi.Client, args []string, stdout io.Writer) int{
+	"roleid":  consultarRoleID,
+	"roles":   consultarRoles,
+	"inspect": consultarInspect,
+}
+
+//consultar implementa os subcomandos de consulta
+//
+//Parâmetros:
+//	comando: string - Nome do subcomando, uma das chaves de consultas
+//	args: []string - Argumentos após o nome do subcomando
+//	stdout: io.Writer - Saída do resultado da consulta
+//
+//Retorno:
+//	int - Código de saída do programa, 1 caso alguma consulta falhe
+//
+//Exemplos:
+//	./sorteio roleid Fulano "Ciclano da Silva"
+//	./sorteio roles 32
+//	./sorteio inspect Fulano
+
+func consultar(comando string, args []string, stdout io.Writer) int {
+	client, err := pwapi.NewClient(pwapi.WithConfig(pwapi.AppConfig))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao criar o cliente: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	return consultas[comando](context.Background(), client, args, stdout)
+}
+
+// consultarRoleID escreve o ID de cada personagem informado pelo nome
+func consultarRoleID(ctx context.Context, client *pwapi.Client, args []string, stdout io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "uso: sorteio roleid <nome> [nome...]")
+		return 2
+	}
+
+	codigo := 0
+	for _, nome := range args {
+		roleID, err := client.RoleIDByName(ctx, nome)
+		switch {
+		case errors.Is(err, pwapi.ErrNotFound):
+			fmt.Fprintf(os.Stderr, "Personagem %q não encontrado\n", nome)
+			codigo = 1
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Erro ao buscar o personagem %q: %v\n", nome, err)
+			codigo = 1
+		default:
+			fmt.Fprintf(stdout, "%d\t%s\n", roleID.RoleID, nome)
+		}
+	}
+	return codigo
+}
+
+// consultarRoles escreve o ID e o nome dos personagens de cada conta informada
+func consultarRoles(ctx context.Context, client *pwapi.Client, args []string, stdout io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "uso: sorteio roles <userid> [userid...]")
+		return 2
+	}
+
+	codigo := 0
+	for _, arg := range args {
+		userID, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "UserID inválido: %q\n", arg)
+			return 2
+		}
+
+		roles, err := client.UserRoles(ctx, pwapi.UserID(userID))
+		switch {
+		case errors.Is(err, pwapi.ErrNotFound):
+			fmt.Fprintf(os.Stderr, "Conta %d não encontrada\n", userID)
+			codigo = 1
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Erro ao buscar os personagens da conta %d: %v\n", userID, err)
+			codigo = 1
+		default:
+			for _, role := range roles {
+				fmt.Fprintf(stdout, "%d\t%d\t%s\n", userID, role.RoleID.RoleID, role.Name)
+			}
+		}
+	}
+	return codigo
+}
+
+// consultarInspect escreve o inventário, o equipamento e o armazém de um personagem informado pelo ID ou nome
+func consultarInspect(ctx context.Context, client *pwapi.Client, args []string, stdout io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "uso: sorteio inspect <roleid | nome>")
+		return 2
+	}
+
+	// Nomes formados apenas por dígitos são tratados como RoleID
+	roleID := pwapi.RoleID{}
+	if id, err := strconv.Atoi(args[0]); err == nil {
+		roleID.RoleID = id
+	} else if roleID, err = client.RoleIDByName(ctx, args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao buscar o personagem %q: %v\n", args[0], err)
+		return 1
+	}
+
+	role, err := client.Role(ctx, roleID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao buscar o personagem %d: %v\n", roleID.RoleID, err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Personagem %d %s, conta %d, level %d, cultivo %d\n", role.Base.ID, role.Base.Name, role.Base.UserID,
+		role.Status.Level, role.Status.Level2)
+	escreverItens(stdout, fmt.Sprintf("Inventário (%d espaços, %d moedas)", role.Pocket.Capacity, role.Pocket.Money), role.Pocket.Items)
+	escreverItens(stdout, "Equipamento", role.Equipment.Items)
+	escreverItens(stdout, fmt.Sprintf("Armazém (%d espaços, %d moedas)", role.Storehouse.Capacity, role.Storehouse.Money), role.Storehouse.Items)
+	escreverItens(stdout, fmt.Sprintf("Armazém de roupas (%d espaços)", role.Storehouse.Size1), role.Storehouse.Dress)
+	escreverItens(stdout, fmt.Sprintf("Armazém de materiais (%d espaços)", role.Storehouse.Size2), role.Storehouse.Material)
+	return 0
+}
+
+// escreverItens escreve uma lista de itens com a posição, o ID, a quantidade, o proctype, a expiração e o Data em hexadecimal
+func escreverItens(stdout io.Writer, titulo string, itens []pwapi.GRoleInventory) {
+	fmt.Fprintf(stdout, "\n%s:\n", titulo)
+	if len(itens) == 0 {
+		fmt.Fprintln(stdout, "  (vazio)")
+		return
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Pos\tID\tQtd\tProcType\tExpira\tData")
+	for _, item := range itens {
+		expira := "-"
+		if item.ExpireDate > 0 {
+			expira = time.Unix(int64(item.ExpireDate), 0).Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "  %d\t%d\t%d/%d\t%d\t%s\t%s\n", item.Pos, item.ID, item.Count, item.MaxCount, item.ProcType, expira,
+			hex.EncodeToString(item.Data))
+	}
+	w.Flush()
+}
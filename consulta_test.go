@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"pwapi/pwapi"
+	"pwapi/pwapi/pwtest"
+)
+
+func TestConsultas(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{})
+	srv.AddOfflineRole(pwapi.RoleBase{ID: 3072, Name: "Fulano Alt", UserID: 32}, pwapi.RoleStatus{})
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	var saida bytes.Buffer
+	if codigo := consultarRoleID(ctx, client, []string{"Fulano Alt", "Beltrano"}, &saida); codigo != 1 {
+		t.Errorf("roleid com um nome inexistente retornou %d, esperado 1", codigo)
+	}
+	if got := saida.String(); got != "3072\tFulano Alt\n" {
+		t.Errorf("roleid escreveu %q", got)
+	}
+
+	saida.Reset()
+	if codigo := consultarRoles(ctx, client, []string{"32"}, &saida); codigo != 0 {
+		t.Errorf("roles retornou %d", codigo)
+	}
+	if got := saida.String(); got != "32\t1024\tFulano\n32\t3072\tFulano Alt\n" {
+		t.Errorf("roles escreveu %q", got)
+	}
+	if codigo := consultarRoles(ctx, client, []string{"abc"}, &saida); codigo != 2 {
+		t.Errorf("roles com UserID inválido retornou %d, esperado 2", codigo)
+	}
+
+	srv.SetRoleItems(pwapi.RoleID{RoleID: 1024}, pwapi.GRoleData{
+		Pocket:    pwapi.GRolePocket{Capacity: 32, Money: 1000, Items: []pwapi.Item{{ID: 7749, Pos: 3, Count: 5, MaxCount: 30, Data: []byte{0x13, 0x08}}}},
+		Equipment: pwapi.GRoleEquipment{Items: []pwapi.Item{{ID: 1234, Count: 1, MaxCount: 1}}},
+	})
+	for _, arg := range []string{"Fulano", "1024"} {
+		saida.Reset()
+		if codigo := consultarInspect(ctx, client, []string{arg}, &saida); codigo != 0 {
+			t.Fatalf("inspect %s retornou %d", arg, codigo)
+		}
+		for _, want := range []string{"Personagem 1024 Fulano, conta 32", "Inventário (32 espaços, 1000 moedas):", "7749", "5/30", "1308",
+			"Equipamento:", "1234", "Armazém (0 espaços, 0 moedas):\n  (vazio)"} {
+			if !strings.Contains(saida.String(), want) {
+				t.Errorf("inspect %s não contém %q:\n%s", arg, want, saida.String())
+			}
+		}
+	}
+	if codigo := consultarInspect(ctx, client, []string{"Beltrano"}, &saida); codigo != 1 {
+		t.Errorf("inspect de personagem inexistente retornou %d, esperado 1", codigo)
+	}
+}
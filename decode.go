@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"pwapi/pwapi"
+	"strings"
+)
+
+//decodificar implementa o subcomando decode
+//
+//Decodifica pacotes a partir de uma string hexadecimal (copiada do Wireshark ou dos exemplos do pwdev.ru),
+//de um arquivo com a string hexadecimal ou de um arquivo de captura gravado com a opção Captura
+//
+//Parâmetros:
+//	args: []string - Argumentos após o nome do subcomando
+//	stdout: io.Writer - Saída dos pacotes decodificados
+//
+//Retorno:
+//	int - Código de saída do programa, 1 caso algum pacote não possa ser decodificado por completo
+//
+//Exemplos:
+//	./sorteio decode 8bc5088000000100000400
+//	./sorteio decode -json -dir resp resposta.txt
+//	./sorteio decode captura.jsonl
+
+func decodificar(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	emJSON := fs.Bool("json", false, "escreve cada pacote como um objeto JSON por linha")
+	dir := fs.String("dir", "", "direção dos pacotes em hexadecimal: req ou resp, detectada pelo opcode quando vazia")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "uso: sorteio decode [-json] [-dir req|resp] <hex | arquivo>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dir != "" && *dir != pwapi.CaptureRequest && *dir != pwapi.CaptureResponse {
+		fs.Usage()
+		return 2
+	}
+
+	// Lê a entrada do argumento, de um arquivo ou da entrada padrão
+	var entrada []byte
+	switch {
+	case fs.NArg() == 0:
+		dados, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao ler a entrada padrão: %v\n", err)
+			return 1
+		}
+		entrada = dados
+	case fileExists(fs.Arg(0)):
+		dados, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao ler %s: %v\n", fs.Arg(0), err)
+			return 1
+		}
+		entrada = dados
+	default:
+		entrada = []byte(strings.Join(fs.Args(), ""))
+	}
+
+	escrever := func(d pwapi.Decoded) error {
+		if *emJSON {
+			linha, err := json.Marshal(d)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(stdout, "%s\n", linha)
+			return err
+		}
+		return d.WriteTree(stdout)
+	}
+
+	// Arquivos de captura possuem um registro JSON por linha
+	if bytes.HasPrefix(bytes.TrimSpace(entrada), []byte("{")) {
+		return decodificarCaptura(entrada, *emJSON, stdout, escrever)
+	}
+
+	data, err := hex.DecodeString(limparHex(string(entrada)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Hexadecimal inválido: %v\n", err)
+		return 1
+	}
+
+	// Um dump pode conter vários frames seguidos
+	codigo := 0
+	for len(data) > 0 {
+		decoded, rest, err := pwapi.DecodePacket(data, *dir)
+		if err != nil {
+			fmt.Fprintf(stdout, "!! cabeçalho inválido: %v\n", err)
+			fmt.Fprintf(stdout, "!! %d bytes restantes: %s\n", len(data), hex.EncodeToString(data))
+			return 1
+		}
+		if err := escrever(decoded); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao escrever o pacote: %v\n", err)
+			return 1
+		}
+		if decoded.Err != nil || len(decoded.Leftover) > 0 {
+			codigo = 1
+		}
+		data = rest
+	}
+	return codigo
+}
+
+// decodificarCaptura decodifica os frames de um arquivo de captura, cada frame com a direção gravada
+func decodificarCaptura(entrada []byte, emJSON bool, stdout io.Writer, escrever func(pwapi.Decoded) error) int {
+	replayer, err := pwapi.NewReplayer(bytes.NewReader(entrada))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao ler a captura: %v\n", err)
+		return 1
+	}
+
+	codigo := 0
+	for _, e := range replayer.Entries() {
+		if e.Dir == pwapi.CaptureMeta {
+			continue
+		}
+		frame, err := e.Frame()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro no registro de %s: %v\n", e.Time.Format("15:04:05.000"), err)
+			codigo = 1
+			continue
+		}
+
+		if !emJSON {
+			fmt.Fprintf(stdout, "\n%s %s\n", e.Time.Format("2006-01-02 15:04:05.000"), e.Backend)
+		}
+		decoded := pwapi.DecodeFrame(frame, e.Dir)
+		if err := escrever(decoded); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao escrever o pacote: %v\n", err)
+			return 1
+		}
+		if decoded.Known() && (decoded.Err != nil || len(decoded.Leftover) > 0) {
+			codigo = 1
+		}
+	}
+	return codigo
+}
+
+// limparHex remove espaços, quebras de linha, separadores e prefixos 0x de um dump hexadecimal
+func limparHex(s string) string {
+	s = strings.ReplaceAll(s, "0x", "")
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r', ':', '-':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// fileExists indica se o caminho é um arquivo existente
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"pwapi/pwapi"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Estados de uma entrega no diário
+//
+// Observações:
+//
+//	pendente: o ganhador foi sorteado e nada foi enviado, a entrega pode ser feita com segurança
+//	enviado: o envio começou e o resultado é desconhecido, o prêmio pode ter sido entregue
+//	confirmado: o serviço confirmou a entrega
+//	falhou: o serviço recusou a entrega ou ela não chegou a ser enviada, veja pwapi.RewardNotDelivered
+const (
+	entregaPendente   = "pendente"
+	entregaEnviada    = "enviado"
+	entregaConfirmada = "confirmado"
+	entregaFalhou     = "falhou"
+)
+
+// diarioPadrao é o arquivo do diário de entregas quando Entregas não está definido em config.yaml
+const diarioPadrao = "entregas.jsonl"
+
+// reenviosAutomaticos é a quantidade de tentativas após a qual o reenvio no início do sorteio desiste de uma entrega,
+// o subcomando deliveries retry continua reenviando
+const reenviosAutomaticos = 5
+
+// errDiario indica que o diário não pôde ser gravado, sem ele não é possível garantir uma única entrega por prêmio
+var errDiario = errors.New("erro no diário de entregas")
+
+// registroEntrega é uma linha do diário, cada mudança de estado acrescenta uma nova linha
+type registroEntrega struct {
+	ID       string            `json:"id"`
+	Estado   string            `json:"estado"`
+	Horario  time.Time         `json:"horario"`
+	Ganhador *pwapi.UserOnline `json:"ganhador,omitempty"` // apenas no registro pendente
+	Premio   *pwapi.Sorteio    `json:"premio,omitempty"`   // apenas no registro pendente
+	Erro     string            `json:"erro,omitempty"`
+}
+
+// entrega é o estado atual de um prêmio do diário, resultado de todas as suas linhas
+type entrega struct {
+	ID         string
+	Estado     string
+	Horario    time.Time
+	Ganhador   pwapi.UserOnline
+	Premio     pwapi.Sorteio
+	Erro       string
+	Tentativas int
+}
+
+// diario é o registro de entregas gravado antes de cada envio (write-ahead)
+//
+// Observações:
+//
+//	Cada linha é gravada e sincronizada com o disco antes do passo seguinte, de modo que após uma queda o diário
+//	indica quais prêmios não foram enviados (pendente e falhou) e quais podem ter sido (enviado)
+//	Um diário nil não grava nada, utilizado na reprodução de capturas e nos testes
+type diario struct {
+	mu       sync.Mutex
+	arquivo  *os.File
+	entregas map[string]*entrega
+	ordem    []string
+	seq      int
+}
+
+// abrirDiario abre ou cria o diário de entregas, carregando o estado de cada prêmio
+//
+// Parâmetros:
+//
+//	caminho: string - Arquivo do diário
+//
+// Retorno:
+//
+//	*diario - Diário pronto para gravação, deve ser fechado com fechar
+//	error - Retorna um erro caso o arquivo não possa ser aberto ou possua uma linha inválida antes da última
+//
+// Observações:
+//
+//	Uma última linha incompleta, gravada durante uma queda, é removida do arquivo
+func abrirDiario(caminho string) (*diario, error) {
+	arquivo, err := os.OpenFile(caminho, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &diario{arquivo: arquivo, entregas: map[string]*entrega{}}
+	scanner := bufio.NewScanner(arquivo)
+	scanner.Buffer(nil, 1<<20)
+	var valido int64 // fim do último registro completo
+	var invalida error
+	for linha := 1; scanner.Scan(); linha++ {
+		if invalida != nil {
+			arquivo.Close()
+			return nil, invalida
+		}
+		var r registroEntrega
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			invalida = fmt.Errorf("%s linha %d: %w", caminho, linha, err)
+			continue
+		}
+		d.aplicar(r)
+		valido += int64(len(scanner.Bytes())) + 1
+	}
+	if err := scanner.Err(); err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+
+	// A última linha incompleta é descartada, o registro é gravado antes da entrega e ela não chegou a acontecer
+	info, err := arquivo.Stat()
+	if err == nil {
+		switch {
+		case info.Size() > valido:
+			err = arquivo.Truncate(valido)
+		case info.Size() == valido-1:
+			// Registro completo sem a quebra de linha final
+			_, err = arquivo.Write([]byte{'\n'})
+		}
+	}
+	if err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// fechar fecha o arquivo do diário
+func (d *diario) fechar() error {
+	if d == nil {
+		return nil
+	}
+	return d.arquivo.Close()
+}
+
+// aplicar atualiza o estado do prêmio com um registro
+func (d *diario) aplicar(r registroEntrega) {
+	e, ok := d.entregas[r.ID]
+	if !ok {
+		e = &entrega{ID: r.ID}
+		d.entregas[r.ID] = e
+		d.ordem = append(d.ordem, r.ID)
+	}
+	if r.Ganhador != nil {
+		e.Ganhador = *r.Ganhador
+	}
+	if r.Premio != nil {
+		e.Premio = *r.Premio
+	}
+	// O resultado incerto de um envio grava enviado novamente, com o erro, sem ser uma nova tentativa
+	if r.Estado == entregaEnviada && e.Estado != entregaEnviada {
+		e.Tentativas++
+	}
+	e.Estado = r.Estado
+	e.Horario = r.Horario
+	e.Erro = r.Erro
+}
+
+// gravar acrescenta o registro ao arquivo e o sincroniza com o disco antes de aplicá-lo
+func (d *diario) gravar(r registroEntrega) error {
+	r.Horario = time.Now()
+	linha, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errDiario, err)
+	}
+	if _, err := d.arquivo.Write(append(linha, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", errDiario, err)
+	}
+	if err := d.arquivo.Sync(); err != nil {
+		return fmt.Errorf("%w: %v", errDiario, err)
+	}
+	d.aplicar(r)
+	return nil
+}
+
+// novo registra um prêmio sorteado como pendente, antes de qualquer envio
+//
+// Retorno:
+//
+//	string - ID da entrega no diário, vazio quando o diário é nil
+//	error - Erro ao gravar o diário, o prêmio não deve ser enviado
+func (d *diario) novo(ganhador pwapi.UserOnline, premio pwapi.Sorteio) (string, error) {
+	if d == nil {
+		return "", nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seq++
+	id := fmt.Sprintf("%s-%d", time.Now().Format("20060102150405"), d.seq)
+	for d.entregas[id] != nil {
+		d.seq++
+		id = fmt.Sprintf("%s-%d", time.Now().Format("20060102150405"), d.seq)
+	}
+	return id, d.gravar(registroEntrega{ID: id, Estado: entregaPendente, Ganhador: &ganhador, Premio: &premio})
+}
+
+// registrar grava a mudança de estado de uma entrega, err é gravado como o motivo
+func (d *diario) registrar(id, estado string, err error) error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.entregas[id] == nil {
+		return fmt.Errorf("entrega %s não encontrada no diário", id)
+	}
+	r := registroEntrega{ID: id, Estado: estado}
+	if err != nil {
+		r.Erro = err.Error()
+	}
+	return d.gravar(r)
+}
+
+// lista retorna uma cópia das entregas na ordem em que foram sorteadas, filtradas pelos estados informados
+func (d *diario) lista(estados ...string) []entrega {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var lista []entrega
+	for _, id := range d.ordem {
+		e := d.entregas[id]
+		incluir := len(estados) == 0
+		for _, estado := range estados {
+			incluir = incluir || e.Estado == estado
+		}
+		if incluir {
+			lista = append(lista, *e)
+		}
+	}
+	return lista
+}
+
+// buscar retorna o estado atual de uma entrega
+func (d *diario) buscar(id string) (entrega, bool) {
+	if d == nil {
+		return entrega{}, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.entregas[id]
+	if !ok {
+		return entrega{}, false
+	}
+	return *e, true
+}
+
+// caminhoDiario retorna o arquivo do diário de entregas configurado em config.yaml
+func caminhoDiario() string {
+	if pwapi.AppConfig.Entregas != "" {
+		return pwapi.AppConfig.Entregas
+	}
+	return diarioPadrao
+}
+
+//comandoEntregas implementa o subcomando deliveries
+//
+//Parâmetros:
+//	args: []string - Ação (list, retry ou mark) e os seus argumentos
+//	stdout: io.Writer - Saída da listagem e do resultado do reenvio
+//
+//Retorno:
+//	int - Código de saída do programa, 1 caso algum prêmio continue sem entrega confirmada
+//
+//Exemplos:
+//	./sorteio deliveries list
+//	./sorteio deliveries list enviado falhou
+//	./sorteio deliveries retry
+//	./sorteio deliveries mark 20240501213000-1 confirmado
+//
+//Observações:
+//	O diário não é bloqueado entre processos, não execute o subcomando durante um sorteio
+
+func comandoEntregas(args []string, stdout io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "uso: sorteio deliveries list [estado...] | retry | mark <id> confirmado|falhou")
+		return 2
+	}
+
+	entregas, err := abrirDiario(caminhoDiario())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao abrir o diário de entregas: %v\n", err)
+		return 1
+	}
+	defer entregas.fechar()
+
+	switch args[0] {
+	case "list":
+		listarEntregas(stdout, entregas.lista(args[1:]...))
+		return 0
+	case "mark":
+		return marcarEntrega(entregas, args[1:], stdout)
+	case "retry":
+		return reenviarEntregas(entregas, stdout)
+	}
+	fmt.Fprintf(os.Stderr, "Ação desconhecida: %q\n", args[0])
+	return 2
+}
+
+// listarEntregas escreve o estado, as tentativas, o ganhador e o prêmio de cada entrega
+func listarEntregas(stdout io.Writer, entregas []entrega) {
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEstado\tTentativas\tHorário\tConta\tPersonagem\tNome\tPrêmio\tErro")
+	for _, e := range entregas {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%d\t%s\t%d %s\t%s\n", e.ID, e.Estado, e.Tentativas, e.Horario.Format("2006-01-02 15:04:05"),
+			e.Ganhador.UserID, e.Ganhador.RoleID.RoleID, e.Ganhador.Name, e.Premio.Quantidade, e.Premio.Nome, e.Erro)
+	}
+	w.Flush()
+}
+
+// marcarEntrega define manualmente o resultado de uma entrega, após verificá-la no servidor
+//
+// Observações:
+//
+//	Entregas confirmadas não podem ser alteradas, marcar como falhou permite que o prêmio seja reenviado
+func marcarEntrega(entregas *diario, args []string, stdout io.Writer) int {
+	if len(args) != 2 || (args[1] != entregaConfirmada && args[1] != entregaFalhou) {
+		fmt.Fprintln(os.Stderr, "uso: sorteio deliveries mark <id> confirmado|falhou")
+		return 2
+	}
+
+	e, ok := entregas.buscar(args[0])
+	switch {
+	case !ok:
+		fmt.Fprintf(os.Stderr, "Entrega %s não encontrada\n", args[0])
+		return 1
+	case e.Estado == entregaConfirmada:
+		fmt.Fprintf(os.Stderr, "Entrega %s já foi confirmada\n", args[0])
+		return 1
+	}
+
+	if err := entregas.registrar(e.ID, args[1], errors.New("marcado manualmente")); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao marcar a entrega %s: %v\n", e.ID, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Entrega %s marcada como %s\n", e.ID, args[1])
+	return 0
+}
+
+// reenviarEntregas reenvia os prêmios pendentes e recusados com o servidor configurado em config.yaml
+func reenviarEntregas(entregas *diario, stdout io.Writer) int {
+	opts := []pwapi.Option{pwapi.WithConfig(pwapi.AppConfig)}
+	if pwapi.AppConfig.MySQL.Host != "" {
+		opts = append(opts, pwapi.WithDSN(pwapi.AppConfig.MySQL.DSN()))
+	}
+	client, err := pwapi.NewClient(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao criar o cliente: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	arquivoLog, err := os.OpenFile("log.txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao abrir o arquivo de log: %v\n", err)
+		return 1
+	}
+	defer arquivoLog.Close()
+
+	s := sorteio{client: client, cfg: pwapi.AppConfig, log: log.New(arquivoLog, "", log.LstdFlags), diario: entregas}
+	return s.reenviarTodas(context.Background(), stdout)
+}
+
+// reenviarTodas reenvia os prêmios não entregues e escreve quantos foram entregues e quantos continuam sem confirmação
+func (s *sorteio) reenviarTodas(ctx context.Context, stdout io.Writer) int {
+	entregues, err := s.reenviar(ctx, false)
+	fmt.Fprintf(stdout, "%d prêmios reenviados\n", entregues)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Reenvio interrompido: %v\n", err)
+		return 1
+	}
+
+	restantes := s.diario.lista(entregaPendente, entregaEnviada, entregaFalhou)
+	if len(restantes) == 0 {
+		return 0
+	}
+	fmt.Fprintf(stdout, "%d prêmios sem entrega confirmada:\n", len(restantes))
+	listarEntregas(stdout, restantes)
+	return 1
+}
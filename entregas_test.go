@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pwapi/pwapi"
+	"pwapi/pwapi/pwtest"
+)
+
+func TestDiarioRecuperaAposQueda(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "entregas.jsonl")
+	d, err := abrirDiario(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ganhador := pwapi.UserOnline{UserID: 32, RoleID: pwapi.RoleID{RoleID: 1024}, Name: "Fulano"}
+	premio := pwapi.Sorteio{Tipo: pwapi.RewardMailItem, Nome: "Oráculo", Quantidade: 1, Item: pwapi.Item{ID: 7749, Data: []byte{0x13, 0x08}}}
+	confirmado, _ := d.novo(ganhador, premio)
+	d.registrar(confirmado, entregaEnviada, nil)
+	d.registrar(confirmado, entregaConfirmada, nil)
+	enviado, _ := d.novo(ganhador, premio)
+	d.registrar(enviado, entregaEnviada, nil)
+	pendente, err := d.novo(ganhador, premio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.fechar()
+
+	// A queda durante a gravação deixa uma linha incompleta no fim do arquivo
+	f, err := os.OpenFile(caminho, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"` + pendente + `","estado":"envi`)
+	f.Close()
+
+	d, err = abrirDiario(caminho)
+	if err != nil {
+		t.Fatalf("abrirDiario após a queda: %v", err)
+	}
+	if lista := d.lista(entregaPendente, entregaFalhou); len(lista) != 1 || lista[0].ID != pendente ||
+		lista[0].Premio.Item.ID != 7749 || !bytes.Equal(lista[0].Premio.Item.Data, []byte{0x13, 0x08}) {
+		t.Errorf("entregas a reenviar = %+v, esperado apenas %s com o item", lista, pendente)
+	}
+	if e, _ := d.buscar(enviado); e.Estado != entregaEnviada || e.Tentativas != 1 || e.Ganhador != ganhador {
+		t.Errorf("entrega enviada = %+v", e)
+	}
+
+	// Os registros seguintes começam em uma nova linha e o diário continua legível
+	if err := d.registrar(pendente, entregaFalhou, nil); err != nil {
+		t.Fatal(err)
+	}
+	d.fechar()
+	d, err = abrirDiario(caminho)
+	if err != nil {
+		t.Fatalf("abrirDiario após novo registro: %v", err)
+	}
+	defer d.fechar()
+	if e, _ := d.buscar(pendente); e.Estado != entregaFalhou {
+		t.Errorf("entrega %s = %+v, esperado falhou", pendente, e)
+	}
+	if e, _ := d.buscar(confirmado); e.Estado != entregaConfirmada {
+		t.Errorf("entrega %s = %+v, esperado confirmado", confirmado, e)
+	}
+}
+
+func TestSorteioReenviaSomenteNaoEntregues(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Cheio", UserID: 32}, pwapi.RoleStatus{Level: 105, Level2: 22})
+	srv.AddOfflineRole(pwapi.RoleBase{ID: 2048, Name: "Incerto", UserID: 48}, pwapi.RoleStatus{Level: 105, Level2: 22})
+	srv.SetMailRetCode(pwapi.RoleID{RoleID: 1024}, pwapi.RetCodeMailboxFull)
+
+	entregas, err := abrirDiario(filepath.Join(t.TempDir(), "entregas.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer entregas.fechar()
+
+	// Um envio interrompido antes da resposta fica como enviado, o prêmio pode ter chegado
+	incerto, _ := entregas.novo(pwapi.UserOnline{UserID: 48, RoleID: pwapi.RoleID{RoleID: 2048}, Name: "Incerto"},
+		pwapi.Sorteio{Tipo: pwapi.RewardMailMoney, Nome: "Moedas", Quantidade: 700})
+	entregas.registrar(incerto, entregaEnviada, nil)
+
+	s := sorteio{
+		client: client,
+		cfg: pwapi.Config{
+			QuantidadeDeSorteados: 1,
+			GmReceber:             true,
+			CanalMensagem:         9,
+			Moedas:                []int{500},
+		},
+		rng:    rand.New(rand.NewSource(1)),
+		log:    log.New(io.Discard, "", 0),
+		diario: entregas,
+	}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("executar: %v", err)
+	}
+
+	recusadas := entregas.lista(entregaFalhou)
+	if len(recusadas) != 1 || recusadas[0].Ganhador.RoleID.RoleID != 1024 || recusadas[0].Tentativas != 1 {
+		t.Fatalf("entregas recusadas = %+v, esperado a caixa cheia de 1024", recusadas)
+	}
+
+	// Na execução seguinte a caixa foi esvaziada: o prêmio recusado é reenviado antes do sorteio, o incerto não
+	srv.SetMailRetCode(pwapi.RoleID{RoleID: 1024}, 0)
+	s.cfg.Moedas = []int{900}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("executar: %v", err)
+	}
+	if e, _ := entregas.buscar(recusadas[0].ID); e.Estado != entregaConfirmada || e.Tentativas != 2 {
+		t.Errorf("entrega reenviada = %+v, esperado confirmado na segunda tentativa", e)
+	}
+	if e, _ := entregas.buscar(incerto); e.Estado != entregaEnviada || e.Tentativas != 1 {
+		t.Errorf("entrega incerta = %+v, esperado enviado sem reenvio", e)
+	}
+
+	var moedas []int
+	for _, mail := range srv.Mails() {
+		if mail.Receiver.RoleID == 2048 {
+			t.Errorf("entrega incerta reenviada: %+v", mail)
+		}
+		moedas = append(moedas, mail.AttachMoney)
+	}
+	if len(moedas) != 2 || moedas[0] != 500 || moedas[1] != 900 {
+		t.Errorf("moedas entregues = %v, esperado o prêmio reenviado e o novo sorteio", moedas)
+	}
+
+	// Após a verificação manual a entrega incerta pode ser marcada como falhou e reenviada pelo subcomando
+	var saida bytes.Buffer
+	if codigo := marcarEntrega(entregas, []string{incerto, entregaFalhou}, &saida); codigo != 0 {
+		t.Fatalf("marcarEntrega = %d", codigo)
+	}
+	if codigo := s.reenviarTodas(context.Background(), &saida); codigo != 0 {
+		t.Fatalf("reenviarTodas = %d:\n%s", codigo, saida.String())
+	}
+	if !strings.Contains(saida.String(), "1 prêmios reenviados") {
+		t.Errorf("saída = %s", saida.String())
+	}
+	if codigo := marcarEntrega(entregas, []string{incerto, entregaFalhou}, &saida); codigo == 0 {
+		t.Error("marcarEntrega alterou uma entrega confirmada")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Wait(ctx, func() bool { return len(srv.Mails()) == 3 }); err != nil {
+		t.Errorf("e-mails = %+v, esperado o reenvio da entrega incerta", srv.Mails())
+	}
+}
+
+func TestSorteioNaoReenviaEmailSemResposta(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Level: 105, Level2: 22})
+
+	entregas, err := abrirDiario(filepath.Join(t.TempDir(), "entregas.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer entregas.fechar()
+	pendente, _ := entregas.novo(pwapi.UserOnline{UserID: 32, RoleID: pwapi.RoleID{RoleID: 1024}, Name: "Fulano"},
+		pwapi.Sorteio{Tipo: pwapi.RewardMailMoney, Nome: "Moedas", Quantidade: 700})
+
+	// A conexão com o gdeliveryd fica no pool e o reenvio a reaproveita, o e-mail chega e a resposta se perde
+	if _, err := client.OnlineUsers(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	srv.DropReplyNext("gdeliveryd", 1)
+
+	s := sorteio{
+		client: client,
+		cfg: pwapi.Config{
+			QuantidadeDeSorteados: 1,
+			GmReceber:             true,
+			CanalMensagem:         9,
+			Moedas:                []int{500},
+		},
+		rng:    rand.New(rand.NewSource(1)),
+		log:    log.New(io.Discard, "", 0),
+		diario: entregas,
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.executar(context.Background()); err != nil {
+			t.Fatalf("executar: %v", err)
+		}
+	}
+
+	if e, _ := entregas.buscar(pendente); e.Estado != entregaEnviada || e.Tentativas != 1 {
+		t.Errorf("entrega sem resposta = %+v, esperado enviado sem reenvio", e)
+	}
+	reenviados := 0
+	for _, mail := range srv.Mails() {
+		if mail.AttachMoney == 700 {
+			reenviados++
+		}
+	}
+	if reenviados != 1 {
+		t.Errorf("e-mails da entrega sem resposta = %d, esperado exatamente 1 em %+v", reenviados, srv.Mails())
+	}
+}
@@ -3,8 +3,9 @@ module pwapi
 go 1.21.5
 
 require (
-	github.com/go-sql-driver/mysql v1.7.1 // indirect
-	golang.org/x/sys v0.5.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	github.com/go-sql-driver/mysql v1.7.1
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v2 v2.4.0
 )
+
+require golang.org/x/sys v0.5.0 // indirect
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"encoding/hex"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
@@ -9,6 +10,8 @@ import (
 	"path/filepath"
 	"pwapi/pwapi"
 	"regexp"
+	"strconv"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -49,18 +52,18 @@ func loadConfig(filename string) error {
 	return nil
 }
 
-//removeUser remove um usuário de um slice de RoleID
+//removeUser remove um usuário de um slice de usuários
 //
-//A função recebe um slice de RoleID e um índice e retorna um novo slice sem o usuário no índice informado
+//A função recebe um slice de usuários (RoleID ou UserOnline) e um índice e retorna um novo slice sem o usuário no índice informado
 //
 //Parâmetros:
-//	slice: []pwapi.RoleID - Slice de RoleID
+//	slice: []T - Slice de usuários
 //	index: int - Índice do usuário a ser removido
 //
 //Retorno:
-//	[]pwapi.RoleID - Retorna um novo slice sem o usuário no índice informado
+//	[]T - Retorna um novo slice sem o usuário no índice informado
 
-func removeUser(slice []pwapi.RoleID, index int) []pwapi.RoleID {
+func removeUser[T any](slice []T, index int) []T {
 	return append(slice[:index], slice[index+1:]...)
 }
 
@@ -89,6 +92,14 @@ func removerCaracteresIndesejados(nome string) string {
 }
 
 func main() {
+	reproduzir := flag.String("reproduzir", "", "reproduz um sorteio gravado em Captura, sem acessar o servidor")
+	flag.Parse()
+
+	// Subcomandos que não realizam sorteio
+	if flag.Arg(0) == "decode" {
+		os.Exit(decodificar(flag.Args()[1:], os.Stdout))
+	}
+
 	// Carrega as configurações do arquivo config.yaml
 	configerr := loadConfig("config.yaml")
 	if configerr != nil {
@@ -96,229 +107,102 @@ func main() {
 		return
 	}
 
-	// Inicializa a conexão com o banco de dados
-	pwapi.InitializeDB()
-	defer pwapi.CloseDB()
-
-	// Verifica se o servidor está online
-	serverOnline := pwapi.IsServerOnline()
-	if !serverOnline {
-		fmt.Println("Servidor offline")
-		return
+	// O subcomando deliveries reenvia ou marca os prêmios do diário de entregas
+	if flag.Arg(0) == "deliveries" {
+		os.Exit(comandoEntregas(flag.Args()[1:], os.Stdout))
 	}
 
-	// Busca a lista de usuários online
-	onlineList := pwapi.GetOnlineList()
-
-	// Verifica se existem usuários online
-	if len(onlineList) == 0 {
-		fmt.Println("nenhum usuário online")
-		return
+	// Subcomandos de consulta utilizam o servidor configurado, mas não realizam sorteio
+	if _, ok := consultas[flag.Arg(0)]; ok {
+		os.Exit(consultar(flag.Arg(0), flag.Args()[1:], os.Stdout))
 	}
 
-	// Inicio do sorteio
+	// A semente é gravada na captura para que a reprodução faça as mesmas escolhas
+	semente := time.Now().UnixNano()
+	opts := []pwapi.Option{pwapi.WithConfig(pwapi.AppConfig)}
 
-	//define a variável para verificar se o usuário é válido
-	usuarioValido := false
+	if *reproduzir != "" {
+		replayer, err := pwapi.LoadReplayer(*reproduzir)
+		if err != nil {
+			fmt.Printf("Erro ao carregar a captura: %v\n", err)
+			return
+		}
+		if valor, ok := replayer.Meta("semente"); ok {
+			semente, _ = strconv.ParseInt(valor, 10, 64)
+		}
+		opts = append(opts, pwapi.WithReplayer(replayer))
 
-	//define a variável para armazenar o ID do personagem
-	var roleID pwapi.RoleID
+		// A reprodução não acessa o banco de dados, a verificação de GM é ignorada
+		pwapi.AppConfig.GmReceber = true
+	} else if pwapi.AppConfig.Captura != "" {
+		arquivoCaptura, err := os.OpenFile(pwapi.AppConfig.Captura, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			fmt.Printf("Erro ao abrir o arquivo de captura: %v\n", err)
+			return
+		}
+		defer arquivoCaptura.Close()
 
-	// Exibe a quantidade de usuários online caso o modo debug esteja ativado
-	if pwapi.AppConfig.Debug {
-		fmt.Printf("Total de usuários online: %d\n", len(onlineList))
-		//fmt.Printf("onlineList: %v\n\n", onlineList)
+		recorder := pwapi.NewRecorder(arquivoCaptura)
+		recorder.Meta("semente", strconv.FormatInt(semente, 10))
+		opts = append(opts, pwapi.WithRecorder(recorder))
 	}
 
-	// Exibe a quantidade de usuários a serem sorteados caso o modo debug esteja ativado
-	if pwapi.AppConfig.Debug {
-		fmt.Printf("Quantidade de usuários a sortear: %d\n", pwapi.AppConfig.QuantidadeDeSorteados)
+	client, err := pwapi.NewClient(opts...)
+	if err != nil {
+		fmt.Printf("Erro ao criar o cliente: %v\n", err)
+		return
 	}
+	pwapi.SetDefault(client)
 
-	//define a variável para armazenar os dados do personagem
-	var roleBase pwapi.RoleBase
-
-	// Realiza o sorteio da quantidade de usuários definida no arquivo de configuração
-	for i := 0; i < pwapi.AppConfig.QuantidadeDeSorteados; i++ {
-
-		// Sorteia um usuário aleatório e verifica se ele atende aos critérios de level, cultivo e se é um GM
-		// de acordo com as configurações do arquivo de configuração
-		// Caso o usuário não atenda aos critérios, ele é removido da lista de usuários online e um novo usuário é sorteado
-		// até que um usuário válido seja encontrado
-
-		for !usuarioValido {
-
-			// Exibe o número do sorteio caso o modo debug esteja ativado
-			if pwapi.AppConfig.Debug {
-				fmt.Printf("\nSorteando usuário %d\n", i+1)
-			}
-
-			// Verifica se ainda existem usuários online
-			if len(onlineList) == 0 {
-				fmt.Println("Nenhum usuário restante")
-				return
-			}
-
-			// Seleciona um usuário aleatório
-			key := rand.Intn(len(onlineList))
-			roleID = onlineList[key]
-
-			// Busca os dados do personagem (role)
-			role := pwapi.GetRoleStatus(roleID)
-			if pwapi.AppConfig.Debug {
-				fmt.Printf("Usuário sorteado: %v\n", roleID)
-			}
-
-			// Verifica se o personagem possui o level mínimo
-			if role.Level < pwapi.AppConfig.LevelMinimo {
-				if pwapi.AppConfig.Debug {
-					fmt.Print("Personagem não possui o level mínimo\n\n")
-				}
-				// Remove o usuário da lista de usuários online
-				onlineList = removeUser(onlineList, key)
-				continue
-			}
-
-			// Verifica se o personagem possui o cultivo mínimo
-			if role.Level2 < pwapi.AppConfig.CultivoMinimo {
-				if pwapi.AppConfig.Debug {
-					fmt.Print("Personagem não possui o cultivo mínimo\n\n")
-				}
-				// Remove o usuário da lista de usuários online
-				onlineList = removeUser(onlineList, key)
-				continue
-			}
-
-			//Busca o nome do personagem
-			roleBase = pwapi.GetRoleBase(roleID)
-
-			// Verifica se o usuário é um gm
-			ehGm := pwapi.UsuarioEGM(roleBase.UserID)
-			if !pwapi.AppConfig.GmReceber && ehGm {
-				if pwapi.AppConfig.Debug {
-					fmt.Print("Usuário é um GM\n\n")
-				}
-				// Remove o usuário da lista de usuários online
-				onlineList = removeUser(onlineList, key)
-				continue
-			}
-
-			// Se o usuário atender a todos os critérios, a variável usuarioValido é setada como true
-			// e o loop é encerrado
-			usuarioValido = true
-
-			//remove o usuário sorteado do proximo sorteio
-			onlineList = removeUser(onlineList, key)
-		}
-
-		// ajuste para o próximo sorteio
-		usuarioValido = false
-
-		// Cria um slice de Sorteio com as moedas e golds a serem sorteados
-		var Sorteio []pwapi.Sorteio
-
-		// Adiciona as moedas ao sorteio
-		for _, moeda := range pwapi.AppConfig.Moedas {
-			Sorteio = append(Sorteio, pwapi.Sorteio{
-				Tipo:       "moedas",
-				Nome:       "Moedas",
-				Quantidade: moeda,
-			})
-		}
+	// Inicializa a conexão com o banco de dados
+	if *reproduzir == "" {
+		pwapi.InitializeDB()
+		defer pwapi.CloseDB()
+	}
 
-		// Adiciona os golds ao sorteio
-		for _, gold := range pwapi.AppConfig.Golds {
-			Sorteio = append(Sorteio, pwapi.Sorteio{
-				Tipo:       "gold",
-				Nome:       "Gold",
-				Quantidade: gold,
-			})
-		}
+	// Fecha as conexões mantidas com o gamedbd, gdeliveryd e provider ao final do sorteio
+	defer pwapi.ClosePools()
 
-		// Adiciona os itens ao sorteio
-		for _, item := range pwapi.AppConfig.ItensSortear {
-			//convert item.Data from string to []byte
-			stringData := item.Data
-			Octets, err := hex.DecodeString(stringData)
-			if err != nil {
-				fmt.Println("Erro:", err)
-				return
+	// Exibe as estatísticas dos pools de conexões caso o modo debug esteja ativado
+	if pwapi.AppConfig.Debug {
+		defer func() {
+			for _, stats := range pwapi.GetPoolStats() {
+				fmt.Printf("Pool %s: %d conexões abertas, %d reaproveitadas, %d descartadas\n", stats.Addr, stats.Dials, stats.Reuses, stats.Discarded)
 			}
+		}()
+	}
 
-			Sorteio = append(Sorteio, pwapi.Sorteio{
-				Tipo:       "item",
-				Nome:       item.Nome,
-				Quantidade: item.Count,
-				Item: pwapi.Item{
-					ID:         item.ID,
-					Pos:        item.Pos,
-					Count:      item.Count,
-					MaxCount:   item.MaxCount,
-					Data:       Octets,
-					ProcType:   item.ProcType,
-					ExpireDate: item.ExpireDate,
-					GUID1:      item.GUID1,
-					GUID2:      item.GUID2,
-					Mask:       item.Mask,
-				},
-			})
-		}
-
-		// Sorteia um item do slice Sorteio
-		key := rand.Intn(len(Sorteio))
-		Sorteado := Sorteio[key]
-
-		// Exibe o item sorteado caso o modo debug esteja ativado
-		if pwapi.AppConfig.Debug {
-			fmt.Println("Item sorteado:", Sorteado)
-		}
-
-		// Abrir ou criar o arquivo de log
+	// Abrir ou criar o arquivo de log, a reprodução escreve na saída padrão para não se misturar aos prêmios reais
+	logger := log.New(os.Stdout, "[reprodução] ", 0)
+	if *reproduzir == "" {
 		arquivoLog, err := os.OpenFile("log.txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 		if err != nil {
 			log.Fatal("Erro ao abrir o arquivo de log:", err)
 		}
 		defer arquivoLog.Close()
+		logger = log.New(arquivoLog, "", log.LstdFlags)
+	}
 
-		// Configurar o logger para escrever no arquivo
-		log.SetOutput(arquivoLog)
-
-		// instancia a variável mensagem para exibir no chat do jogo e no log
-		var mensagem string
-
-		// Remove os caracteres indesejados do nome do personagem
-		roleName := removerCaracteresIndesejados(roleBase.Name)
-
-		if Sorteado.Tipo == "moedas" {
-			// prepara a mensagem para exibir no chat do jogo e no log
-			mensagem = fmt.Sprintf("^ffffffO jogador &%s& acabou de ganhar ^33cc33 %d Moedas", roleName, Sorteado.Quantidade)
-
-			// Adiciona as moedas ao personagem
-			pwapi.SendMail(roleID, "Logue e ganhe", "Parabens, você ganhou moedas no logue e ganhe", pwapi.Item{}, Sorteado.Quantidade)
-		}
-
-		if Sorteado.Tipo == "gold" {
-			// prepara a mensagem para exibir no chat do jogo e no log
-			mensagem = fmt.Sprintf("^ffffffO jogador &%s& acabou de ganhar ^33cc33 %d Golds", roleName, Sorteado.Quantidade)
-
-			// Adiciona os golds ao personagem
-			pwapi.AddCash(roleBase.UserID, Sorteado.Quantidade)
-
-		}
-
-		if Sorteado.Tipo == "item" {
-			// prepara a mensagem para exibir no chat do jogo e no log
-			mensagem = fmt.Sprintf("^ffffffO jogador &%s& acabou de ganhar ^33cc33 %d %s", roleName, Sorteado.Quantidade, Sorteado.Nome)
-
-			// Adiciona o item ao personagem
-			pwapi.SendMail(roleID, "Logue e ganhe", "Parabens, você ganhou um item no logue e ganhe", Sorteado.Item, 0)
-
+	// Abre o diário de entregas, a reprodução não entrega prêmios reais e não o utiliza
+	var entregas *diario
+	if *reproduzir == "" {
+		entregas, err = abrirDiario(caminhoDiario())
+		if err != nil {
+			fmt.Printf("Erro ao abrir o diário de entregas: %v\n", err)
+			return
 		}
-
-		// Exibe a mensagem no chat do jogo
-		pwapi.ChatItem(mensagem)
-		log.Println(mensagem)
-
+		defer entregas.fechar()
 	}
 
+	// Realiza o sorteio com o cliente configurado a partir de config.yaml
+	s := sorteio{
+		client: client,
+		cfg:    pwapi.AppConfig,
+		rng:    rand.New(rand.NewSource(semente)),
+		log:    logger,
+		diario: entregas,
+	}
+	if err := s.executar(context.Background()); err != nil {
+		fmt.Println(err)
+	}
 }
@@ -0,0 +1,123 @@
+package pwapi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// benchRoleStatus é um RoleStatus com blobs de tamanho próximo aos de um personagem real
+var benchRoleStatus = RoleStatus{Sversion: 1, Level: 105, Level2: 22, Exp: 123456, Posx: 1234.5, Posy: 220.25,
+	CustomStatus: bytes.Repeat([]byte{1}, 64), FilterData: bytes.Repeat([]byte{2}, 128), DbltimeData: bytes.Repeat([]byte{3}, 32),
+	Petcorral: bytes.Repeat([]byte{4}, 512), Property: bytes.Repeat([]byte{5}, 180), VarData: bytes.Repeat([]byte{6}, 256),
+	Skills: bytes.Repeat([]byte{7}, 900), Waypointlist: bytes.Repeat([]byte{8}, 200), Coolingtime: bytes.Repeat([]byte{9}, 300)}
+
+func benchRoleStatusData(b *testing.B) []byte {
+	data, err := Marshal(benchRoleStatus)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	return data
+}
+
+func BenchmarkUnmarshalRoleStatus(b *testing.B) {
+	data := benchRoleStatusData(b)
+	for i := 0; i < b.N; i++ {
+		var v RoleStatus
+		if _, err := Unmarshal(data, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalRoleStatusReflect(b *testing.B) {
+	data := benchRoleStatusData(b)
+	for i := 0; i < b.N; i++ {
+		var v RoleStatus
+		if _, err := unmarshalValue(data, reflect.ValueOf(&v).Elem()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshalRoleStatusLevel mede a leitura parcial utilizada no filtro de elegibilidade do sorteio
+func BenchmarkUnmarshalRoleStatusLevel(b *testing.B) {
+	data := benchRoleStatusData(b)
+	for i := 0; i < b.N; i++ {
+		var v RoleStatusLevel
+		if _, err := Unmarshal(data, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalRoleStatusProfile(b *testing.B) {
+	profile, err := LookupProfile("1.5.x")
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := benchRoleStatusData(b)
+	for i := 0; i < b.N; i++ {
+		var v RoleStatus
+		if _, err := UnmarshalProfile(data, &v, profile); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalRoleStatus(b *testing.B) {
+	benchRoleStatusData(b)
+	buf := make([]byte, 0, 4096)
+	for i := 0; i < b.N; i++ {
+		if _, err := benchRoleStatus.AppendPW(buf[:0]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalRoleStatusReflect(b *testing.B) {
+	benchRoleStatusData(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := marshalValue(reflect.ValueOf(benchRoleStatus)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalRoleBase(b *testing.B) {
+	data, err := Marshal(RoleBase{Version: 1, ID: 1024, Name: "Fulano", CustomData: bytes.Repeat([]byte{1}, 48),
+		Forbid: []GRoleForbid{{Type: 100, Reason: "spam"}}, UserID: 32})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var v RoleBase
+		if _, err := Unmarshal(data, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshalRoleStatusProfileParallel mede a leitura com perfil em várias goroutines, como no sorteio
+func BenchmarkUnmarshalRoleStatusProfileParallel(b *testing.B) {
+	profile, err := LookupProfile("1.5.x")
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := benchRoleStatusData(b)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var v RoleStatus
+			if _, err := UnmarshalProfile(data, &v, profile); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
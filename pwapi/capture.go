@@ -0,0 +1,252 @@
+package pwapi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Direções de um registro de captura
+const (
+	CaptureRequest  = "req"  // frame enviado ao serviço
+	CaptureResponse = "resp" // frame recebido do serviço
+	CaptureMeta     = "meta" // informação adicional da execução, como a semente do sorteio
+)
+
+// ErrReplayMismatch indica que a captura não possui a requisição ou a resposta pedida durante a reprodução
+var ErrReplayMismatch = errors.New("requisição não encontrada na captura")
+
+// CaptureEntry é um registro do arquivo de captura
+//
+// Observações:
+//
+//	O arquivo de captura possui um registro JSON por linha, na ordem em que os frames passaram pela conexão
+//	Payload é o payload do frame em hexadecimal, sem o cabeçalho
+type CaptureEntry struct {
+	Time    time.Time `json:"time"`
+	Backend string    `json:"backend,omitempty"`
+	Dir     string    `json:"dir"`
+	Opcode  uint32    `json:"opcode,omitempty"`
+	Payload string    `json:"payload,omitempty"`
+	Key     string    `json:"key,omitempty"`
+	Value   string    `json:"value,omitempty"`
+}
+
+// Frame retorna o frame do registro
+func (e CaptureEntry) Frame() (Frame, error) {
+	payload, err := hex.DecodeString(e.Payload)
+	if err != nil {
+		return Frame{}, fmt.Errorf("payload da captura inválido: %w", err)
+	}
+	raw := EncodeFrame(e.Opcode, payload)
+	return Frame{Opcode: e.Opcode, Payload: raw[len(raw)-len(payload):], Raw: raw}, nil
+}
+
+// Recorder grava os frames trocados com os serviços em um arquivo de captura
+//
+// Observações:
+//
+//	Pode ser utilizado por várias goroutines ao mesmo tempo, cada registro é gravado por inteiro
+//	Erros de escrita não interrompem a comunicação com o servidor, o primeiro erro é retornado por Err
+type Recorder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder cria um Recorder que grava em w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w, enc: json.NewEncoder(w)}
+}
+
+// Record grava um frame enviado (CaptureRequest) ou recebido (CaptureResponse) de um serviço
+func (r *Recorder) Record(backend, dir string, frame Frame) {
+	r.write(CaptureEntry{
+		Time:    time.Now(),
+		Backend: backend,
+		Dir:     dir,
+		Opcode:  frame.Opcode,
+		Payload: hex.EncodeToString(frame.Payload),
+	})
+}
+
+// Meta grava uma informação adicional da execução, recuperada na reprodução por Replayer.Meta
+func (r *Recorder) Meta(key, value string) {
+	r.write(CaptureEntry{Time: time.Now(), Dir: CaptureMeta, Key: key, Value: value})
+}
+
+// Err retorna o primeiro erro de escrita
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) write(e CaptureEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(e)
+}
+
+// recordPacket grava cada frame de um pacote enviado, um pacote pode conter mais de um frame
+func (r *Recorder) recordPacket(backend string, data []byte) {
+	for len(data) > 0 {
+		frame, rest, err := ParseFrame(data)
+		if err != nil {
+			// Bytes que não formam um frame são gravados inteiros para não se perderem
+			r.Record(backend, CaptureRequest, Frame{Payload: data})
+			return
+		}
+		r.Record(backend, CaptureRequest, frame)
+		data = rest
+	}
+}
+
+// Replayer responde as requisições a partir de um arquivo de captura, sem acessar a rede
+//
+// Observações:
+//
+//	Cada requisição é associada à próxima requisição ainda não utilizada da captura com o mesmo serviço e opcode,
+//	a resposta devolvida é a primeira resposta seguinte com o opcode esperado (e o mesmo xid, no caso das RPCs)
+//	Desde que o sorteio faça as mesmas escolhas (veja Meta), a execução gravada é reproduzida por completo
+type Replayer struct {
+	mu      sync.Mutex
+	entries []CaptureEntry
+	used    []bool
+}
+
+// NewReplayer lê um arquivo de captura gravado pelo Recorder
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	p := &Replayer{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 2*MaxFrameSize+1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e CaptureEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("captura inválida na linha %d: %w", line, err)
+		}
+		p.entries = append(p.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	p.used = make([]bool, len(p.entries))
+	return p, nil
+}
+
+// LoadReplayer lê o arquivo de captura do caminho informado, veja NewReplayer
+func LoadReplayer(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplayer(f)
+}
+
+// Entries retorna os registros da captura
+func (p *Replayer) Entries() []CaptureEntry {
+	return p.entries
+}
+
+// Meta retorna o último valor gravado para key por Recorder.Meta
+func (p *Replayer) Meta(key string) (string, bool) {
+	for i := len(p.entries) - 1; i >= 0; i-- {
+		if e := p.entries[i]; e.Dir == CaptureMeta && e.Key == key {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
+
+// Unused retorna as requisições e respostas da captura que não foram utilizadas na reprodução
+//
+// Observações:
+//
+//	Ao final de uma reprodução fiel, restam apenas frames não solicitados, como keepalives do gdeliveryd
+func (p *Replayer) Unused() []CaptureEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var unused []CaptureEntry
+	for i, e := range p.entries {
+		if !p.used[i] && e.Dir != CaptureMeta {
+			unused = append(unused, e)
+		}
+	}
+	return unused
+}
+
+// exchange devolve a resposta gravada para a requisição
+//
+// Parâmetros:
+//
+//	backend: string - Serviço que recebeu a requisição
+//	req: Frame - Requisição enviada
+//	respOpcode: uint32 - Opcode da resposta esperada
+//	justSend: bool - Quando true, a requisição é apenas consumida da captura
+//	rpc: bool - Quando true, a resposta deve possuir o xid da requisição gravada
+func (p *Replayer) exchange(backend string, req Frame, respOpcode uint32, justSend, rpc bool) (Frame, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := p.next(0, backend, CaptureRequest, req.Opcode, nil)
+	if i < 0 {
+		return Frame{}, fmt.Errorf("%w: %s opcode 0x%X", ErrReplayMismatch, backend, req.Opcode)
+	}
+	p.used[i] = true
+	if justSend {
+		return Frame{}, nil
+	}
+
+	var match func(CaptureEntry) bool
+	if rpc {
+		recorded, err := p.entries[i].Frame()
+		if err != nil {
+			return Frame{}, err
+		}
+		if len(recorded.Payload) < 4 {
+			return Frame{}, fmt.Errorf("%w: rpc 0x%X gravada sem xid", ErrReplayMismatch, req.Opcode)
+		}
+		xid := binary.BigEndian.Uint32(recorded.Payload) &^ xidRequest
+		match = func(e CaptureEntry) bool {
+			f, err := e.Frame()
+			return err == nil && len(f.Payload) >= 4 && binary.BigEndian.Uint32(f.Payload) == xid
+		}
+	}
+
+	j := p.next(i+1, backend, CaptureResponse, respOpcode, match)
+	if j < 0 {
+		return Frame{}, fmt.Errorf("%w: resposta 0x%X do %s", ErrReplayMismatch, respOpcode, backend)
+	}
+	p.used[j] = true
+	return p.entries[j].Frame()
+}
+
+// next retorna o índice do primeiro registro não utilizado a partir de start, ou -1
+func (p *Replayer) next(start int, backend, dir string, opcode uint32, match func(CaptureEntry) bool) int {
+	for i := start; i < len(p.entries); i++ {
+		e := p.entries[i]
+		if p.used[i] || e.Backend != backend || e.Dir != dir || e.Opcode != opcode {
+			continue
+		}
+		if match == nil || match(e) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,284 @@
+package pwapi
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// DefaultPorts são as portas padrão dos serviços do Perfect World
+var DefaultPorts = map[string]int{
+	"gamedbd":    29400,
+	"gdeliveryd": 29100,
+	"provider":   29300,
+}
+
+// Client é uma conexão com um servidor de Perfect World
+//
+// Observações:
+//
+//	Cada Client possui seus próprios endereços, timeouts, pools de conexões e banco de dados,
+//	permitindo falar com vários servidores no mesmo processo
+//	As funções do pacote (GetRoleBase, SendMail, ...) utilizam o cliente padrão retornado por Default
+type Client struct {
+	ip       string
+	ports    map[string]int
+	timeouts map[string]TimeoutConfig
+	poolCfg  PoolConfig
+	dsn      string
+	db       *sql.DB
+	logger   *log.Logger
+	rec      *Recorder
+	replay   *Replayer
+	profile  string
+
+	retryCfg   RetryConfig
+	breakerCfg BreakerConfig
+	goldCfg    GoldConfig
+	breakersMu sync.Mutex
+	breakers   map[string]*breaker
+
+	poolsMu sync.Mutex
+	pools   map[string]*Pool
+
+	muxesMu sync.Mutex
+	muxes   map[string]*MuxConn
+}
+
+// Option configura um Client em NewClient
+type Option func(*Client)
+
+// WithAddress define o IP do servidor
+func WithAddress(ip string) Option {
+	return func(c *Client) { c.ip = ip }
+}
+
+// WithPort define a porta de um serviço (gamedbd, gdeliveryd ou provider)
+func WithPort(backend string, port int) Option {
+	return func(c *Client) { c.ports[backend] = port }
+}
+
+// WithTimeouts define os prazos de comunicação com um serviço
+func WithTimeouts(backend string, t TimeoutConfig) Option {
+	return func(c *Client) { c.timeouts[backend] = t }
+}
+
+// WithPoolConfig define o tamanho e o tempo ocioso dos pools de conexões
+func WithPoolConfig(p PoolConfig) Option {
+	return func(c *Client) { c.poolCfg = p }
+}
+
+// WithDSN define a string de conexão com o banco de dados MySQL, aberto em NewClient
+func WithDSN(dsn string) Option {
+	return func(c *Client) { c.dsn = dsn }
+}
+
+// WithDB utiliza um banco de dados já aberto, o Client não o fecha em Close
+func WithDB(db *sql.DB) Option {
+	return func(c *Client) { c.db = db }
+}
+
+// WithLogger define onde as mensagens de diagnóstico são escritas, por padrão são descartadas
+func WithLogger(l *log.Logger) Option {
+	return func(c *Client) { c.logger = l }
+}
+
+// WithRecorder grava em uma captura todos os frames trocados com os serviços
+func WithRecorder(r *Recorder) Option {
+	return func(c *Client) { c.rec = r }
+}
+
+// WithReplayer responde as requisições a partir de uma captura gravada por WithRecorder, sem acessar a rede
+func WithReplayer(r *Replayer) Option {
+	return func(c *Client) { c.replay = r }
+}
+
+// WithProfile define o perfil de versão do servidor utilizado para decodificar RoleBase e RoleStatus
+//
+// Parâmetros:
+//
+//	name: string - Nome de um perfil de Profiles (ex: 1.3.6) ou ProfileAuto para detectar pelo byte de versão
+//
+// Observações:
+//
+//	Sem perfil, os pacotes são decodificados com todos os campos e o byte de versão não é verificado
+//	Com um perfil, respostas com byte de versão diferente retornam *VersionError
+func WithProfile(name string) Option {
+	return func(c *Client) { c.profile = name }
+}
+
+// WithRetry define as novas tentativas de RoleBase, RoleStatus e OnlineList após falhas de comunicação
+func WithRetry(r RetryConfig) Option {
+	return func(c *Client) { c.retryCfg = r }
+}
+
+// WithCircuitBreaker define quando o circuito de um serviço abre e por quanto tempo as chamadas falham rápido
+func WithCircuitBreaker(b BreakerConfig) Option {
+	return func(c *Client) { c.breakerCfg = b }
+}
+
+// WithGold define a estratégia de entrega de gold e o multiplicador de cash, veja GoldConfig
+func WithGold(g GoldConfig) Option {
+	return func(c *Client) { c.goldCfg = g }
+}
+
+// WithConfig aplica o IP, as portas, os timeouts, os pools, as novas tentativas, o circuit breaker, a entrega
+// de gold e o perfil de versão de um Config
+//
+// Observações:
+//
+//	O banco de dados não é aberto, utilize também WithDSN(cfg.MySQL.DSN()) quando necessário
+//	Quando cfg.Debug é true as mensagens de diagnóstico são escritas na saída padrão
+func WithConfig(cfg Config) Option {
+	return func(c *Client) {
+		if cfg.IP != "" {
+			c.ip = cfg.IP
+		}
+		for backend, port := range cfg.Ports {
+			c.ports[backend] = port
+		}
+		for backend, t := range cfg.Timeouts {
+			c.timeouts[backend] = t
+		}
+		c.poolCfg = cfg.Conexoes
+		c.retryCfg = cfg.Tentativas
+		c.breakerCfg = cfg.Circuito
+		c.goldCfg = cfg.Gold
+		if cfg.VersaoServidor != "" {
+			c.profile = cfg.VersaoServidor
+		}
+		if cfg.Debug {
+			c.logger = log.New(os.Stdout, "", 0)
+		}
+	}
+}
+
+// NewClient cria um Client a partir das opções informadas
+//
+// Parâmetros:
+//
+//	opts: ...Option - Opções de configuração, aplicadas em ordem
+//
+// Retorno:
+//
+//	*Client - Cliente pronto para uso, as conexões com os serviços são abertas sob demanda
+//	error - Retorna um erro caso o perfil de WithProfile não exista ou o banco de dados informado em WithDSN
+//	não possa ser aberto
+func NewClient(opts ...Option) (*Client, error) {
+	c := &Client{
+		ip:       "127.0.0.1",
+		ports:    map[string]int{},
+		timeouts: map[string]TimeoutConfig{},
+		pools:    map[string]*Pool{},
+		muxes:    map[string]*MuxConn{},
+		breakers: map[string]*breaker{},
+	}
+	for backend, port := range DefaultPorts {
+		c.ports[backend] = port
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.logger == nil {
+		c.logger = log.New(io.Discard, "", 0)
+	}
+
+	if c.profile != "" && c.profile != ProfileAuto {
+		if _, err := LookupProfile(c.profile); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.dsn != "" && c.db == nil {
+		if err := c.openDB(c.dsn); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close fecha as conexões com os serviços e o banco de dados aberto por WithDSN
+func (c *Client) Close() error {
+	c.closeConns()
+	if c.dsn != "" {
+		return c.closeDB()
+	}
+	return nil
+}
+
+// addr retorna o endereço ip:porta de um serviço
+func (c *Client) addr(backend string) string {
+	return net.JoinHostPort(c.ip, strconv.Itoa(c.ports[backend]))
+}
+
+// backendFor retorna o nome do serviço que atende na porta informada, ou a própria porta quando desconhecida
+func (c *Client) backendFor(port int) string {
+	for backend, p := range c.ports {
+		if p == port {
+			return backend
+		}
+	}
+	return strconv.Itoa(port)
+}
+
+// debugf escreve uma mensagem de diagnóstico no logger do cliente
+func (c *Client) debugf(format string, args ...interface{}) {
+	c.logger.Printf(format, args...)
+}
+
+// DSN monta a string de conexão do driver MySQL
+func (m MySQLConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", m.Usuario, m.Senha, m.Host, m.DB)
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultClient *Client
+	defaultErr    error // erro de NewClient ao criar o cliente padrão a partir de AppConfig
+)
+
+// Default retorna o cliente utilizado pelas funções do pacote
+//
+// Observações:
+//
+//	O cliente é criado na primeira chamada a partir de AppConfig, portanto AppConfig deve estar carregado antes
+//	Retorna nil caso o cliente não possa ser criado (VersaoServidor desconhecida, por exemplo), o erro é
+//	retornado pelas funções do pacote e por DefaultErr
+func Default() *Client {
+	c, _ := loadDefault()
+	return c
+}
+
+// DefaultErr retorna o erro que impediu a criação do cliente padrão a partir de AppConfig, ou nil
+func DefaultErr() error {
+	_, err := loadDefault()
+	return err
+}
+
+// loadDefault retorna o cliente padrão, criando-o na primeira chamada, ou o erro retornado por NewClient
+func loadDefault() (*Client, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultClient == nil && defaultErr == nil {
+		defaultClient, defaultErr = NewClient(WithConfig(AppConfig))
+	}
+	return defaultClient, defaultErr
+}
+
+// SetDefault substitui o cliente utilizado pelas funções do pacote
+//
+// Observações:
+//
+//	SetDefault(nil) descarta o cliente e o erro atuais, o próximo uso recria o cliente a partir de AppConfig
+func SetDefault(c *Client) {
+	defaultMu.Lock()
+	defaultClient = c
+	defaultErr = nil
+	defaultMu.Unlock()
+}
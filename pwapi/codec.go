@@ -0,0 +1,618 @@
+package pwapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Erros base retornados pelo codec
+//
+// Observações:
+//
+//	Os erros retornados por Marshal e Unmarshal são sempre do tipo *CodecError e embrulham um destes erros,
+//	portanto podem ser verificados com errors.Is(err, ErrShortBuffer) e similares
+var (
+	// ErrShortBuffer indica que o pacote terminou antes de todos os campos serem lidos
+	ErrShortBuffer = errors.New("buffer insuficiente")
+
+	// ErrUnsupportedKind indica um tipo de campo que o codec não sabe empacotar
+	ErrUnsupportedKind = errors.New("tipo de campo não suportado")
+
+	// ErrBadCuint indica um compact uint malformado
+	ErrBadCuint = errors.New("cuint inválido")
+
+	// ErrBadTag indica uma tag `pw` inválida ou incompatível com o tipo do campo
+	ErrBadTag = errors.New("tag pw inválida")
+)
+
+// CodecError descreve uma falha ao empacotar ou desempacotar um campo de um pacote
+type CodecError struct {
+	Op     string // "marshal" ou "unmarshal"
+	Field  string // caminho do campo, ex: RoleBase.Forbid[0].Reason
+	Offset int    // posição no buffer onde a falha ocorreu
+	Err    error
+}
+
+func (e *CodecError) Error() string {
+	return fmt.Sprintf("pwapi: %s %s (offset %d): %v", e.Op, e.Field, e.Offset, e.Err)
+}
+
+func (e *CodecError) Unwrap() error {
+	return e.Err
+}
+
+var cuintType = reflect.TypeOf(Cuint(0))
+
+// Packer é implementado pelos structs gerados pelo pwgen, empacotados sem reflexão
+type Packer interface {
+	// AppendPW acrescenta o struct empacotado em b
+	AppendPW(b []byte) ([]byte, error)
+}
+
+// Unpacker é implementado pelos structs gerados pelo pwgen, desempacotados sem reflexão
+type Unpacker interface {
+	// UnpackPW desempacota o struct do início de data e retorna a quantidade de bytes consumidos
+	UnpackPW(data []byte) (int, error)
+}
+
+// Marshal empacota um struct no formato binário utilizado pelos serviços do Perfect World
+//
+// Parâmetros:
+//
+//	v: interface{} - Struct (ou ponteiro para struct) a ser empacotado
+//
+// Retorno:
+//
+//	[]byte - Bytes do pacote, sem o cabeçalho
+//	error - *CodecError caso algum campo não possa ser empacotado
+//
+// Observações:
+//
+//	Inteiros são gravados em big-endian, strings em UTF-16LE e slices são precedidos pelo tamanho em compact uint
+//	A representação de cada campo pode ser alterada pela tag `pw`, veja wireType para o vocabulário completo
+//	Structs que implementam Packer (gerados a partir de protocol.yaml) são empacotados sem reflexão
+func Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, &CodecError{Op: "marshal", Field: "<nil>", Err: ErrUnsupportedKind}
+		}
+		rv = rv.Elem()
+	}
+	if p, ok := v.(Packer); ok {
+		return p.AppendPW(nil)
+	}
+	return marshalValue(rv)
+}
+
+// marshalValue empacota um struct por reflexão a partir do seu plano
+func marshalValue(rv reflect.Value) ([]byte, error) {
+	if rv.Kind() != reflect.Struct {
+		return nil, &CodecError{Op: "marshal", Field: rv.Type().String(), Err: ErrUnsupportedKind}
+	}
+
+	p, err := planFor(rv.Type())
+	if err != nil {
+		return nil, withOp(err, "marshal")
+	}
+
+	e := &encoder{}
+	if err := e.encodeStruct(p, rv); err != nil {
+		return nil, prefixField(err, p.name)
+	}
+	return e.buf, nil
+}
+
+// Unmarshal desempacota os bytes de um pacote em um struct
+//
+// Parâmetros:
+//
+//	data: []byte - Bytes do pacote, sem o cabeçalho
+//	v: interface{} - Ponteiro não nulo para o struct de destino
+//
+// Retorno:
+//
+//	[]byte - Bytes restantes após o último campo do struct
+//	error - *CodecError caso o pacote esteja truncado ou malformado
+//
+// Observações:
+//
+//	Todas as leituras verificam os limites do buffer, um pacote truncado nunca causa panic
+//	Structs que implementam Unpacker (gerados a partir de protocol.yaml) são desempacotados sem reflexão
+func Unmarshal(data []byte, v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return data, &CodecError{Op: "unmarshal", Field: fmt.Sprintf("%T", v), Err: ErrUnsupportedKind}
+	}
+	if u, ok := v.(Unpacker); ok {
+		n, err := u.UnpackPW(data)
+		return data[n:], err
+	}
+	return unmarshalValue(data, rv.Elem())
+}
+
+// unmarshalValue desempacota um struct por reflexão a partir do seu plano
+func unmarshalValue(data []byte, rv reflect.Value) ([]byte, error) {
+	if rv.Kind() != reflect.Struct {
+		return data, &CodecError{Op: "unmarshal", Field: rv.Type().String(), Err: ErrUnsupportedKind}
+	}
+
+	p, err := planFor(rv.Type())
+	if err != nil {
+		return data, withOp(err, "unmarshal")
+	}
+
+	d := &decoder{data: data}
+	if err := d.decodeStruct(p, rv); err != nil {
+		return data[d.off:], prefixField(err, p.name)
+	}
+	return data[d.off:], nil
+}
+
+// encoder acumula os bytes de um pacote durante o Marshal
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) encodeStruct(p *structPlan, v reflect.Value) error {
+	for _, f := range p.fields {
+		field := v.Field(f.index)
+
+		// Campos que carregam a quantidade de elementos de um slice são preenchidos automaticamente
+		if f.lenFor >= 0 {
+			field = reflect.ValueOf(int64(v.Field(f.lenFor).Len()))
+		}
+
+		var err error
+		if f.lenFrom >= 0 {
+			err = e.encodeArray(f.codec, field, false)
+		} else {
+			err = e.encode(f.codec, field)
+		}
+		if err != nil {
+			return prefixField(err, "."+f.name)
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encode(c *fieldCodec, v reflect.Value) error {
+	switch c.wire {
+	case wireInt8, wireUint8:
+		e.buf = append(e.buf, byte(integerOf(v)))
+	case wireInt16, wireUint16:
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(integerOf(v)))
+	case wireInt32, wireUint32:
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(integerOf(v)))
+	case wireInt64, wireUint64:
+		e.buf = binary.BigEndian.AppendUint64(e.buf, integerOf(v))
+	case wireCuint:
+		e.buf = AppendCuint(e.buf, uint32(integerOf(v)))
+	case wireFloat32:
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(v.Float())))
+	case wireFloat64:
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+	case wireOctets:
+		return e.writeBytes(c, bytesOf(v))
+	case wireUTF16, wireGBK:
+		return e.text(v.String(), c.wire, c.fixed)
+	case wireArray:
+		return e.encodeArray(c, v, v.Kind() == reflect.Slice)
+	case wireStruct:
+		return e.encodeStruct(c.plan, v)
+	default:
+		return &CodecError{Op: "marshal", Offset: len(e.buf), Err: fmt.Errorf("%w: %s", ErrUnsupportedKind, v.Kind())}
+	}
+	return nil
+}
+
+// Métodos utilizados pelo código gerado pelo pwgen
+
+func (e *encoder) uint8(v uint8)   { e.buf = append(e.buf, v) }
+func (e *encoder) uint16(v uint16) { e.buf = binary.BigEndian.AppendUint16(e.buf, v) }
+func (e *encoder) uint32(v uint32) { e.buf = binary.BigEndian.AppendUint32(e.buf, v) }
+func (e *encoder) uint64(v uint64) { e.buf = binary.BigEndian.AppendUint64(e.buf, v) }
+func (e *encoder) cuint(v uint32)  { e.buf = AppendCuint(e.buf, v) }
+
+func (e *encoder) float32(v float32) { e.uint32(math.Float32bits(v)) }
+func (e *encoder) float64(v float64) { e.uint64(math.Float64bits(v)) }
+
+// octets grava bytes precedidos pelo tamanho, ou completados com zeros quando fixed > 0
+func (e *encoder) octets(b []byte, fixed int) error {
+	return e.writeBytes(&fieldCodec{wire: wireOctets, fixed: fixed}, b)
+}
+
+// text grava uma string na codificação de w (wireUTF16 ou wireGBK)
+func (e *encoder) text(s string, w wireType, fixed int) error {
+	encoded, err := textEncoding(w).NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		return &CodecError{Op: "marshal", Offset: len(e.buf), Err: err}
+	}
+	return e.writeBytes(&fieldCodec{wire: w, fixed: fixed}, encoded)
+}
+
+// writeBytes grava octets, precedidos pelo tamanho ou completados com zeros até o tamanho fixo
+func (e *encoder) writeBytes(c *fieldCodec, b []byte) error {
+	if c.fixed == 0 {
+		e.buf = AppendCuint(e.buf, uint32(len(b)))
+		e.buf = append(e.buf, b...)
+		return nil
+	}
+	if len(b) > c.fixed {
+		return &CodecError{Op: "marshal", Offset: len(e.buf),
+			Err: fmt.Errorf("%w: %d bytes não cabem em fixed=%d", ErrShortBuffer, len(b), c.fixed)}
+	}
+	e.buf = append(e.buf, b...)
+	e.buf = append(e.buf, make([]byte, c.fixed-len(b))...)
+	return nil
+}
+
+// encodeArray grava os elementos de um slice ou array, com o prefixo de quantidade quando prefixed é true
+func (e *encoder) encodeArray(c *fieldCodec, v reflect.Value, prefixed bool) error {
+	if prefixed {
+		e.buf = AppendCuint(e.buf, uint32(v.Len()))
+	}
+	for j := 0; j < v.Len(); j++ {
+		if err := e.encode(c.elem, v.Index(j)); err != nil {
+			return prefixField(err, fmt.Sprintf("[%d]", j))
+		}
+	}
+	return nil
+}
+
+// decoder percorre os bytes de um pacote durante o Unmarshal
+type decoder struct {
+	data []byte
+	off  int
+
+	// spans recebe a posição de cada campo lido quando trace é true, utilizado pelo Decode
+	trace bool
+	depth int
+	spans []FieldSpan
+}
+
+// FieldSpan descreve a posição de um campo desempacotado no payload
+type FieldSpan struct {
+	Name  string        // nome do campo, ou [i] para elementos de slices
+	Depth int           // nível do campo, 0 para os campos do struct raiz
+	Start int           // posição do primeiro byte do campo
+	End   int           // posição após o último byte lido, igual a Start quando a leitura falhou
+	Value reflect.Value // valor desempacotado
+}
+
+// enter registra o início de um campo e retorna o índice do seu FieldSpan, ou -1 sem trace
+func (d *decoder) enter(name string, v reflect.Value) int {
+	if !d.trace {
+		return -1
+	}
+	d.spans = append(d.spans, FieldSpan{Name: name, Depth: d.depth, Start: d.off, End: d.off, Value: v})
+	d.depth++
+	return len(d.spans) - 1
+}
+
+// leave registra o fim do campo iniciado por enter
+func (d *decoder) leave(i int) {
+	if i < 0 {
+		return
+	}
+	d.depth--
+	d.spans[i].End = d.off
+}
+
+// take consome n bytes do buffer, verificando os limites
+func (d *decoder) take(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.off < n {
+		return nil, &CodecError{Op: "unmarshal", Offset: d.off,
+			Err: fmt.Errorf("%w: precisa de %d bytes, restam %d", ErrShortBuffer, n, len(d.data)-d.off)}
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+// cuint lê um compact uint do buffer
+func (d *decoder) cuint() (int, error) {
+	v, size, err := DecodeCuint(d.data[d.off:])
+	if err != nil {
+		return 0, &CodecError{Op: "unmarshal", Offset: d.off, Err: err}
+	}
+	d.off += size
+	return int(v), nil
+}
+
+// count valida a quantidade de elementos de um slice contra o restante do buffer
+func (d *decoder) count(n int) (int, error) {
+	if n < 0 || n > len(d.data)-d.off {
+		return 0, &CodecError{Op: "unmarshal", Offset: d.off,
+			Err: fmt.Errorf("%w: %d elementos declarados, restam %d bytes", ErrShortBuffer, n, len(d.data)-d.off)}
+	}
+	return n, nil
+}
+
+// Métodos utilizados pelo código gerado pelo pwgen
+
+func (d *decoder) uint8() (uint8, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *decoder) uint16() (uint16, error) {
+	b, err := d.take(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	b, err := d.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (d *decoder) uint64() (uint64, error) {
+	b, err := d.take(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (d *decoder) float32() (float32, error) {
+	u, err := d.uint32()
+	return math.Float32frombits(u), err
+}
+
+func (d *decoder) float64() (float64, error) {
+	u, err := d.uint64()
+	return math.Float64frombits(u), err
+}
+
+// octets lê bytes precedidos pelo tamanho, ou exatamente fixed bytes, retornando uma cópia
+func (d *decoder) octets(fixed int) ([]byte, error) {
+	b, err := d.readBytes(&fieldCodec{wire: wireOctets, fixed: fixed})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+// text lê uma string na codificação de w (wireUTF16 ou wireGBK)
+func (d *decoder) text(w wireType, fixed int) (string, error) {
+	start := d.off
+	b, err := d.readBytes(&fieldCodec{wire: w, fixed: fixed})
+	if err != nil {
+		return "", err
+	}
+	decoded, err := textEncoding(w).NewDecoder().Bytes(b)
+	if err != nil {
+		return "", &CodecError{Op: "unmarshal", Offset: start, Err: err}
+	}
+	s := string(decoded)
+	if fixed > 0 {
+		s = strings.TrimRight(s, "\x00")
+	}
+	return s, nil
+}
+
+func (d *decoder) decodeStruct(p *structPlan, v reflect.Value) error {
+	for _, f := range p.fields {
+		field := v.Field(f.index)
+
+		span := d.enter(f.name, field)
+		var err error
+		if f.lenFrom >= 0 {
+			err = d.decodeArray(f.codec, field, int(integerOf(v.Field(f.lenFrom))))
+		} else {
+			err = d.decode(f.codec, field)
+		}
+		d.leave(span)
+		if err != nil {
+			return prefixField(err, "."+f.name)
+		}
+	}
+	return nil
+}
+
+func (d *decoder) decode(c *fieldCodec, v reflect.Value) error {
+	switch c.wire {
+	case wireInt8, wireUint8:
+		b, err := d.take(1)
+		if err != nil {
+			return err
+		}
+		setInteger(v, uint64(b[0]), int64(int8(b[0])), c.wire == wireInt8)
+	case wireInt16, wireUint16:
+		b, err := d.take(2)
+		if err != nil {
+			return err
+		}
+		u := binary.BigEndian.Uint16(b)
+		setInteger(v, uint64(u), int64(int16(u)), c.wire == wireInt16)
+	case wireInt32, wireUint32:
+		b, err := d.take(4)
+		if err != nil {
+			return err
+		}
+		u := binary.BigEndian.Uint32(b)
+		setInteger(v, uint64(u), int64(int32(u)), c.wire == wireInt32)
+	case wireInt64, wireUint64:
+		b, err := d.take(8)
+		if err != nil {
+			return err
+		}
+		u := binary.BigEndian.Uint64(b)
+		setInteger(v, u, int64(u), c.wire == wireInt64)
+	case wireCuint:
+		n, err := d.cuint()
+		if err != nil {
+			return err
+		}
+		setInteger(v, uint64(n), int64(n), true)
+	case wireFloat32:
+		b, err := d.take(4)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(b))))
+	case wireFloat64:
+		b, err := d.take(8)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(b)))
+	case wireOctets:
+		b, err := d.readBytes(c)
+		if err != nil {
+			return err
+		}
+		setBytes(v, b)
+	case wireUTF16, wireGBK:
+		s, err := d.text(c.wire, c.fixed)
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case wireArray:
+		if v.Kind() == reflect.Array {
+			return d.decodeArray(c, v, v.Len())
+		}
+		n, err := d.cuint()
+		if err != nil {
+			return err
+		}
+		return d.decodeArray(c, v, n)
+	case wireStruct:
+		return d.decodeStruct(c.plan, v)
+	default:
+		return &CodecError{Op: "unmarshal", Offset: d.off, Err: fmt.Errorf("%w: %s", ErrUnsupportedKind, v.Kind())}
+	}
+	return nil
+}
+
+// readBytes lê octets precedidos pelo tamanho em cuint, ou exatamente c.fixed bytes
+func (d *decoder) readBytes(c *fieldCodec) ([]byte, error) {
+	if c.fixed > 0 {
+		return d.take(c.fixed)
+	}
+	n, err := d.cuint()
+	if err != nil {
+		return nil, err
+	}
+	return d.take(n)
+}
+
+// decodeArray lê n elementos para um slice ou array
+func (d *decoder) decodeArray(c *fieldCodec, v reflect.Value, n int) error {
+	if _, err := d.count(n); err != nil {
+		return err
+	}
+	switch {
+	case v.Kind() == reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+	case n > v.Len():
+		// Ignorar os elementos excedentes desalinharia a leitura dos campos seguintes
+		return &CodecError{Op: "unmarshal", Offset: d.off,
+			Err: fmt.Errorf("%w: %d elementos declarados, o array comporta %d", ErrShortBuffer, n, v.Len())}
+	}
+	for j := 0; j < n; j++ {
+		span := d.enter(fmt.Sprintf("[%d]", j), v.Index(j))
+		err := d.decode(c.elem, v.Index(j))
+		d.leave(span)
+		if err != nil {
+			return prefixField(err, fmt.Sprintf("[%d]", j))
+		}
+	}
+	return nil
+}
+
+// integerOf retorna o valor de um campo inteiro como uint64, preservando o padrão de bits de valores negativos
+func integerOf(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	default:
+		return uint64(v.Int())
+	}
+}
+
+// setInteger atribui um inteiro lido do pacote respeitando o sinal da representação e do campo de destino
+func setInteger(v reflect.Value, u uint64, i int64, signed bool) {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(u)
+	default:
+		if signed {
+			v.SetInt(i)
+		} else {
+			v.SetInt(int64(u))
+		}
+	}
+}
+
+// bytesOf retorna o conteúdo de um campo string, []byte ou [N]byte
+func bytesOf(v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.String:
+		return []byte(v.String())
+	case reflect.Array:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return b
+	}
+	return v.Bytes()
+}
+
+// setBytes copia os octets lidos para um campo string, []byte ou [N]byte
+func setBytes(v reflect.Value, b []byte) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(string(b))
+	case reflect.Array:
+		reflect.Copy(v, reflect.ValueOf(b))
+	default:
+		v.SetBytes(append([]byte{}, b...))
+	}
+}
+
+// prefixField acrescenta um trecho ao início do caminho do campo de um *CodecError
+func prefixField(err error, prefix string) error {
+	var ce *CodecError
+	if errors.As(err, &ce) {
+		ce.Field = prefix + ce.Field
+	}
+	return err
+}
+
+// withOp define a operação de um *CodecError gerado durante a construção do plano
+func withOp(err error, op string) error {
+	var ce *CodecError
+	if errors.As(err, &ce) && ce.Op == "" {
+		ce.Op = op
+	}
+	return err
+}
+
+// utf16le é a codificação utilizada pelo Perfect World para strings
+var utf16le = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+
+// textEncoding retorna a codificação de texto de uma representação de string
+func textEncoding(w wireType) encoding.Encoding {
+	if w == wireGBK {
+		return simplifiedchinese.GBK
+	}
+	return utf16le
+}
@@ -0,0 +1,313 @@
+package pwapi
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type codecItem struct {
+	ID   int32
+	Name string
+}
+
+type codecPacket struct {
+	RoleID int32
+	Level  uint16
+	Name   string
+	Items  []codecItem
+	Data   []byte
+}
+
+type tagWidths struct {
+	A int    `pw:"int8"`
+	B int    `pw:"uint16"`
+	C int64  `pw:"int32"`
+	D uint32 `pw:"uint64"`
+	E int    `pw:"int16"`
+	F uint8  `pw:"byte"`
+}
+
+type tagCuint struct {
+	Small int    `pw:"cuint"`
+	Large uint32 `pw:"cuint"`
+}
+
+type tagText struct {
+	UTF16 string `pw:"utf16"`
+	Alias string `pw:"string"`
+	GBK   string `pw:"gbk"`
+	Raw   string `pw:"octets"`
+}
+
+type tagFixed struct {
+	Name  string `pw:"fixed=6"`
+	Title string `pw:"gbk,fixed=4"`
+	Key   []byte `pw:"octets,fixed=3"`
+	Hash  [2]byte
+}
+
+type tagLen struct {
+	Count uint16
+	Level int32
+	Items []int32 `pw:"array,len=Count"`
+}
+
+type tagSkip struct {
+	RoleID int32
+	Cache  map[string]int `pw:"-"`
+	Note   string         `pw:"-"`
+	Level  int32
+}
+
+type tagArray struct {
+	Items [2]int32
+	Level int32
+}
+
+type tagArrayLen struct {
+	Count uint8
+	Items [2]int32 `pw:"array,len=Count"`
+	Level int32
+}
+
+type badTagOption struct {
+	Level int32 `pw:"int24"`
+}
+
+type badTagFixed struct {
+	Level int32 `pw:"fixed=4"`
+}
+
+type badTagFixedSize struct {
+	Name string `pw:"fixed=x"`
+}
+
+type badTagWidth struct {
+	Name string `pw:"int32"`
+}
+
+type badTagLenType struct {
+	Name  string
+	Items []int32 `pw:"array,len=Name"`
+}
+
+type badTagLenLater struct {
+	Items []int32 `pw:"array,len=Count"`
+	Count int32
+}
+
+type badTagLenScalar struct {
+	Count int32
+	Level int32 `pw:"len=Count"`
+}
+
+type codecUnsupported struct {
+	RoleID int32
+	Attrs  map[string]int
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	packet := codecPacket{RoleID: 1024, Level: 105, Name: "ab", Items: []codecItem{{ID: 7, Name: "c"}}, Data: []byte{1, 2}}
+	data, err := Marshal(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 22 {
+		t.Fatalf("Marshal = % X, esperado 22 bytes", data)
+	}
+
+	// Cada corte interrompe a leitura de um campo diferente
+	tests := []struct {
+		cut    int
+		field  string
+		offset int
+	}{
+		{0, "codecPacket.RoleID", 0},
+		{3, "codecPacket.RoleID", 0},
+		{5, "codecPacket.Level", 4},
+		{8, "codecPacket.Name", 7},
+		{14, "codecPacket.Items[0].ID", 12},
+		{17, "codecPacket.Items[0].Name", 17},
+		{21, "codecPacket.Data", 20},
+	}
+
+	for _, tt := range tests {
+		rest, err := Unmarshal(data[:tt.cut], &codecPacket{})
+		var ce *CodecError
+		if !errors.As(err, &ce) {
+			t.Errorf("Unmarshal com %d bytes = %v, esperado *CodecError", tt.cut, err)
+			continue
+		}
+		if ce.Op != "unmarshal" || ce.Field != tt.field || ce.Offset != tt.offset || !errors.Is(err, ErrShortBuffer) {
+			t.Errorf("Unmarshal com %d bytes = %+v, esperado ErrShortBuffer em %s (offset %d)", tt.cut, ce, tt.field, tt.offset)
+		}
+		if len(rest) > tt.cut {
+			t.Errorf("Unmarshal com %d bytes retornou %d bytes restantes", tt.cut, len(rest))
+		}
+	}
+
+	// Nenhum prefixo do pacote é aceito ou causa panic
+	for n := 0; n < len(data); n++ {
+		if _, err := Unmarshal(data[:n], &codecPacket{}); err == nil {
+			t.Errorf("Unmarshal com %d de %d bytes não retornou erro", n, len(data))
+		}
+	}
+}
+
+func TestCodecUnsupportedKind(t *testing.T) {
+	_, err := Marshal(codecUnsupported{RoleID: 1, Attrs: map[string]int{"str": 5}})
+	var ce *CodecError
+	if !errors.As(err, &ce) || ce.Op != "marshal" || ce.Field != "codecUnsupported.Attrs" || !errors.Is(err, ErrUnsupportedKind) {
+		t.Errorf("Marshal com um map = %v, esperado ErrUnsupportedKind em codecUnsupported.Attrs", err)
+	}
+
+	_, err = Unmarshal([]byte{0, 0, 0, 1, 0}, &codecUnsupported{})
+	if !errors.As(err, &ce) || ce.Op != "unmarshal" || ce.Field != "codecUnsupported.Attrs" || !errors.Is(err, ErrUnsupportedKind) {
+		t.Errorf("Unmarshal com um map = %v, esperado ErrUnsupportedKind em codecUnsupported.Attrs", err)
+	}
+
+	// O destino precisa ser um ponteiro para struct
+	var roleID int32
+	tests := []interface{}{codecPacket{}, (*codecPacket)(nil), &roleID}
+	for _, v := range tests {
+		if _, err := Unmarshal([]byte{0, 0, 0, 1}, v); !errors.As(err, &ce) || ce.Op != "unmarshal" || !errors.Is(err, ErrUnsupportedKind) {
+			t.Errorf("Unmarshal em %T = %v, esperado ErrUnsupportedKind", v, err)
+		}
+	}
+	if _, err := Marshal(roleID); !errors.As(err, &ce) || ce.Op != "marshal" || !errors.Is(err, ErrUnsupportedKind) {
+		t.Errorf("Marshal de um int32 = %v, esperado ErrUnsupportedKind", err)
+	}
+}
+
+func TestTagVocabulary(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []byte
+	}{
+		{"larguras explícitas", tagWidths{A: -2, B: 0x1234, C: -1, D: 7, E: -300, F: 0xFE},
+			[]byte{0xFE, 0x12, 0x34, 0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0, 0, 0, 0, 7, 0xFE, 0xD4, 0xFE}},
+		{"cuint", tagCuint{Small: 0x3F, Large: 0x18A}, []byte{0x3F, 0x81, 0x8A}},
+		{"utf16, gbk e octets", tagText{UTF16: "中", Alias: "a", GBK: "中", Raw: "ab"},
+			[]byte{0x02, 0x2D, 0x4E, 0x02, 0x61, 0x00, 0x02, 0xD6, 0xD0, 0x02, 0x61, 0x62}},
+		{"fixed completado com zeros", tagFixed{Name: "ab", Title: "中", Key: []byte{9, 0, 0}, Hash: [2]byte{1, 2}},
+			[]byte{0x61, 0x00, 0x62, 0x00, 0x00, 0x00, 0xD6, 0xD0, 0x00, 0x00, 0x09, 0x00, 0x00, 0x01, 0x02}},
+		{"array,len=Campo", tagLen{Count: 2, Level: 9, Items: []int32{1, -1}},
+			[]byte{0x00, 0x02, 0, 0, 0, 9, 0, 0, 0, 1, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"campos ignorados", tagSkip{RoleID: 1, Level: 2}, []byte{0, 0, 0, 1, 0, 0, 0, 2}},
+		{"array de tamanho fixo", tagArray{Items: [2]int32{3, 4}, Level: 5},
+			[]byte{0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0, 5}},
+	}
+
+	for _, tt := range tests {
+		got, err := Marshal(tt.value)
+		if err != nil {
+			t.Errorf("%s: Marshal: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: Marshal = % X, esperado % X", tt.name, got, tt.want)
+		}
+
+		decoded := reflect.New(reflect.TypeOf(tt.value))
+		rest, err := Unmarshal(tt.want, decoded.Interface())
+		if err != nil || len(rest) != 0 {
+			t.Errorf("%s: Unmarshal = %v, %d bytes restantes", tt.name, err, len(rest))
+			continue
+		}
+		if !reflect.DeepEqual(decoded.Elem().Interface(), tt.value) {
+			t.Errorf("%s: Unmarshal = %+v, esperado %+v", tt.name, decoded.Elem().Interface(), tt.value)
+		}
+	}
+}
+
+func TestTagLenIsFilledFromSlice(t *testing.T) {
+	// O campo de quantidade é gravado a partir do slice, mesmo desatualizado no struct
+	got, err := Marshal(tagLen{Count: 9, Items: []int32{7}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x00, 0x01, 0, 0, 0, 0, 0, 0, 0, 7}; !bytes.Equal(got, want) {
+		t.Errorf("Marshal = % X, esperado % X", got, want)
+	}
+
+	// Campos ignorados não são alterados pelo Unmarshal
+	skip := tagSkip{Note: "mantida"}
+	if _, err := Unmarshal([]byte{0, 0, 0, 1, 0, 0, 0, 2}, &skip); err != nil || skip.Note != "mantida" || skip.Level != 2 {
+		t.Errorf("Unmarshal com pw:\"-\" = %+v, %v", skip, err)
+	}
+}
+
+func TestTagFixedTruncation(t *testing.T) {
+	// Um valor maior que o tamanho fixo não é cortado silenciosamente
+	tests := []tagFixed{
+		{Name: "abcd"},
+		{Title: "中文字"},
+		{Key: []byte{1, 2, 3, 4}},
+	}
+	for _, v := range tests {
+		_, err := Marshal(v)
+		var ce *CodecError
+		if !errors.As(err, &ce) || ce.Op != "marshal" || !errors.Is(err, ErrShortBuffer) {
+			t.Errorf("Marshal(%+v) = %v, esperado ErrShortBuffer", v, err)
+		}
+	}
+
+	// Na leitura o preenchimento com zeros é removido das strings
+	var v tagFixed
+	data := []byte{0x61, 0x00, 0x00, 0x00, 0x00, 0x00, 0x61, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}
+	if _, err := Unmarshal(data, &v); err != nil || v.Name != "a" || v.Title != "a" || !bytes.Equal(v.Key, []byte{1, 0, 0}) {
+		t.Errorf("Unmarshal com fixed = %+v, %v", v, err)
+	}
+	if _, err := Unmarshal(data[:8], &v); !errors.Is(err, ErrShortBuffer) {
+		t.Errorf("Unmarshal com fixed truncado = %v, esperado ErrShortBuffer", err)
+	}
+}
+
+func TestBadTag(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		field string
+	}{
+		{badTagOption{}, "badTagOption.Level"},
+		{badTagFixed{}, "badTagFixed.Level"},
+		{badTagFixedSize{}, "badTagFixedSize.Name"},
+		{badTagWidth{}, "badTagWidth.Name"},
+		{badTagLenType{}, "badTagLenType.Items"},
+		{badTagLenLater{}, "badTagLenLater.Items"},
+		{badTagLenScalar{}, "badTagLenScalar.Level"},
+	}
+
+	for _, tt := range tests {
+		_, err := Marshal(tt.value)
+		var ce *CodecError
+		if !errors.As(err, &ce) || ce.Op != "marshal" || ce.Field != tt.field || !errors.Is(err, ErrBadTag) {
+			t.Errorf("Marshal(%T) = %v, esperado ErrBadTag em %s", tt.value, err, tt.field)
+		}
+
+		// O plano inválido não fica em cache
+		target := reflect.New(reflect.TypeOf(tt.value)).Interface()
+		_, err = Unmarshal(make([]byte, 16), target)
+		if !errors.As(err, &ce) || ce.Op != "unmarshal" || ce.Field != tt.field || !errors.Is(err, ErrBadTag) {
+			t.Errorf("Unmarshal(%T) = %v, esperado ErrBadTag em %s", target, err, tt.field)
+		}
+	}
+}
+
+func TestUnmarshalArrayOverflow(t *testing.T) {
+	// Três elementos declarados em Count para um array de dois
+	data := []byte{0x03, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0, 5, 0, 0, 0, 6}
+	_, err := Unmarshal(data, &tagArrayLen{})
+	var ce *CodecError
+	if !errors.As(err, &ce) || ce.Op != "unmarshal" || ce.Field != "tagArrayLen.Items" || !errors.Is(err, ErrShortBuffer) {
+		t.Errorf("Unmarshal com mais elementos que o array = %v, esperado *CodecError em tagArrayLen.Items", err)
+	}
+
+	// Menos elementos que o array deixam os demais zerados
+	var v tagArrayLen
+	if _, err := Unmarshal([]byte{0x01, 0, 0, 0, 3, 0, 0, 0, 5}, &v); err != nil || v.Items != [2]int32{3, 0} || v.Level != 5 {
+		t.Errorf("Unmarshal com menos elementos que o array = %+v, %v", v, err)
+	}
+}
@@ -0,0 +1,117 @@
+package pwapi
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Cuint é um inteiro não negativo no formato compact uint utilizado pelos protocolos do Perfect World
+//
+// Observações:
+//
+//	O tamanho do valor no pacote depende da sua magnitude:
+//	0x00000000 - 0x0000003F: 1 byte  (0xxxxxxx)
+//	0x00000040 - 0x00003FFF: 2 bytes (10xxxxxx xxxxxxxx)
+//	0x00004000 - 0x1FFFFFFF: 4 bytes (110xxxxx xxxxxxxx xxxxxxxx xxxxxxxx)
+//	0x20000000 - 0xFFFFFFFF: 5 bytes (0xE0 seguido do valor em 32 bits)
+type Cuint uint32
+
+// PwCuint é mantido por compatibilidade, prefira Cuint
+type PwCuint = Cuint
+
+// AppendCuint acrescenta v no formato compact uint ao final de dst
+//
+// Parâmetros:
+//
+//	dst: []byte - Slice de destino, pode ser nil
+//	v: uint32 - Valor a ser codificado
+//
+// Retorno:
+//
+//	[]byte - Slice dst acrescido de 1, 2, 4 ou 5 bytes
+func AppendCuint(dst []byte, v uint32) []byte {
+	switch {
+	case v < 0x40:
+		return append(dst, byte(v))
+	case v < 0x4000:
+		return binary.BigEndian.AppendUint16(dst, uint16(v)|0x8000)
+	case v < 0x20000000:
+		return binary.BigEndian.AppendUint32(dst, v|0xC0000000)
+	}
+	return binary.BigEndian.AppendUint32(append(dst, 0xE0), v)
+}
+
+// DecodeCuint lê um compact uint do início de data
+//
+// Parâmetros:
+//
+//	data: []byte - Bytes a partir do compact uint
+//
+// Retorno:
+//
+//	uint32 - Valor decodificado
+//	int - Quantidade de bytes consumidos
+//	error - ErrShortBuffer caso faltem bytes, ErrBadCuint caso o primeiro byte seja inválido
+func DecodeCuint(data []byte) (uint32, int, error) {
+	if len(data) == 0 {
+		return 0, 0, fmt.Errorf("%w: cuint vazio", ErrShortBuffer)
+	}
+
+	b := data[0]
+	size := 1
+	switch {
+	case b < 0x80:
+		return uint32(b), 1, nil
+	case b < 0xC0:
+		size = 2
+	case b < 0xE0:
+		size = 4
+	case b == 0xE0:
+		size = 5
+	default:
+		return 0, 0, fmt.Errorf("%w: primeiro byte 0x%02X", ErrBadCuint, b)
+	}
+
+	if len(data) < size {
+		return 0, 0, fmt.Errorf("%w: cuint de %d bytes, restam %d", ErrShortBuffer, size, len(data))
+	}
+
+	switch size {
+	case 2:
+		return uint32(binary.BigEndian.Uint16(data) & 0x3FFF), 2, nil
+	case 4:
+		return binary.BigEndian.Uint32(data) & 0x1FFFFFFF, 4, nil
+	}
+	return binary.BigEndian.Uint32(data[1:]), 5, nil
+}
+
+// CuintSize retorna a quantidade de bytes ocupada por v no formato compact uint
+func CuintSize(v uint32) int {
+	switch {
+	case v < 0x40:
+		return 1
+	case v < 0x4000:
+		return 2
+	case v < 0x20000000:
+		return 4
+	}
+	return 5
+}
+
+// MarshalBinary implementa encoding.BinaryMarshaler
+func (c Cuint) MarshalBinary() ([]byte, error) {
+	return AppendCuint(nil, uint32(c)), nil
+}
+
+// UnmarshalBinary implementa encoding.BinaryUnmarshaler, data deve conter exatamente um compact uint
+func (c *Cuint) UnmarshalBinary(data []byte) error {
+	v, n, err := DecodeCuint(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("%w: %d bytes sobrando após o valor", ErrBadCuint, len(data)-n)
+	}
+	*c = Cuint(v)
+	return nil
+}
@@ -0,0 +1,95 @@
+package pwapi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCuintRoundTrip(t *testing.T) {
+	tests := []struct {
+		value uint32
+		size  int
+	}{
+		{0, 1},
+		{63, 1},
+		{64, 2},
+		{16383, 2},
+		{16384, 4},
+		{1<<29 - 1, 4},
+		{1 << 29, 5},
+		{1<<32 - 1, 5},
+	}
+
+	for _, tt := range tests {
+		encoded := AppendCuint(nil, tt.value)
+		if len(encoded) != tt.size {
+			t.Errorf("AppendCuint(%d) = % X, esperado %d bytes", tt.value, encoded, tt.size)
+		}
+		if CuintSize(tt.value) != tt.size {
+			t.Errorf("CuintSize(%d) = %d, esperado %d", tt.value, CuintSize(tt.value), tt.size)
+		}
+
+		got, n, err := DecodeCuint(append(encoded, 0xFF))
+		if err != nil {
+			t.Fatalf("DecodeCuint(% X): %v", encoded, err)
+		}
+		if got != tt.value || n != tt.size {
+			t.Errorf("DecodeCuint(% X) = %d, %d; esperado %d, %d", encoded, got, n, tt.value, tt.size)
+		}
+
+		var c Cuint
+		if err := c.UnmarshalBinary(encoded); err != nil || uint32(c) != tt.value {
+			t.Errorf("Cuint.UnmarshalBinary(% X) = %d, %v", encoded, c, err)
+		}
+		if b, _ := Cuint(tt.value).MarshalBinary(); !bytes.Equal(b, encoded) {
+			t.Errorf("Cuint(%d).MarshalBinary() = % X, esperado % X", tt.value, b, encoded)
+		}
+	}
+}
+
+func TestCuintKnownEncodings(t *testing.T) {
+	tests := []struct {
+		value   uint32
+		encoded []byte
+	}{
+		{0x3F, []byte{0x3F}},
+		{0x40, []byte{0x80, 0x40}},
+		{0x18A, []byte{0x81, 0x8A}},
+		{0x3FFF, []byte{0xBF, 0xFF}},
+		{0x4000, []byte{0xC0, 0x00, 0x40, 0x00}},
+		{0x1FFFFFFF, []byte{0xDF, 0xFF, 0xFF, 0xFF}},
+		{0x20000000, []byte{0xE0, 0x20, 0x00, 0x00, 0x00}},
+	}
+
+	for _, tt := range tests {
+		if got := AppendCuint(nil, tt.value); !bytes.Equal(got, tt.encoded) {
+			t.Errorf("AppendCuint(0x%X) = % X, esperado % X", tt.value, got, tt.encoded)
+		}
+	}
+}
+
+func TestDecodeCuintErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"vazio", nil, ErrShortBuffer},
+		{"2 bytes truncado", []byte{0x80}, ErrShortBuffer},
+		{"4 bytes truncado", []byte{0xC0, 0x00, 0x01}, ErrShortBuffer},
+		{"5 bytes truncado", []byte{0xE0, 0x00, 0x00, 0x00}, ErrShortBuffer},
+		{"primeiro byte inválido", []byte{0xF0, 0x00, 0x00, 0x00, 0x00}, ErrBadCuint},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := DecodeCuint(tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: DecodeCuint(% X) erro = %v, esperado %v", tt.name, tt.data, err, tt.want)
+		}
+	}
+
+	var c Cuint
+	if err := c.UnmarshalBinary([]byte{0x01, 0x02}); !errors.Is(err, ErrBadCuint) {
+		t.Errorf("UnmarshalBinary com bytes sobrando: erro = %v, esperado %v", err, ErrBadCuint)
+	}
+}
@@ -1,7 +1,9 @@
 package pwapi
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,8 +11,6 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
-var db *sql.DB
-
 // UsuarioEGM verifica se o usuário está na tabela auth
 //
 // Parâmetros:
@@ -25,12 +25,33 @@ var db *sql.DB
 //	O termo Gm é uma abreviação de Game Master, que é o nome dado aos administradores do jogo.
 
 func UsuarioEGM(userID UserID) bool {
-	// Inicializa a conexão com o banco de dados, se ainda não estiver inicializada
-	rows, err := db.Query("SELECT DISTINCT userid FROM auth WHERE userid = ?", userID)
+	c, err := loadDefault()
+	if err != nil {
+		fmt.Printf("Erro ao criar o cliente: %v\n", err)
+		os.Exit(1)
+	}
+	ehGm, err := c.IsGM(context.Background(), userID)
 	if err != nil {
 		fmt.Printf("Erro ao consultar o banco de dados: %v\n", err)
 		os.Exit(1)
 	}
+	return ehGm
+}
+
+// ErrNoDB indica que o cliente não possui banco de dados configurado
+var ErrNoDB = errors.New("banco de dados não inicializado")
+
+// IsGM verifica se o usuário está na tabela auth, veja UsuarioEGM
+func (c *Client) IsGM(ctx context.Context, userID UserID) (bool, error) {
+	if c.db == nil {
+		return false, ErrNoDB
+	}
+
+	rows, err := c.db.QueryContext(ctx, "SELECT DISTINCT userid FROM auth WHERE userid = ?", userID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
 
 	//Contador para verificar se o usuário está na tabela auth
 	var numRows int
@@ -38,42 +59,62 @@ func UsuarioEGM(userID UserID) bool {
 	// Verifica se o usuário está na tabela auth
 	for rows.Next() {
 		var userID UserID
-		err = rows.Scan(&userID)
-		if err != nil {
-			fmt.Printf("Erro ao consultar o banco de dados: %v\n", err)
-			os.Exit(1)
+		if err := rows.Scan(&userID); err != nil {
+			return false, err
 		}
 		numRows++
 	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
 
 	// Retorna true se o usuário estiver na tabela auth, false caso contrário
-	if numRows > 0 {
-		return true
-	} else {
-		return false
-	}
+	return numRows > 0, nil
 }
 
-// InitializeDB inicializa a conexão com o banco de dados
-func InitializeDB() {
+// DB retorna o banco de dados do cliente, ou nil caso não tenha sido configurado
+func (c *Client) DB() *sql.DB {
+	return c.db
+}
 
-	connectionString := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", AppConfig.MySQL.Usuario, AppConfig.MySQL.Senha, AppConfig.MySQL.Host, AppConfig.MySQL.DB)
-	var err error
-	db, err = sql.Open("mysql", connectionString)
+// InitializeDB inicializa a conexão com o banco de dados do cliente padrão a partir de AppConfig.MySQL
+func InitializeDB() {
+	c, err := loadDefault()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	err = db.Ping()
-	if err != nil {
+	if err := c.openDB(AppConfig.MySQL.DSN()); err != nil {
 		log.Fatal(err)
 	}
-
 }
 
-// CloseDB fecha a conexão com o banco de dados
+// CloseDB fecha a conexão com o banco de dados do cliente padrão
 func CloseDB() {
-	if db != nil {
+	if c, err := loadDefault(); err == nil {
+		c.closeDB()
+	}
+}
+
+func (c *Client) openDB(dsn string) error {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Ping(); err != nil {
 		db.Close()
+		return err
+	}
+
+	c.db = db
+	return nil
+}
+
+func (c *Client) closeDB() error {
+	if c.db == nil {
+		return nil
 	}
+	err := c.db.Close()
+	c.db = nil
+	return err
 }
@@ -0,0 +1,255 @@
+package pwapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Decoded é um frame decodificado a partir do registro de pacotes conhecidos
+type Decoded struct {
+	Frame  Frame
+	Dir    string     // CaptureRequest ou CaptureResponse, detectada quando não informada
+	Packet PacketType // pacote registrado para o opcode, Name vazio quando desconhecido
+	RPC    *RPCResponse
+
+	// Value aponta para o struct desempacotado, preenchido até o campo onde a leitura falhou
+	Value reflect.Value
+
+	// Fields contém a posição de cada campo em Data, na ordem de leitura
+	Fields []FieldSpan
+
+	// Data são os bytes desempacotados no struct, o payload sem o cabeçalho RPC
+	Data []byte
+
+	// Leftover são os bytes que sobraram após o último campo do struct
+	Leftover []byte
+
+	// Err indica que o payload terminou antes do struct ou está malformado
+	Err error
+}
+
+// Known indica se o opcode possui um pacote registrado
+func (d Decoded) Known() bool {
+	return d.Packet.Type != nil
+}
+
+// DecodePacket decodifica o primeiro frame de data
+//
+// Parâmetros:
+//
+//	data: []byte - Bytes começando no cabeçalho do frame, como em deleteHeader
+//	dir: string - CaptureRequest, CaptureResponse ou vazio para detectar pela presença do pacote no registro
+//
+// Retorno:
+//
+//	Decoded - Frame decodificado, campos faltando ou sobrando são indicados em Err e Leftover
+//	[]byte - Bytes restantes após o frame
+//	error - Erro caso o cabeçalho do frame seja inválido ou o payload esteja truncado
+func DecodePacket(data []byte, dir string) (Decoded, []byte, error) {
+	frame, rest, err := ParseFrame(data)
+	if err != nil {
+		return Decoded{}, data, err
+	}
+	return DecodeFrame(frame, dir), rest, nil
+}
+
+// DecodeFrame decodifica um frame já separado, veja DecodePacket
+//
+// Observações:
+//
+//	Quando dir não é informada e o opcode possui requisição e resposta registradas (RPCs do gamedbd),
+//	o bit mais alto do xid decide: requisições possuem o bit ligado e respostas o bit desligado
+func DecodeFrame(frame Frame, dir string) Decoded {
+	d := Decoded{Frame: frame, Dir: dir, Data: frame.Payload}
+
+	if dir == "" {
+		req, hasReq := LookupPacket(frame.Opcode, CaptureRequest)
+		resp, hasResp := LookupPacket(frame.Opcode, CaptureResponse)
+		switch {
+		case hasReq && hasResp:
+			d.Dir, d.Packet = CaptureResponse, resp
+			if len(frame.Payload) >= 4 && binary.BigEndian.Uint32(frame.Payload)&xidRequest != 0 {
+				d.Dir, d.Packet = CaptureRequest, req
+			}
+		case hasReq:
+			d.Dir, d.Packet = CaptureRequest, req
+		case hasResp:
+			d.Dir, d.Packet = CaptureResponse, resp
+		}
+	} else {
+		d.Packet, _ = LookupPacket(frame.Opcode, dir)
+	}
+
+	if !d.Known() {
+		d.Leftover = frame.Payload
+		return d
+	}
+
+	if d.Packet.RPC {
+		response, err := parseRPCResponse(frame)
+		if err != nil {
+			d.Err = err
+			return d
+		}
+		d.RPC = &response
+		d.Data = response.Data
+	}
+
+	d.Value = reflect.New(d.Packet.Type)
+	d.Leftover, d.Fields, d.Err = unmarshalTrace(d.Data, d.Value)
+	return d
+}
+
+// unmarshalTrace é o Unmarshal que registra a posição de cada campo lido
+func unmarshalTrace(data []byte, v reflect.Value) ([]byte, []FieldSpan, error) {
+	p, err := planFor(v.Elem().Type())
+	if err != nil {
+		return data, nil, withOp(err, "unmarshal")
+	}
+
+	d := &decoder{data: data, trace: true}
+	if err := d.decodeStruct(p, v.Elem()); err != nil {
+		return data[d.off:], d.spans, prefixField(err, p.name)
+	}
+	return data[d.off:], d.spans, nil
+}
+
+// WriteTree escreve o frame decodificado como uma árvore de campos, com a posição de cada campo em Data
+func (d Decoded) WriteTree(w io.Writer) error {
+	var b strings.Builder
+
+	name := d.Packet.Name
+	if !d.Known() {
+		name = "desconhecido"
+	}
+	dir := map[string]string{CaptureRequest: "requisição", CaptureResponse: "resposta"}[d.Dir]
+	fmt.Fprintf(&b, "%s opcode 0x%X", name, d.Frame.Opcode)
+	if dir != "" {
+		fmt.Fprintf(&b, " (%s)", dir)
+	}
+	fmt.Fprintf(&b, ", payload de %d bytes\n", len(d.Frame.Payload))
+	if d.RPC != nil {
+		fmt.Fprintf(&b, "  xid %d, retcode %d\n", d.RPC.XID, d.RPC.RetCode)
+	}
+
+	width := 0
+	for _, f := range d.Fields {
+		if n := 2*f.Depth + len(f.Name); n > width {
+			width = n
+		}
+	}
+	for _, f := range d.Fields {
+		label := strings.Repeat("  ", f.Depth) + f.Name
+		line := fmt.Sprintf("  %-*s [%d:%d] %s", width, label, f.Start, f.End, formatValue(f.Value))
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	if d.Err != nil {
+		fmt.Fprintf(&b, "  !! bytes faltando: %v\n", d.Err)
+	}
+	if len(d.Leftover) > 0 {
+		fmt.Fprintf(&b, "  !! %d bytes restantes: %s\n", len(d.Leftover), hex.EncodeToString(d.Leftover))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatValue formata o valor de um campo para a árvore, structs e slices são representados pelos filhos
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Struct:
+		return ""
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("(%d bytes) %s", v.Len(), hex.EncodeToString(bytesOf(v)))
+		}
+		return fmt.Sprintf("(%d itens)", v.Len())
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// MarshalJSON representa o frame decodificado com os campos na ordem do pacote e octets em hexadecimal
+func (d Decoded) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	fmt.Fprintf(&b, `"opcode":%d,"dir":%q`, d.Frame.Opcode, d.Dir)
+	if d.Known() {
+		name, _ := json.Marshal(d.Packet.Name)
+		fmt.Fprintf(&b, `,"name":%s`, name)
+	}
+	if d.RPC != nil {
+		fmt.Fprintf(&b, `,"xid":%d,"retcode":%d`, d.RPC.XID, d.RPC.RetCode)
+	}
+	if d.Value.IsValid() {
+		b.WriteString(`,"fields":`)
+		if err := writeJSONValue(&b, d.Value.Elem()); err != nil {
+			return nil, err
+		}
+	}
+	if len(d.Leftover) > 0 {
+		fmt.Fprintf(&b, `,"leftover":%q`, hex.EncodeToString(d.Leftover))
+	}
+	if d.Err != nil {
+		msg, _ := json.Marshal(d.Err.Error())
+		fmt.Fprintf(&b, `,"error":%s`, msg)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+// writeJSONValue escreve v em JSON preservando a ordem dos campos dos structs
+func writeJSONValue(b *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		b.WriteString("{")
+		first := true
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() || f.Tag.Get("pw") == "-" {
+				continue
+			}
+			if !first {
+				b.WriteString(",")
+			}
+			first = false
+			fmt.Fprintf(b, "%q:", f.Name)
+			if err := writeJSONValue(b, v.Field(i)); err != nil {
+				return err
+			}
+		}
+		b.WriteString("}")
+		return nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			fmt.Fprintf(b, "%q", hex.EncodeToString(bytesOf(v)))
+			return nil
+		}
+		b.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if err := writeJSONValue(b, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		b.WriteString("]")
+		return nil
+	}
+
+	encoded, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	b.Write(encoded)
+	return nil
+}
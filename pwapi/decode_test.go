@@ -0,0 +1,88 @@
+package pwapi
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func rpcResponseFrame(t *testing.T, opcode uint32, xid uint32, v interface{}) []byte {
+	t.Helper()
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := binary.BigEndian.AppendUint32(nil, xid)
+	payload = binary.BigEndian.AppendUint32(payload, 0)
+	return EncodeFrame(opcode, append(payload, data...))
+}
+
+func TestDecodePacket(t *testing.T) {
+	base := RoleBase{ID: 1024, Name: "Fulano", Forbid: []GRoleForbid{{Type: 100, Reason: "spam"}}, UserID: 32}
+	raw := rpcResponseFrame(t, 0xBC5, 7, base)
+
+	decoded, rest, err := DecodePacket(raw, "")
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("DecodePacket: %v, %d bytes restantes", err, len(rest))
+	}
+	if decoded.Dir != CaptureResponse || decoded.Packet.Name != "GetRoleBaseRes" || decoded.RPC.XID != 7 {
+		t.Fatalf("pacote = %s %s, xid %+v", decoded.Dir, decoded.Packet.Name, decoded.RPC)
+	}
+	if decoded.Err != nil || len(decoded.Leftover) != 0 {
+		t.Fatalf("Err = %v, Leftover = %x", decoded.Err, decoded.Leftover)
+	}
+	if got := decoded.Value.Interface().(*RoleBase); got.Name != "Fulano" || got.Forbid[0].Reason != "spam" {
+		t.Errorf("RoleBase = %+v", got)
+	}
+
+	var tree strings.Builder
+	decoded.WriteTree(&tree)
+	for _, want := range []string{"GetRoleBaseRes opcode 0xBC5 (resposta)", "xid 7, retcode 0", `Name`, `"Fulano"`, "      Reason", "(1 itens)"} {
+		if !strings.Contains(tree.String(), want) {
+			t.Errorf("árvore sem %q:\n%s", want, tree.String())
+		}
+	}
+
+	// A requisição com o mesmo opcode é reconhecida pelo bit de requisição do xid
+	arg, _ := Marshal(GetRoleBaseArg{Handler: -1, RoleID: RoleID{1024}})
+	decoded, _, _ = DecodePacket(EncodeFrame(0xBC5, arg), "")
+	if decoded.Dir != CaptureRequest || decoded.Packet.Name != "GetRoleBaseArg" {
+		t.Errorf("requisição decodificada como %s %s", decoded.Dir, decoded.Packet.Name)
+	}
+}
+
+func TestDecodePacketLeftoverAndMissing(t *testing.T) {
+	status, _ := Marshal(RoleStatus{Level: 105})
+
+	// Bytes a mais após o último campo
+	extra := append(append([]byte{}, status...), 0xDE, 0xAD)
+	decoded := DecodeFrame(Frame{Opcode: 0xBC7, Payload: append(make([]byte, 8), extra...)}, CaptureResponse)
+	if decoded.Err != nil || string(decoded.Leftover) != "\xde\xad" {
+		t.Errorf("Leftover = %x, Err = %v", decoded.Leftover, decoded.Err)
+	}
+
+	// Payload truncado no meio do struct
+	decoded = DecodeFrame(Frame{Opcode: 0xBC7, Payload: append(make([]byte, 8), status[:20]...)}, CaptureResponse)
+	if !errors.Is(decoded.Err, ErrShortBuffer) {
+		t.Fatalf("Err = %v, esperado ErrShortBuffer", decoded.Err)
+	}
+	if got := decoded.Value.Interface().(*RoleStatus); got.Level != 105 {
+		t.Errorf("campos lidos antes da falha foram perdidos: %+v", got)
+	}
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"Level":105`) || !strings.Contains(string(encoded), `"error":`) {
+		t.Errorf("JSON = %s", encoded)
+	}
+
+	// Opcode desconhecido mantém o payload inteiro como restante
+	decoded = DecodeFrame(Frame{Opcode: 0x1234, Payload: []byte{1, 2}}, "")
+	if decoded.Known() || len(decoded.Leftover) != 2 {
+		t.Errorf("opcode desconhecido: %+v", decoded)
+	}
+}
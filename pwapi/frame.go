@@ -0,0 +1,178 @@
+package pwapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxFrameSize é o maior payload aceito ao ler um frame de um socket
+//
+// Observações:
+//
+//	Protege contra um cabeçalho corrompido anunciando um tamanho absurdo, o que faria o leitor alocar gigabytes
+const MaxFrameSize = 16 << 20
+
+// maxSkippedFrames limita quantos frames não relacionados podem ser descartados enquanto se espera uma resposta
+const maxSkippedFrames = 64
+
+// ErrFrameTooLarge indica que o cabeçalho do frame anuncia um payload maior que MaxFrameSize
+var ErrFrameTooLarge = errors.New("frame maior que o permitido")
+
+// ErrUnexpectedFrame indica que a resposta esperada não chegou após descartar maxSkippedFrames frames
+var ErrUnexpectedFrame = errors.New("resposta esperada não recebida")
+
+// Frame é um pacote completo trocado com os serviços do Perfect World
+//
+// Observações:
+//
+//	Todo pacote começa com o opcode e o tamanho do payload, ambos no formato compact uint
+type Frame struct {
+	Opcode  uint32
+	Payload []byte
+	Raw     []byte // frame completo, cabeçalho incluído
+}
+
+// EncodeFrame monta um frame a partir do opcode e do payload
+func EncodeFrame(opcode uint32, payload []byte) []byte {
+	raw := AppendCuint(nil, opcode)
+	raw = AppendCuint(raw, uint32(len(payload)))
+	return append(raw, payload...)
+}
+
+// ParseFrame lê um frame do início de data
+//
+// Parâmetros:
+//
+//	data: []byte - Bytes começando no cabeçalho do frame
+//
+// Retorno:
+//
+//	Frame - Frame lido, Payload e Raw apontam para data
+//	[]byte - Bytes restantes após o frame
+//	error - *CodecError caso o cabeçalho seja inválido ou o payload esteja truncado
+func ParseFrame(data []byte) (Frame, []byte, error) {
+	opcode, n1, err := DecodeCuint(data)
+	if err != nil {
+		return Frame{}, data, &CodecError{Op: "unmarshal", Field: "header.opcode", Err: err}
+	}
+	length, n2, err := DecodeCuint(data[n1:])
+	if err != nil {
+		return Frame{}, data, &CodecError{Op: "unmarshal", Field: "header.length", Offset: n1, Err: err}
+	}
+
+	start := n1 + n2
+	if uint64(len(data)-start) < uint64(length) {
+		return Frame{}, data, &CodecError{Op: "unmarshal", Field: "payload", Offset: start,
+			Err: fmt.Errorf("%w: precisa de %d bytes, restam %d", ErrShortBuffer, length, len(data)-start)}
+	}
+
+	end := start + int(length)
+	return Frame{Opcode: opcode, Payload: data[start:end], Raw: data[:end]}, data[end:], nil
+}
+
+// ReadFrame lê exatamente um frame de r
+//
+// Parâmetros:
+//
+//	r: io.Reader - Conexão com o serviço
+//
+// Retorno:
+//
+//	Frame - Frame lido
+//	error - Erro de leitura, ErrFrameTooLarge ou ErrBadCuint
+//
+// Observações:
+//
+//	O cabeçalho é lido byte a byte e o payload com io.ReadFull, de modo que respostas grandes que chegam
+//	em vários segmentos TCP são lidas por completo
+func ReadFrame(r io.Reader) (Frame, error) {
+	raw := make([]byte, 0, 10)
+
+	opcode, raw, err := readCuint(r, raw)
+	if err != nil {
+		return Frame{}, err
+	}
+	length, raw, err := readCuint(r, raw)
+	if err != nil {
+		return Frame{}, err
+	}
+	if length > MaxFrameSize {
+		return Frame{}, fmt.Errorf("%w: opcode 0x%X anuncia %d bytes", ErrFrameTooLarge, opcode, length)
+	}
+
+	header := len(raw)
+	raw = append(raw, make([]byte, length)...)
+	if _, err := io.ReadFull(r, raw[header:]); err != nil {
+		return Frame{}, err
+	}
+
+	return Frame{Opcode: opcode, Payload: raw[header:], Raw: raw}, nil
+}
+
+// ReadFrameFor lê frames de r até encontrar um com o opcode informado
+//
+// Parâmetros:
+//
+//	r: io.Reader - Conexão com o serviço
+//	opcode: uint32 - Opcode da resposta esperada
+//
+// Retorno:
+//
+//	Frame - Frame da resposta esperada
+//	error - Erro de leitura ou ErrUnexpectedFrame
+//
+// Observações:
+//
+//	O gdeliveryd envia pacotes não solicitados, como challenge e keepalive, que são descartados aqui
+func ReadFrameFor(r io.Reader, opcode uint32) (Frame, error) {
+	return readFrameFor(r, opcode, nil)
+}
+
+// readFrameFor é ReadFrameFor com uma função chamada para cada frame descartado
+func readFrameFor(r io.Reader, opcode uint32, onSkip func(Frame)) (Frame, error) {
+	for i := 0; i < maxSkippedFrames; i++ {
+		frame, err := ReadFrame(r)
+		if err != nil {
+			return Frame{}, err
+		}
+		if frame.Opcode == opcode {
+			return frame, nil
+		}
+		if onSkip != nil {
+			onSkip(frame)
+		}
+	}
+	return Frame{}, fmt.Errorf("%w: opcode 0x%X", ErrUnexpectedFrame, opcode)
+}
+
+// readCuint lê um compact uint de r, acrescentando os bytes lidos em raw
+func readCuint(r io.Reader, raw []byte) (uint32, []byte, error) {
+	start := len(raw)
+	raw = append(raw, 0)
+	if _, err := io.ReadFull(r, raw[start:]); err != nil {
+		return 0, raw, err
+	}
+
+	size := 1
+	switch b := raw[start]; {
+	case b > 0xE0:
+		return 0, raw, fmt.Errorf("%w: primeiro byte 0x%02X", ErrBadCuint, b)
+	case b == 0xE0:
+		size = 5
+	case b >= 0xC0:
+		size = 4
+	case b >= 0x80:
+		size = 2
+	}
+
+	if size > 1 {
+		raw = append(raw, make([]byte, size-1)...)
+		if _, err := io.ReadFull(r, raw[start+1:]); err != nil {
+			return 0, raw, err
+		}
+	}
+
+	v, _, err := DecodeCuint(raw[start:])
+	return v, raw, err
+}
@@ -0,0 +1,89 @@
+package pwapi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// Opcodes utilizados nos testes de comunicação com os serviços
+const (
+	opKeepAlive     = 0x5A  // keepalive que o gdeliveryd envia sem ser solicitado
+	opQueryOnline   = 0x189 // GMQueryOnline
+	opQueryOnlineRe = 0x18A // GMQueryOnline_Re
+	opGetRoleBase   = 0xBC5 // GetRoleBaseArg e GetRoleBaseRes
+)
+
+func TestReadFrameForSkipsUnrelatedFrames(t *testing.T) {
+	resp := EncodeFrame(opQueryOnlineRe, []byte{0, 0, 0, 1})
+	stream := append(EncodeFrame(opKeepAlive, []byte{0x01}), resp...)
+
+	r := bytes.NewReader(append(stream, EncodeFrame(opKeepAlive, []byte{0x02})...))
+	frame, err := ReadFrameFor(r, opQueryOnlineRe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(frame.Raw, resp) {
+		t.Errorf("frame = % X, esperado a resposta % X após o keepalive", frame.Raw, resp)
+	}
+
+	// Os frames posteriores à resposta continuam no leitor
+	if next, err := ReadFrame(r); err != nil || next.Opcode != opKeepAlive {
+		t.Errorf("ReadFrame após a resposta = %+v, %v, esperado o segundo keepalive", next, err)
+	}
+}
+
+func TestReadFrameForReassemblesSplitFrames(t *testing.T) {
+	// Opcode e tamanho com mais de um byte, e payload maior que um segmento
+	payload := bytes.Repeat([]byte{0xAB, 0xCD}, 40000)
+	resp := EncodeFrame(opGetRoleBase, payload)
+	stream := append(EncodeFrame(opKeepAlive, []byte{0x01}), resp...)
+
+	frame, err := ReadFrameFor(iotest.OneByteReader(bytes.NewReader(stream)), opGetRoleBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Opcode != opGetRoleBase || !bytes.Equal(frame.Payload, payload) || !bytes.Equal(frame.Raw, resp) {
+		t.Errorf("frame lido byte a byte difere do enviado: opcode 0x%X, %d bytes", frame.Opcode, len(frame.Payload))
+	}
+
+	// Um frame interrompido no meio do payload não é retornado pela metade
+	_, err = ReadFrameFor(iotest.OneByteReader(bytes.NewReader(resp[:len(resp)/2])), opGetRoleBase)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadFrameFor truncado = %v, esperado io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestReadFrameForSkipLimit(t *testing.T) {
+	resp := EncodeFrame(opQueryOnlineRe, []byte{0, 0, 0, 1})
+	stream := func(unrelated int) io.Reader {
+		var b []byte
+		for i := 0; i < unrelated; i++ {
+			b = append(b, EncodeFrame(opKeepAlive, []byte{0x01})...)
+		}
+		return bytes.NewReader(append(b, resp...))
+	}
+
+	if _, err := ReadFrameFor(stream(maxSkippedFrames-1), opQueryOnlineRe); err != nil {
+		t.Errorf("ReadFrameFor com %d frames descartados: %v", maxSkippedFrames-1, err)
+	}
+	if _, err := ReadFrameFor(stream(maxSkippedFrames+1), opQueryOnlineRe); !errors.Is(err, ErrUnexpectedFrame) {
+		t.Errorf("ReadFrameFor com %d frames descartados = %v, esperado ErrUnexpectedFrame", maxSkippedFrames+1, err)
+	}
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+	// Apenas o cabeçalho, o leitor não pode alocar nem esperar pelo payload anunciado
+	header := AppendCuint(AppendCuint(nil, opGetRoleBase), MaxFrameSize+1)
+	if _, err := ReadFrameFor(bytes.NewReader(header), opGetRoleBase); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("ReadFrameFor com %d bytes anunciados = %v, esperado ErrFrameTooLarge", MaxFrameSize+1, err)
+	}
+
+	// MaxFrameSize ainda é aceito, a leitura falha apenas pela falta do payload
+	header = AppendCuint(AppendCuint(nil, opGetRoleBase), MaxFrameSize)
+	if _, err := ReadFrame(bytes.NewReader(header)); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadFrame com MaxFrameSize anunciado = %v, esperado io.EOF", err)
+	}
+}
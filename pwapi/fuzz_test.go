@@ -0,0 +1,144 @@
+package pwapi
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Os alvos de fuzz rodam com o corpus inicial em go test, para explorar novas entradas utilize:
+//
+//	go test ./pwapi -run '^$' -fuzz FuzzUnmarshal -fuzztime 1m
+
+// fuzzTypes são os structs de todos os pacotes registrados, na ordem de Packets
+func fuzzTypes() []reflect.Type {
+	var types []reflect.Type
+	seen := map[reflect.Type]bool{}
+	for _, p := range Packets() {
+		if !seen[p.Type] {
+			seen[p.Type] = true
+			types = append(types, p.Type)
+		}
+	}
+	return types
+}
+
+// addGoldenSeeds adiciona os pacotes de testdata/golden ao corpus com o índice do seu struct em fuzzTypes
+func addGoldenSeeds(f *testing.F, types []reflect.Type) {
+	files, _ := filepath.Glob(filepath.Join(goldenDir, "*.hex"))
+	for _, file := range files {
+		frame := readGoldenHex(f, file)
+		f.Add(uint8(0), frame)
+
+		d, _, err := DecodePacket(frame, "")
+		if err != nil || !d.Known() {
+			continue
+		}
+		for i, typ := range types {
+			if typ == d.Packet.Type {
+				f.Add(uint8(i), d.Data)
+			}
+		}
+	}
+}
+
+// FuzzUnmarshal verifica que nenhum payload causa panic e que o código gerado e a reflexão concordam
+func FuzzUnmarshal(f *testing.F) {
+	types := fuzzTypes()
+	for i, typ := range types {
+		data, _ := marshalValue(reflect.New(typ).Elem())
+		f.Add(uint8(i), data)
+	}
+	addGoldenSeeds(f, types)
+	f.Add(uint8(0), []byte{0xE0, 0xFF, 0xFF, 0xFF, 0xFF})
+	f.Add(uint8(1), []byte{0xBF, 0xFF})
+
+	f.Fuzz(func(t *testing.T, which uint8, data []byte) {
+		typ := types[int(which)%len(types)]
+
+		generated := reflect.New(typ)
+		rest, errGen := Unmarshal(data, generated.Interface())
+
+		viaReflect := reflect.New(typ)
+		restReflect, errReflect := unmarshalValue(data, viaReflect.Elem())
+
+		if len(rest) != len(restReflect) || (errGen == nil) != (errReflect == nil) {
+			t.Fatalf("%s: Unmarshal restam %d (%v), reflexão restam %d (%v)", typ.Name(), len(rest), errGen,
+				len(restReflect), errReflect)
+		}
+
+		for _, p := range Profiles() {
+			if _, ok := p.Versions[typ.Name()]; ok {
+				UnmarshalProfile(data, reflect.New(typ).Interface(), p)
+			}
+		}
+
+		if errGen != nil {
+			return
+		}
+		if !reflect.DeepEqual(generated.Interface(), viaReflect.Interface()) {
+			t.Fatalf("%s: Unmarshal = %+v, reflexão %+v", typ.Name(), generated.Elem(), viaReflect.Elem())
+		}
+
+		// O valor lido é empacotado e lido novamente sem alterações
+		packed, err := Marshal(generated.Interface())
+		if err != nil {
+			t.Fatalf("%s: Marshal do valor lido: %v", typ.Name(), err)
+		}
+		again := reflect.New(typ)
+		if rest, err := Unmarshal(packed, again.Interface()); err != nil || len(rest) != 0 {
+			t.Fatalf("%s: Unmarshal(Marshal(v)): %v, restam %d", typ.Name(), err, len(rest))
+		}
+		if !reflect.DeepEqual(again.Interface(), generated.Interface()) {
+			t.Fatalf("%s: Unmarshal(Marshal(v)) = %+v, esperado %+v", typ.Name(), again.Elem(), generated.Elem())
+		}
+	})
+}
+
+// FuzzDecodePacket verifica que frames arbitrários são decodificados e exibidos sem panic
+func FuzzDecodePacket(f *testing.F) {
+	addGoldenSeeds(f, nil)
+	f.Add(uint8(0), []byte{})
+	f.Add(uint8(0), []byte{0x8B, 0xC5, 0xFF})
+
+	f.Fuzz(func(t *testing.T, _ uint8, data []byte) {
+		for len(data) > 0 {
+			d, rest, err := DecodePacket(data, "")
+			if err != nil {
+				return
+			}
+			if len(rest) >= len(data) {
+				t.Fatalf("DecodePacket não consumiu bytes de %x", data)
+			}
+			if err := d.WriteTree(io.Discard); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := d.MarshalJSON(); err != nil {
+				t.Fatal(err)
+			}
+			data = rest
+		}
+	})
+}
+
+// FuzzCuint verifica que todo cuint lido é gravado de volta com o mesmo valor
+func FuzzCuint(f *testing.F) {
+	for _, seed := range [][]byte{{0x3F}, {0x80, 0x40}, {0xC0, 0x00, 0x40, 0x00}, {0xE0, 0x20, 0, 0, 0}, {0xFF}} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, n, err := DecodeCuint(data)
+		if err != nil {
+			return
+		}
+		if n < 1 || n > 5 || n > len(data) {
+			t.Fatalf("DecodeCuint(%x) consumiu %d bytes", data, n)
+		}
+		encoded := AppendCuint(nil, v)
+		if got, m, err := DecodeCuint(encoded); err != nil || got != v || m != len(encoded) {
+			t.Fatalf("DecodeCuint(AppendCuint(%d)) = %d, %d, %v", v, got, m, err)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package pwapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Estratégias de entrega de gold aceitas em GoldConfig.Entrega
+const (
+	GoldDebugAddCash = "debugaddcash" // DebugAddCash enviado ao gamedbd, confirmado pelo CashAdd da conta
+	GoldUseCashNow   = "usecashnow"   // linha inserida na tabela usecashnow, confirmada quando é consumida
+)
+
+// Valores padrão aplicados quando não informados em Config.Gold
+const (
+	DefaultCashMultiplier   = 100
+	DefaultGoldZone         = 1
+	DefaultGoldConfirmation = 30 * time.Second
+	DefaultGoldInterval     = time.Second
+)
+
+// ErrGoldNotConfirmed indica que o gold foi enviado mas o crédito não foi confirmado dentro de GoldConfig.Confirmacao
+var ErrGoldNotConfirmed = errors.New("entrega de gold não confirmada")
+
+// GoldConfig define como o gold é creditado na conta do ganhador
+//
+// Observações:
+//
+//	Cash é o termo do jogo para a moeda premium, que os servidores privados chamam de Gold. Cada gold equivale a
+//	Multiplicador unidades de cash, 100 na maioria dos servidores
+//	Com GoldDebugAddCash o saldo da conta é lido pelo GetUser antes e depois do envio, e a entrega só é confirmada
+//	quando o CashAdd aumenta. Com GoldUseCashNow a linha inserida no banco é consumida pelo gauthd, que credita o
+//	cash na próxima verificação da tabela
+type GoldConfig struct {
+	Entrega       string        `yaml:"Entrega"`       // GoldDebugAddCash (padrão) ou GoldUseCashNow
+	Multiplicador int           `yaml:"Multiplicador"` // cash por gold, 0 usa DefaultCashMultiplier
+	Zona          int           `yaml:"Zona"`          // zoneid das linhas de usecashnow, 0 usa DefaultGoldZone
+	Confirmacao   time.Duration `yaml:"Confirmacao"`   // espera máxima pela confirmação do crédito
+	Intervalo     time.Duration `yaml:"Intervalo"`     // espera entre as verificações do crédito
+}
+
+// Validate verifica se a estratégia de entrega é conhecida
+func (g GoldConfig) Validate() error {
+	switch g.Entrega {
+	case "", GoldDebugAddCash, GoldUseCashNow:
+		return nil
+	}
+	return fmt.Errorf("pwapi: entrega de gold %q desconhecida, utilize %q ou %q", g.Entrega, GoldDebugAddCash, GoldUseCashNow)
+}
+
+// goldConfig retorna a entrega de gold configurada, completando com os valores padrão
+func (c *Client) goldConfig() GoldConfig {
+	g := c.goldCfg
+	if g.Entrega == "" {
+		g.Entrega = GoldDebugAddCash
+	}
+	if g.Multiplicador <= 0 {
+		g.Multiplicador = DefaultCashMultiplier
+	}
+	if g.Zona <= 0 {
+		g.Zona = DefaultGoldZone
+	}
+	if g.Confirmacao <= 0 {
+		g.Confirmacao = DefaultGoldConfirmation
+	}
+	if g.Intervalo <= 0 {
+		g.Intervalo = DefaultGoldInterval
+	}
+	return g
+}
+
+// addCashVerified envia DebugAddCash e aguarda o CashAdd da conta aumentar
+//
+// Observações:
+//
+//	Se a leitura inicial falhar o pacote não é enviado, sem ela não seria possível confirmar a entrega
+func (c *Client) addCashVerified(ctx context.Context, userID UserID, cash int, g GoldConfig) error {
+	before, err := c.UserCash(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("erro ao ler o cash da conta %d: %w", userID, err)
+	}
+
+	if err := Send(ctx, c, DebugAddCash{UserID: userID, Cash: cash}); err != nil {
+		return err
+	}
+
+	return c.waitGold(ctx, userID, cash, g, func() (bool, error) {
+		after, err := c.UserCash(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		return after.CashAdd-before.CashAdd >= cash, nil
+	})
+}
+
+// useCashNow insere o cash na tabela usecashnow e aguarda o gauthd consumir a linha
+//
+// Observações:
+//
+//	Cada linha recebe um sn próprio, veja insertUseCashNow, e a confirmação procura a linha por esse sn
+//	Sem confirmação a linha permanece na tabela e ainda pode ser creditada, verifique-a antes de reenviar o gold
+func (c *Client) useCashNow(ctx context.Context, userID UserID, cash int, g GoldConfig) error {
+	if c.db == nil {
+		return ErrNoDB
+	}
+
+	sn, err := c.insertUseCashNow(ctx, userID, cash, g)
+	if err != nil {
+		return err
+	}
+
+	return c.waitGold(ctx, userID, cash, g, func() (bool, error) {
+		var pending int
+		err := c.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM usecashnow WHERE userid = ? AND zoneid = ? AND sn = ?",
+			userID, g.Zona, sn).Scan(&pending)
+		return pending == 0, err
+	})
+}
+
+// insertUseCashNow insere a linha de usecashnow em uma transação e retorna o seu sn
+//
+// Observações:
+//
+//	O sn é o horário do envio em segundos, ou o maior sn pendente da conta + 1 quando ele já foi utilizado,
+//	portanto dois golds enviados no mesmo segundo não compartilham a linha nem a confirmação
+//	O FOR UPDATE bloqueia as linhas da conta até o COMMIT, dois envios simultâneos não escolhem o mesmo sn
+//	Uma falha no COMMIT não garante que a linha deixou de ser gravada e retorna ErrGoldNotConfirmed
+func (c *Client) insertUseCashNow(ctx context.Context, userID UserID, cash int, g GoldConfig) (int64, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao inserir em usecashnow: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sn int64
+	err = tx.QueryRowContext(ctx, "SELECT GREATEST(COALESCE(MAX(sn), 0) + 1, ?) FROM usecashnow "+
+		"WHERE userid = ? AND zoneid = ? FOR UPDATE", time.Now().Unix(), userID, g.Zona).Scan(&sn)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao inserir em usecashnow: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO usecashnow (userid, zoneid, sn, aid, point, cash, status, creatime) "+
+		"VALUES (?, ?, ?, 1, 0, ?, 1, NOW())", userID, g.Zona, sn, cash)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao inserir em usecashnow: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%w: erro ao gravar o cash da conta %d em usecashnow: %w", ErrGoldNotConfirmed, userID, err)
+	}
+	return sn, nil
+}
+
+// waitGold repete confirmed a cada g.Intervalo até a confirmação do crédito ou o fim de g.Confirmacao
+//
+// Retorno:
+//
+//	error - ErrGoldNotConfirmed quando o prazo termina ou confirmed falha, ou o erro de ctx
+func (c *Client) waitGold(ctx context.Context, userID UserID, cash int, g GoldConfig, confirmed func() (bool, error)) error {
+	deadline := time.Now().Add(g.Confirmacao)
+	for {
+		timer := time.NewTimer(g.Intervalo)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		// Após o envio, uma falha na verificação não indica que o cash deixou de ser creditado
+		ok, err := confirmed()
+		if err != nil {
+			return fmt.Errorf("%w: erro ao confirmar o cash da conta %d: %w", ErrGoldNotConfirmed, userID, err)
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pwapi: %w: %d de cash para a conta %d por %s após %v", ErrGoldNotConfirmed, cash, userID,
+				g.Entrega, g.Confirmacao)
+		}
+		c.debugf("Cash da conta %d ainda não creditado por %s", userID, g.Entrega)
+	}
+}
@@ -0,0 +1,335 @@
+package pwapi
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStmt é um comando recebido pelo fakeDB
+type fakeStmt struct {
+	query string
+	args  []driver.Value
+}
+
+// fakeDB é um banco de dados em memória para database/sql, com as respostas definidas por handle
+//
+// Observações:
+//
+//	handle recebe cada comando e retorna as linhas da consulta ou a quantidade de linhas alteradas
+//	BEGIN, COMMIT e ROLLBACK são apenas registrados, commitErr é retornado pelo COMMIT
+type fakeDB struct {
+	handle    func(query string, args []driver.Value) (rows [][]driver.Value, affected int64, err error)
+	commitErr error
+
+	mu    sync.Mutex
+	stmts []fakeStmt
+}
+
+// open retorna um *sql.DB que envia os comandos ao fakeDB
+func (f *fakeDB) open(t *testing.T) *sql.DB {
+	db := sql.OpenDB(f)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// received retorna os comandos recebidos que começam com prefix
+func (f *fakeDB) received(prefix string) []fakeStmt {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var stmts []fakeStmt
+	for _, s := range f.stmts {
+		if strings.HasPrefix(s.query, prefix) {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+func (f *fakeDB) exec(query string, named []driver.NamedValue) ([][]driver.Value, int64, error) {
+	args := make([]driver.Value, len(named))
+	for i, v := range named {
+		args[i] = v.Value
+	}
+	f.record(query, args)
+	return f.handle(query, args)
+}
+
+func (f *fakeDB) record(query string, args []driver.Value) {
+	f.mu.Lock()
+	f.stmts = append(f.stmts, fakeStmt{query: query, args: args})
+	f.mu.Unlock()
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakeDB: prepare não suportado")
+}
+func (fakeConn) Close() error { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("BEGIN", nil)
+	return fakeTx{c.db}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (t fakeTx) Commit() error {
+	t.db.record("COMMIT", nil)
+	return t.db.commitErr
+}
+
+func (t fakeTx) Rollback() error {
+	t.db.record("ROLLBACK", nil)
+	return nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	_, affected, err := c.db.exec(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, _, err := c.db.exec(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return []string{"c"}
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// cashTable simula a tabela usecashnow, com a chave primária (userid, zoneid, sn)
+//
+// Observações:
+//
+//	Com consume, o gauthd consome cada linha após a primeira verificação, sem ele as linhas ficam pendentes
+type cashTable struct {
+	mu      sync.Mutex
+	rows    map[[3]int64]bool
+	consume bool
+}
+
+func (c *cashTable) handle(query string, args []driver.Value) ([][]driver.Value, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rows == nil {
+		c.rows = map[[3]int64]bool{}
+	}
+
+	switch {
+	case strings.HasPrefix(query, "SELECT GREATEST"):
+		next := args[0].(int64)
+		for k := range c.rows {
+			if k[0] == args[1] && k[1] == args[2] && k[2] >= next {
+				next = k[2] + 1
+			}
+		}
+		return [][]driver.Value{{next}}, 0, nil
+	case strings.HasPrefix(query, "INSERT INTO usecashnow"):
+		k := [3]int64{args[0].(int64), args[1].(int64), args[2].(int64)}
+		if _, ok := c.rows[k]; ok {
+			return nil, 0, errors.New("Duplicate entry for key 'PRIMARY'")
+		}
+		c.rows[k] = false
+		return nil, 1, nil
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM usecashnow"):
+		k := [3]int64{args[0].(int64), args[1].(int64), args[2].(int64)}
+		polled, ok := c.rows[k]
+		switch {
+		case !ok:
+			return [][]driver.Value{{int64(0)}}, 0, nil
+		case polled && c.consume:
+			delete(c.rows, k)
+			return [][]driver.Value{{int64(0)}}, 0, nil
+		}
+		c.rows[k] = true
+		return [][]driver.Value{{int64(1)}}, 0, nil
+	}
+	return nil, 0, fmt.Errorf("cashTable: comando inesperado %q", query)
+}
+
+func TestUseCashNow(t *testing.T) {
+	// A linha inserida é consumida pelo gauthd na segunda verificação
+	table := &cashTable{consume: true}
+	fake := &fakeDB{handle: table.handle}
+	c, err := NewClient(WithDB(fake.open(t)), WithGold(GoldConfig{Entrega: GoldUseCashNow, Multiplicador: 10, Zona: 2,
+		Confirmacao: time.Second, Intervalo: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.AddGold(context.Background(), 32, 5); err != nil {
+		t.Fatalf("AddGold por usecashnow: %v", err)
+	}
+	inserts := fake.received("INSERT INTO usecashnow")
+	if len(inserts) != 1 {
+		t.Fatalf("INSERT recebidos = %+v, esperado 1", inserts)
+	}
+	args := inserts[0].args
+	if args[0] != int64(32) || args[1] != int64(2) || args[3] != int64(50) {
+		t.Errorf("INSERT com userid, zoneid, sn e cash = %v, esperado 32, 2, sn e 50", args)
+	}
+
+	// O sn é escolhido e inserido na mesma transação
+	var order []string
+	for _, stmt := range fake.received("") {
+		order = append(order, strings.Fields(stmt.query)[0])
+	}
+	if want := "BEGIN SELECT INSERT COMMIT SELECT SELECT"; strings.Join(order, " ") != want {
+		t.Errorf("comandos = %v, esperado %s", order, want)
+	}
+
+	// A verificação procura a linha inserida, pelo mesmo sn
+	selects := fake.received("SELECT COUNT(*) FROM usecashnow")
+	if len(selects) != 2 {
+		t.Fatalf("verificações = %d, esperado 2", len(selects))
+	}
+	if sel := selects[0].args; sel[0] != args[0] || sel[1] != args[1] || sel[2] != args[2] {
+		t.Errorf("SELECT com %v, esperado a conta, a zona e o sn do INSERT %v", sel, args[:3])
+	}
+}
+
+func TestUseCashNowBackToBack(t *testing.T) {
+	// O primeiro gold fica pendente, o segundo é enviado no mesmo segundo para a mesma conta
+	table := &cashTable{}
+	fake := &fakeDB{handle: table.handle}
+	c, err := NewClient(WithDB(fake.open(t)), WithGold(GoldConfig{Entrega: GoldUseCashNow, Zona: 2,
+		Confirmacao: 30 * time.Millisecond, Intervalo: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.AddGold(context.Background(), 32, 5); !errors.Is(err, ErrGoldNotConfirmed) {
+		t.Fatalf("primeiro AddGold = %v, esperado ErrGoldNotConfirmed", err)
+	}
+	table.mu.Lock()
+	table.consume = true
+	table.mu.Unlock()
+	if err := c.AddGold(context.Background(), 32, 7); err != nil {
+		t.Fatalf("segundo AddGold: %v", err)
+	}
+
+	inserts := fake.received("INSERT INTO usecashnow")
+	if len(inserts) != 2 {
+		t.Fatalf("INSERT recebidos = %+v, esperado 2", inserts)
+	}
+	first, second := inserts[0].args[2].(int64), inserts[1].args[2].(int64)
+	if second <= first {
+		t.Errorf("sn do segundo gold = %d, esperado maior que o sn pendente %d", second, first)
+	}
+
+	// A confirmação do segundo gold não depende da linha pendente do primeiro
+	selects := fake.received("SELECT COUNT(*) FROM usecashnow")
+	if last := selects[len(selects)-1].args; last[2] != second {
+		t.Errorf("última verificação com sn %v, esperado %d", last[2], second)
+	}
+	if pending := len(table.rows); pending != 1 {
+		t.Errorf("linhas pendentes = %d, esperado apenas a do primeiro gold", pending)
+	}
+}
+
+func TestUseCashNowNotConfirmed(t *testing.T) {
+	// O gauthd nunca consome a linha
+	fake := &fakeDB{handle: (&cashTable{}).handle}
+	c, err := NewClient(WithDB(fake.open(t)), WithGold(GoldConfig{Entrega: GoldUseCashNow,
+		Confirmacao: 50 * time.Millisecond, Intervalo: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.AddGold(context.Background(), 32, 5)
+	if !errors.Is(err, ErrGoldNotConfirmed) || RewardNotDelivered(err) {
+		t.Errorf("AddGold sem confirmação = %v, esperado ErrGoldNotConfirmed", err)
+	}
+
+	// Uma falha na verificação também não confirma o crédito, mas a linha já foi inserida
+	table := &cashTable{}
+	fake.handle = func(query string, args []driver.Value) ([][]driver.Value, int64, error) {
+		if strings.HasPrefix(query, "SELECT COUNT") {
+			return nil, 0, errors.New("conexão perdida")
+		}
+		return table.handle(query, args)
+	}
+	if err := c.AddGold(context.Background(), 32, 5); !errors.Is(err, ErrGoldNotConfirmed) {
+		t.Errorf("AddGold com a verificação falhando = %v, esperado ErrGoldNotConfirmed", err)
+	}
+
+	// Após uma falha no COMMIT a linha pode ter sido gravada
+	fake.handle = (&cashTable{}).handle
+	fake.commitErr = errors.New("conexão perdida")
+	polls := len(fake.received("SELECT COUNT"))
+	err = c.AddGold(context.Background(), 32, 5)
+	if !errors.Is(err, ErrGoldNotConfirmed) || RewardNotDelivered(err) {
+		t.Errorf("AddGold com o COMMIT falhando = %v, esperado ErrGoldNotConfirmed", err)
+	}
+	if n := len(fake.received("SELECT COUNT")); n != polls {
+		t.Errorf("verificações após o COMMIT falhar = %d", n-polls)
+	}
+}
+
+func TestUseCashNowInsertError(t *testing.T) {
+	table := &cashTable{}
+	fake := &fakeDB{handle: func(query string, args []driver.Value) ([][]driver.Value, int64, error) {
+		if strings.HasPrefix(query, "INSERT") {
+			return nil, 0, errors.New("tabela usecashnow inexistente")
+		}
+		return table.handle(query, args)
+	}}
+	c, err := NewClient(WithDB(fake.open(t)), WithGold(GoldConfig{Entrega: GoldUseCashNow}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.AddGold(context.Background(), 32, 5)
+	if err == nil || errors.Is(err, ErrGoldNotConfirmed) {
+		t.Errorf("AddGold com o INSERT falhando = %v", err)
+	}
+	if n := len(fake.received("SELECT COUNT")); n != 0 {
+		t.Errorf("verificações após o INSERT falhar = %d", n)
+	}
+	if n := len(fake.received("ROLLBACK")); n != 1 {
+		t.Errorf("ROLLBACK após o INSERT falhar = %d, esperado 1", n)
+	}
+
+	noDB, err := NewClient(WithGold(GoldConfig{Entrega: GoldUseCashNow}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := noDB.AddGold(context.Background(), 32, 5); !errors.Is(err, ErrNoDB) {
+		t.Errorf("AddGold sem banco de dados = %v, esperado ErrNoDB", err)
+	}
+}
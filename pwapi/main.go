@@ -1,9 +1,8 @@
 package pwapi
 
 import (
+	"context"
 	"fmt"
-	"net"
-	"time"
 )
 
 //GetOnlineList retorna uma lista de RoleID de usuários online
@@ -13,47 +12,90 @@ import (
 //
 //Retorno:
 //	[]RoleID - Retorna uma lista de RoleID de usuários online
+//	error - Retorna um erro caso a comunicação falhe ou a resposta esteja malformada
 //
 //Observações:
 //	RoleID é o ID do personagem no jogo
 //	Esta função envia uma requisição para o gdeliveryd para obter a lista de usuários online
 //	Esta função é utilizada para obter a lista de usuários online para realizar sorteios
+//	Falhas de comunicação são repetidas com espera exponencial, veja RetryConfig
 //  As informações utilizadas para escrever esta função foram obtidas através de engenharia reversa realizada por desenvolvedores da comunidade
 //  Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/GMQueryOnline
 
-func GetOnlineList() []RoleID {
+func GetOnlineList() ([]RoleID, error) {
+	return GetOnlineListCtx(context.Background())
+}
 
-	//Configuração do pacote GMQueryOnline
-	GMQueryOnlinePacket := GMQueryOnline{
-		QType: 0,
-	}
-	pack := createPack(GMQueryOnlinePacket)
-	opcode := 0x189
-	opcodeHex := fmt.Sprintf("%X", opcode)
-	pack = createHeader(opcodeHex, pack)
-
-	// Envio do pacote e tratamento de erros
-	recvAfterSend := true
-	justSend := false
-	response, err := SendToDelivery(pack, recvAfterSend, justSend)
+// GetOnlineListCtx é a variante de GetOnlineList que respeita o cancelamento e o prazo de ctx
+func GetOnlineListCtx(ctx context.Context) ([]RoleID, error) {
+	c, err := loadDefault()
 	if err != nil {
-		fmt.Printf("Erro ao enviar para o gdeliveryd: %v\n", err)
+		return nil, err
 	}
+	return c.OnlineList(ctx)
+}
 
-	//Deleta o cabeçalho do pacote
-	//Ao invés de deletar o cabeçalho, o struct GMQueryOnlineRe foi modificado para evidenciar os dados do cabeçalho
-	//data := deleteHeader(response)
+// OnlineList retorna a lista de RoleID de usuários online no servidor do cliente, veja GetOnlineList
+func (c *Client) OnlineList(ctx context.Context) ([]RoleID, error) {
 
-	//GMQueryOnlineRe é a estrutura do pacote que será recebido do gdeliveryd
-	var usersOnline GMQueryOnlineRe
+	//GMQueryOnline_Re é a resposta do gdeliveryd com a lista de RoleID dos usuários online
+	usersOnline, err := callRetry[GMQueryOnline, GMQueryOnlineRe](ctx, c, GMQueryOnline{QType: 0})
+	if err != nil {
+		return nil, err
+	}
+	return usersOnline.RoleIDS, nil
+}
 
-	//Desempacota os dados do pacote recebido
-	unpackData(response, &usersOnline)
+// GetOnlineUsers retorna os usuários online com a conta e o nome do personagem
+//
+// Retorno:
+//
+//	[]UserOnline - Usuários online, com UserID, RoleID e Name preenchidos
+//	error - Retorna um erro caso a comunicação falhe, o gdeliveryd retorne um código de erro ou a paginação não avance
+//
+// Observações:
+//
+//	Utiliza GMListOnlineUser, que o gdeliveryd responde em páginas: cada resposta traz o Handler da próxima
+//	página e a última traz -1. Todas as páginas são lidas antes do retorno
+//	Diferente de GetOnlineList, dispensa uma chamada a GetRoleBase por usuário para obter a conta e o nome
+//	Falhas de comunicação em cada página são repetidas com espera exponencial, veja RetryConfig
+//	Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/GMListOnlineUser
+func GetOnlineUsers() ([]UserOnline, error) {
+	return GetOnlineUsersCtx(context.Background())
+}
+
+// GetOnlineUsersCtx é a variante de GetOnlineUsers que respeita o cancelamento e o prazo de ctx
+func GetOnlineUsersCtx(ctx context.Context) ([]UserOnline, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return nil, err
+	}
+	return c.OnlineUsers(ctx)
+}
 
-	//OnlineList é a lista de RoleID de usuários online que será retornada
-	var OnlineList []RoleID
-	OnlineList = usersOnline.RoleIDS
-	return OnlineList
+// OnlineUsers retorna os usuários online no servidor do cliente, veja GetOnlineUsers
+func (c *Client) OnlineUsers(ctx context.Context) ([]UserOnline, error) {
+	var users []UserOnline
+	seen := map[int]bool{}
+	for handler := 0; handler != -1; {
+
+		// Um Handler repetido faria a paginação voltar ao início indefinidamente
+		if seen[handler] {
+			return nil, fmt.Errorf("GMListOnlineUser_Re repetiu a página %d após %d usuários", handler, len(users))
+		}
+		seen[handler] = true
+
+		page, err := callRetry[GMListOnlineUser, GMListOnlineUserRe](ctx, c, GMListOnlineUser{GMRoleID: -1, Handler: handler})
+		if err != nil {
+			return nil, err
+		}
+		if page.RetCode != 0 {
+			return nil, &RetCodeError{Opcode: OpGMListOnlineUser, RetCode: int32(page.RetCode)}
+		}
+		users = append(users, page.Users...)
+		handler = page.Handler
+	}
+	return users, nil
 }
 
 //IsServerOnline verifica se o servidor está online
@@ -69,17 +111,38 @@ func GetOnlineList() []RoleID {
 //	O gamedbd é um serviço do servidor do Perfect World que gerencia as informações dos personagens logados
 
 func IsServerOnline() bool {
+	return IsServerOnlineCtx(context.Background())
+}
 
-	//Tenta se conectar ao gamedbd
-	_, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", AppConfig.IP, AppConfig.Ports["gamedbd"]), 5*time.Second)
+// IsServerOnlineCtx é a variante de IsServerOnline que respeita o cancelamento e o prazo de ctx
+func IsServerOnlineCtx(ctx context.Context) bool {
+	c, err := loadDefault()
+	if err == nil {
+		err = c.Ping(ctx)
+	}
 	if err != nil {
 		fmt.Printf("Erro ao conectar ao gamedbd: %v\n", err)
 		return false
 	}
-
 	return true
 }
 
+// Ping verifica se o gamedbd do servidor do cliente aceita conexões, veja IsServerOnline
+func (c *Client) Ping(ctx context.Context) error {
+	if c.replay != nil {
+		return nil
+	}
+
+	//Tenta se conectar ao gamedbd
+	dialCtx, cancel := context.WithDeadline(ctx, deadlineFor(ctx, c.timeoutsFor("gamedbd").Conexao))
+	defer cancel()
+	conn, err := dialContext(dialCtx, c.addr("gamedbd"))
+	if err != nil {
+		return contextErr(ctx, err)
+	}
+	return conn.Close()
+}
+
 //GetRoleStatus retorna o status de um personagem
 //
 //Parâmetros:
@@ -87,40 +150,66 @@ func IsServerOnline() bool {
 //
 //Retorno:
 //	RoleStatus - Retorna o status do personagem
+//	error - Retorna um erro caso a comunicação falhe ou a resposta esteja malformada
 //
 //Observações:
 //	RoleStatus é a estrutura que contém as informações dos status do personagem
 //	Status nesse contexto se refere a informações que se alteram durante o jogo, como nível e cultivo que são utilizadas para verificar se o personagem é elegível para o sorteio
+//	Falhas de comunicação são repetidas com espera exponencial, veja RetryConfig
 //  Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/GetRoleStatusArg
 
-func GetRoleStatus(roleID RoleID) RoleStatus {
+func GetRoleStatus(roleID RoleID) (RoleStatus, error) {
+	return GetRoleStatusCtx(context.Background(), roleID)
+}
 
-	//configuração do pacote GetRoleStatusArg
-	GetRoleStatusArgPacket := GetRoleStatusArg{
-		Handler: -1,
-		RoleID:  roleID,
+// GetRoleStatusCtx é a variante de GetRoleStatus que respeita o cancelamento e o prazo de ctx
+func GetRoleStatusCtx(ctx context.Context, roleID RoleID) (RoleStatus, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return RoleStatus{}, err
 	}
+	return c.RoleStatus(ctx, roleID)
+}
+
+// RoleStatus retorna o status de um personagem do servidor do cliente, veja GetRoleStatus
+func (c *Client) RoleStatus(ctx context.Context, roleID RoleID) (RoleStatus, error) {
 
-	// Cria e prepara o pacote para envio
-	pack := createPack(GetRoleStatusArgPacket)
-	opcode := 0xbc7
-	opcodeHex := fmt.Sprintf("%X", opcode)
-	pack = createHeader(opcodeHex, pack)
+	// A RPC é enviada pela conexão multiplexada, que preenche o Handler com um xid único
+	return callRetry[GetRoleStatusArg, RoleStatus](ctx, c, GetRoleStatusArg{Handler: -1, RoleID: roleID})
+}
+
+// GetRoleLevel retorna o level e o cultivo de um personagem
+//
+// Parâmetros:
+//
+//	roleID: RoleID - ID do personagem
+//
+// Retorno:
+//
+//	RoleStatusLevel - Início do RoleStatus com a versão, o level e o cultivo do personagem
+//	error - Retorna um erro caso a comunicação falhe ou a resposta esteja malformada
+//
+// Observações:
+//
+//	Utiliza a mesma RPC de GetRoleStatus, mas a leitura para após Level2 e os demais campos não são alocados,
+//	o que torna a verificação de elegibilidade de listas grandes de personagens online mais barata
+//	Falhas de comunicação são repetidas como em GetRoleStatus
+func GetRoleLevel(roleID RoleID) (RoleStatusLevel, error) {
+	return GetRoleLevelCtx(context.Background(), roleID)
+}
 
-	// Envio do pacote e tratamento de erros
-	recvAfterSend := false
-	justSend := false
-	response, err := SendToGamedBD(pack, recvAfterSend, justSend)
+// GetRoleLevelCtx é a variante de GetRoleLevel que respeita o cancelamento e o prazo de ctx
+func GetRoleLevelCtx(ctx context.Context, roleID RoleID) (RoleStatusLevel, error) {
+	c, err := loadDefault()
 	if err != nil {
-		fmt.Printf("Erro ao enviar para o gdeliveryd: %v\n", err)
+		return RoleStatusLevel{}, err
 	}
+	return c.RoleLevel(ctx, roleID)
+}
 
-	// Desempacota os dados para obter o RoleStatus
-	data := deleteHeader(response)
-	var roleStatus RoleStatus
-	unpackData(data, &roleStatus)
-
-	return roleStatus
+// RoleLevel retorna o level e o cultivo de um personagem do servidor do cliente, veja GetRoleLevel
+func (c *Client) RoleLevel(ctx context.Context, roleID RoleID) (RoleStatusLevel, error) {
+	return callRetry[GetRoleStatusArg, RoleStatusLevel](ctx, c, GetRoleStatusArg{Handler: -1, RoleID: roleID})
 }
 
 //GetRoleBase retorna as informações básicas de um personagem
@@ -130,42 +219,150 @@ func GetRoleStatus(roleID RoleID) RoleStatus {
 //
 //Retorno:
 //	RoleBase: Retorna as informações básicas do personagem
+//	error - Retorna um erro caso a comunicação falhe ou a resposta esteja malformada
 //
 //Observações:
 //	RoleBase é a estrutura que contém as informações básicas do personagem
 //	Esta função é utilizada para obter as informações básicas do personagem para realizar sorteios
+//	Falhas de comunicação são repetidas com espera exponencial, veja RetryConfig
 //  As informações utilizadas para escrever esta função foram obtidas através de engenharia reversa realizada por desenvolvedores da comunidade
 //  Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/GetRoleBaseArg
 
-func GetRoleBase(roleID RoleID) RoleBase {
+func GetRoleBase(roleID RoleID) (RoleBase, error) {
+	return GetRoleBaseCtx(context.Background(), roleID)
+}
 
-	//Configuração do pacote GetRoleBaseArg
-	GetRoleBaseArgPacket := GetRoleBaseArg{
-		Handler: -1,
-		RoleID:  roleID,
+// GetRoleBaseCtx é a variante de GetRoleBase que respeita o cancelamento e o prazo de ctx
+func GetRoleBaseCtx(ctx context.Context, roleID RoleID) (RoleBase, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return RoleBase{}, err
 	}
+	return c.RoleBase(ctx, roleID)
+}
+
+// RoleBase retorna as informações básicas de um personagem do servidor do cliente, veja GetRoleBase
+func (c *Client) RoleBase(ctx context.Context, roleID RoleID) (RoleBase, error) {
+
+	// A RPC é enviada pela conexão multiplexada, que preenche o Handler com um xid único
+	return callRetry[GetRoleBaseArg, RoleBase](ctx, c, GetRoleBaseArg{Handler: -1, RoleID: roleID})
+}
+
+// GetRole retorna o personagem completo, com inventário, equipamento, armazém e missões
+//
+// Parâmetros:
+//
+//	roleID: RoleID - ID do personagem
+//
+// Retorno:
+//
+//	GRoleData - RoleBase, RoleStatus, inventário (Pocket), equipamento, armazém (Storehouse) e missões (Task)
+//	error - Retorna um erro caso a comunicação falhe ou a resposta esteja malformada
+//
+// Observações:
+//
+//	A resposta é bem maior que a de GetRoleBase e GetRoleStatus, utilize-a apenas quando os itens forem necessários
+//	RoleBase e RoleStatus são decodificados com o layout do perfil de versão do cliente, veja WithProfile
+//	Falhas de comunicação são repetidas com espera exponencial, veja RetryConfig
+//	Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/GetRole
+func GetRole(roleID RoleID) (GRoleData, error) {
+	return GetRoleCtx(context.Background(), roleID)
+}
+
+// GetRoleCtx é a variante de GetRole que respeita o cancelamento e o prazo de ctx
+func GetRoleCtx(ctx context.Context, roleID RoleID) (GRoleData, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return GRoleData{}, err
+	}
+	return c.Role(ctx, roleID)
+}
+
+// Role retorna o personagem completo do servidor do cliente, veja GetRole
+func (c *Client) Role(ctx context.Context, roleID RoleID) (GRoleData, error) {
+	return callRetry[GetRoleArg, GRoleData](ctx, c, GetRoleArg{Handler: -1, RoleID: roleID})
+}
+
+// GetRoleIDByName retorna o ID do personagem com o nome informado
+//
+// Parâmetros:
+//
+//	name: string - Nome do personagem, como exibido no jogo
+//
+// Retorno:
+//
+//	RoleID - ID do personagem
+//	error - Retorna um erro que satisfaz errors.Is(err, ErrNotFound) caso não exista personagem com o nome,
+//	ou um erro caso a comunicação falhe
+//
+// Observações:
+//
+//	Permite endereçar prêmios e consultas a partir de nomes digitados por GMs
+//	Falhas de comunicação são repetidas com espera exponencial, veja RetryConfig
+//	Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/GetRoleId
+func GetRoleIDByName(name string) (RoleID, error) {
+	return GetRoleIDByNameCtx(context.Background(), name)
+}
 
-	// Cria e prepara o pacote para envio
-	pack := createPack(GetRoleBaseArgPacket)
-	opcode := 0xbc5
-	opcodeHex := fmt.Sprintf("%X", opcode)
-	pack = createHeader(opcodeHex, pack)
+// GetRoleIDByNameCtx é a variante de GetRoleIDByName que respeita o cancelamento e o prazo de ctx
+func GetRoleIDByNameCtx(ctx context.Context, name string) (RoleID, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return RoleID{}, err
+	}
+	return c.RoleIDByName(ctx, name)
+}
 
-	// Envio do pacote e tratamento de erros
-	recvAfterSend := false
-	justSend := false
-	response, err := SendToGamedBD(pack, recvAfterSend, justSend)
+// RoleIDByName retorna o ID do personagem com o nome informado no servidor do cliente, veja GetRoleIDByName
+func (c *Client) RoleIDByName(ctx context.Context, name string) (RoleID, error) {
+	res, err := callRetry[GetRoleIDArg, GetRoleIDRes](ctx, c, GetRoleIDArg{Handler: -1, Name: name, Reason: 1})
 	if err != nil {
-		fmt.Printf("Erro ao enviar para o gdeliveryd: %v\n", err)
+		return RoleID{}, err
 	}
 
-	//	Deleta o cabeçalho do pacote
-	data := deleteHeader(response)
+	// Algumas versões respondem o código 0 com o ID -1 para nomes inexistentes
+	if res.RoleID.RoleID <= 0 {
+		return RoleID{}, fmt.Errorf("pwapi: personagem %q %w", name, ErrNotFound)
+	}
+	return res.RoleID, nil
+}
 
-	// Desempacota os dados para obter o RoleBase
-	var roleBase RoleBase
-	unpackData(data, &roleBase)
-	return roleBase
+// GetUserRoles retorna os personagens de uma conta
+//
+// Parâmetros:
+//
+//	userID: UserID - ID da conta
+//
+// Retorno:
+//
+//	[]UserRole - ID e nome de cada personagem da conta, vazio quando a conta não possui personagens
+//	error - Retorna um erro que satisfaz errors.Is(err, ErrNotFound) caso a conta não exista, ou um erro caso a
+//	comunicação falhe
+//
+// Observações:
+//
+//	Falhas de comunicação são repetidas com espera exponencial, veja RetryConfig
+//	Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/GetUserRoles
+func GetUserRoles(userID UserID) ([]UserRole, error) {
+	return GetUserRolesCtx(context.Background(), userID)
+}
+
+// GetUserRolesCtx é a variante de GetUserRoles que respeita o cancelamento e o prazo de ctx
+func GetUserRolesCtx(ctx context.Context, userID UserID) ([]UserRole, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return nil, err
+	}
+	return c.UserRoles(ctx, userID)
+}
+
+// UserRoles retorna os personagens de uma conta do servidor do cliente, veja GetUserRoles
+func (c *Client) UserRoles(ctx context.Context, userID UserID) ([]UserRole, error) {
+	res, err := callRetry[GetUserRolesArg, GetUserRolesRes](ctx, c, GetUserRolesArg{Handler: -1, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	return res.Roles, nil
 }
 
 // ChatItem envia uma mensagem para o chat do jogo
@@ -177,74 +374,150 @@ func GetRoleBase(roleID RoleID) RoleBase {
 //
 // Retorno:
 //
-//	Não há retorno
+//	error - Retorna um erro caso o pacote não possa ser criado ou enviado
 //
 // Observações:
 //
 //	Esta função envia uma mensagem para o chat do jogo
 //	As informações utilizadas para escrever esta função foram obtidas através de engenharia reversa realizada por desenvolvedores da comunidade
 //	Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/ChatBroadCast
-func ChatItem(text string) {
+func ChatItem(text string) error {
+	return ChatItemCtx(context.Background(), text)
+}
+
+// ChatItemCtx é a variante de ChatItem que respeita o cancelamento e o prazo de ctx
+func ChatItemCtx(ctx context.Context, text string) error {
+	c, err := loadDefault()
+	if err != nil {
+		return err
+	}
+	return c.Broadcast(ctx, AppConfig.CanalMensagem, text)
+}
+
+// Broadcast envia uma mensagem para um canal do chat do servidor do cliente, veja ChatItem
+func (c *Client) Broadcast(ctx context.Context, channel int, text string) error {
 
-	//ChatBroadCastAPI é a estrutura do pacote que será enviado para o gdeliveryd
-	ChatBroadCastPacket := ChatBroadCast{
-		Channel:   byte(AppConfig.CanalMensagem),
+	//ChatBroadCast é enviado ao provider, que não responde
+	return Send(ctx, c, ChatBroadCast{
+		Channel:   byte(channel),
 		Emotion:   0,
 		SrcRoleID: 0,
 		Msg:       text,
 		Data:      []byte{},
-	}
-
-	// Cria e prepara o pacote para envio
-	pack := createPack(ChatBroadCastPacket)
-	opcode := 0x78
-	opcodeHex := fmt.Sprintf("%X", opcode)
-	pack = createHeader(opcodeHex, pack)
-
-	// Envio do pacote e tratamento de erros
-	recvAfterSend := true
-	justSend := true
-	_, err := SendToProvider(pack, recvAfterSend, justSend)
-	if err != nil {
-		fmt.Printf("Erro ao enviar para o gdeliveryd: %v\n", err)
-	}
+	})
 }
 
 // AddCash adiciona cash a um personagem
 //
 // Parâmetros:
 // 	userID: UserID - ID do usuário
-// 	cash: int - Quantidade de cash a ser adicionada
+// 	cash: int - Quantidade de gold a ser adicionada, multiplicada por GoldConfig.Multiplicador
 //
 // Retorno:
-// 	Não há retorno
+// 	error - Retorna um erro caso o pacote não possa ser criado ou enviado
 //
 // Observações:
 // 	Cash é um termo mais utilizado em servidores oficiais do Perfect World para se referir a moeda premium
 // 	Em servidores privados, o termo mais utilizado é Gold
+// 	O gamedbd não responde ao DebugAddCash, utilize AddGold para confirmar o crédito
 // 	Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/DebugAddCash
 
-func AddCash(userID UserID, cash int) {
+func AddCash(userID UserID, cash int) error {
+	return AddCashCtx(context.Background(), userID, cash)
+}
+
+// AddCashCtx é a variante de AddCash que respeita o cancelamento e o prazo de ctx
+func AddCashCtx(ctx context.Context, userID UserID, cash int) error {
+	c, err := loadDefault()
+	if err != nil {
+		return err
+	}
+	return c.AddCash(ctx, userID, cash)
+}
 
-	//DebugAddCash é a estrutura do pacote que será enviado para o gamedbd
-	DebugAddCashPacket := DebugAddCash{
+// AddCash adiciona cash a um usuário do servidor do cliente, veja a função AddCash
+func (c *Client) AddCash(ctx context.Context, userID UserID, cash int) error {
+
+	//DebugAddCash é enviado ao gamedbd, que não responde
+	return Send(ctx, c, DebugAddCash{
 		UserID: userID,
-		Cash:   cash * 100,
+		Cash:   cash * c.goldConfig().Multiplicador,
+	})
+}
+
+// AddGold adiciona gold a um usuário e confirma o crédito
+//
+// Parâmetros:
+//
+//	userID: UserID - ID do usuário
+//	gold: int - Quantidade de gold, multiplicada por GoldConfig.Multiplicador
+//
+// Retorno:
+//
+//	error - Retorna um erro caso o envio falhe, ou ErrGoldNotConfirmed caso o crédito não seja confirmado a tempo
+//
+// Observações:
+//
+//	A entrega segue GoldConfig.Entrega: DebugAddCash confirmado pelo CashAdd da conta ou inserção em usecashnow
+//	confirmada quando a linha é consumida, a segunda depende do banco de dados do cliente
+//	O envio não é repetido, após ErrGoldNotConfirmed o gold ainda pode ser creditado mais tarde
+func AddGold(userID UserID, gold int) error {
+	return AddGoldCtx(context.Background(), userID, gold)
+}
+
+// AddGoldCtx é a variante de AddGold que respeita o cancelamento e o prazo de ctx
+func AddGoldCtx(ctx context.Context, userID UserID, gold int) error {
+	c, err := loadDefault()
+	if err != nil {
+		return err
+	}
+	return c.AddGold(ctx, userID, gold)
+}
+
+// AddGold adiciona gold a um usuário do servidor do cliente, veja a função AddGold
+func (c *Client) AddGold(ctx context.Context, userID UserID, gold int) error {
+	g := c.goldConfig()
+	if err := g.Validate(); err != nil {
+		return err
 	}
 
-	// Cria e prepara o pacote para envio
-	pack := createPack(DebugAddCashPacket)
-	opcode := 0x209
-	opcodeHex := fmt.Sprintf("%X", opcode)
-	pack = createHeader(opcodeHex, pack)
+	cash := gold * g.Multiplicador
+	if g.Entrega == GoldUseCashNow {
+		return c.useCashNow(ctx, userID, cash, g)
+	}
+	return c.addCashVerified(ctx, userID, cash, g)
+}
 
-	// Envio do pacote e tratamento de erros
-	recvAfterSend := false
-	justSend := true
-	_, err := SendToGamedBD(pack, recvAfterSend, justSend)
+// GetUserCash retorna os saldos de cash de uma conta
+//
+// Parâmetros:
+//
+//	userID: UserID - ID do usuário
+//
+// Retorno:
+//
+//	UserCash - Início do User da conta, com o cash comprado, creditado e utilizado
+//	error - Retorna um erro caso a comunicação falhe, ou *RetCodeError com ErrNotFound caso a conta não exista
+//
+// Observações:
+//
+//	Falhas de comunicação são repetidas com espera exponencial, veja RetryConfig
+func GetUserCash(userID UserID) (UserCash, error) {
+	return GetUserCashCtx(context.Background(), userID)
+}
+
+// GetUserCashCtx é a variante de GetUserCash que respeita o cancelamento e o prazo de ctx
+func GetUserCashCtx(ctx context.Context, userID UserID) (UserCash, error) {
+	c, err := loadDefault()
 	if err != nil {
-		fmt.Printf("Erro ao enviar para o Gamedbd: %v\n", err)
+		return UserCash{}, err
 	}
+	return c.UserCash(ctx, userID)
+}
+
+// UserCash retorna os saldos de cash de uma conta do servidor do cliente, veja GetUserCash
+func (c *Client) UserCash(ctx context.Context, userID UserID) (UserCash, error) {
+	return callRetry[GetUserArg, UserCash](ctx, c, GetUserArg{Handler: -1, UserID: userID})
 }
 
 // SendMail envia um e-mail para um personagem
@@ -259,18 +532,35 @@ func AddCash(userID UserID, cash int) {
 //
 // Retorno:
 //
-//	Não há retorno
+//	error - Retorna um erro caso o pacote não possa ser criado ou enviado, ou *RetCodeError caso o gdeliveryd recuse o e-mail
 //
 // Observações:
 //
 //	Esta função envia um e-mail para um personagem dentro do jogo
 //	Diferente de mensagens, e-mails podem conter itens e dinheiro
+//	A entrega é confirmada pelo SysSendMail_Re, os motivos mais comuns de recusa são reconhecidos com errors.Is:
+//	ErrNotFound (personagem inexistente), ErrMailboxFull e ErrInvalidAttachment
+//	O envio não é repetido após falhas de comunicação, o gdeliveryd pode ter recebido o pacote
 //	Mais informações em sobre o Opcode e detalhes do pacote em: http://pwdev.ru/index.php/SysSendMail
-func SendMail(RoleID RoleID, title string, content string, item Item, money int) {
+func SendMail(RoleID RoleID, title string, content string, item Item, money int) error {
+	return SendMailCtx(context.Background(), RoleID, title, content, item, money)
+}
+
+// SendMailCtx é a variante de SendMail que respeita o cancelamento e o prazo de ctx
+func SendMailCtx(ctx context.Context, RoleID RoleID, title string, content string, item Item, money int) error {
+	c, err := loadDefault()
+	if err != nil {
+		return err
+	}
+	return c.SendMail(ctx, RoleID, title, content, item, money)
+}
 
-	// Configuração do pacote SysSendMailAPI
+// SendMail envia um e-mail para um personagem do servidor do cliente, veja a função SendMail
+func (c *Client) SendMail(ctx context.Context, RoleID RoleID, title string, content string, item Item, money int) error {
+
+	// Configuração do pacote SysSendMail
 	// valores hardcoded definidos pela comunidade
-	SysSendMailpacket := SysSendMail{
+	resp, err := Call[SysSendMail, SysSendMailRe](ctx, c, SysSendMail{
 		TID:         344,
 		SysID:       1025,
 		SysType:     3,
@@ -279,19 +569,12 @@ func SendMail(RoleID RoleID, title string, content string, item Item, money int)
 		Content:     content,
 		AttachObj:   item,
 		AttachMoney: money,
-	}
-
-	//Cria o pacote
-	pack := createPack(SysSendMailpacket)
-	opcode := 0x1076
-	opcodeHex := fmt.Sprintf("%X", opcode)
-	pack = createHeader(opcodeHex, pack)
-
-	//envia o pacote e verifica se houve erro
-	recvAfterSend := false
-	justSend := true
-	_, err := SendToDelivery(pack, recvAfterSend, justSend)
+	})
 	if err != nil {
-		fmt.Printf("Erro ao enviar para o gdeliveryd: %v\n", err)
+		return err
+	}
+	if resp.RetCode != 0 {
+		return &RetCodeError{Opcode: OpSysSendMail, RetCode: int32(resp.RetCode)}
 	}
+	return nil
 }
@@ -0,0 +1,328 @@
+package pwapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultCallTimeout é o tempo máximo de espera por uma resposta quando o contexto da chamada não possui prazo
+const DefaultCallTimeout = 10 * time.Second
+
+// xidRequest é o bit que marca o xid de uma requisição RPC, a resposta traz o mesmo xid com este bit zerado
+const xidRequest = 0x80000000
+
+// ErrMuxClosed indica que a conexão multiplexada foi encerrada
+var ErrMuxClosed = errors.New("conexão multiplexada encerrada")
+
+// ErrNotFound indica que o personagem ou a conta consultada não existe
+var ErrNotFound = errors.New("não encontrado")
+
+// ErrMailboxFull indica que o e-mail foi recusado porque a caixa de correio do personagem está cheia
+var ErrMailboxFull = errors.New("caixa de correio cheia")
+
+// ErrInvalidAttachment indica que o e-mail foi recusado porque o item ou o dinheiro anexado é inválido
+var ErrInvalidAttachment = errors.New("anexo inválido")
+
+// Códigos de retorno dos serviços reconhecidos por RetCodeError.Is
+//
+// Observações:
+//
+//	SysSendMail_Re repassa o ERR_DATANOTFIND do gamedbd quando o personagem não existe
+const (
+	RetCodeNotFound          = 3   // dados inexistentes (ERR_DATANOTFIND)
+	RetCodeInvalidAttachment = 213 // anexo do e-mail inválido (ERR_MS_ATTACH_INV)
+	RetCodeMailboxFull       = 217 // caixa de correio cheia (ERR_MS_BOXFULL)
+)
+
+// RPCResponse é a resposta de uma RPC do gamedbd
+//
+// Observações:
+//
+//	Toda resposta RPC começa com o xid da requisição e o código de retorno, seguidos dos dados da resposta
+type RPCResponse struct {
+	XID     uint32
+	RetCode int32
+	Data    []byte
+}
+
+// RetCodeError indica que o serviço respondeu a RPC com um código de retorno diferente de zero
+type RetCodeError struct {
+	Opcode  uint32
+	RetCode int32
+}
+
+func (e *RetCodeError) Error() string {
+	return fmt.Sprintf("pwapi: rpc 0x%X retornou o código %d", e.Opcode, e.RetCode)
+}
+
+// Is faz errors.Is(err, ErrNotFound) reconhecer o código RetCodeNotFound, e ErrMailboxFull e ErrInvalidAttachment
+// os códigos de SysSendMail_Re
+func (e *RetCodeError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.RetCode == RetCodeNotFound
+	case ErrMailboxFull:
+		return e.Opcode == OpSysSendMail && e.RetCode == RetCodeMailboxFull
+	case ErrInvalidAttachment:
+		return e.Opcode == OpSysSendMail && e.RetCode == RetCodeInvalidAttachment
+	}
+	return false
+}
+
+// MuxConn multiplexa várias RPCs simultâneas sobre uma única conexão
+//
+// Observações:
+//
+//	Cada requisição recebe um xid único, gravado nos 4 primeiros bytes do payload (o campo Handler dos
+//	structs de argumento), e a resposta é entregue à chamada que aguarda aquele xid
+//	Uma goroutine lê continuamente a conexão, frames sem chamada correspondente são descartados
+type MuxConn struct {
+	conn net.Conn
+
+	// Timeout é aplicado às chamadas cujo contexto não possui prazo
+	Timeout time.Duration
+
+	// WriteTimeout limita o tempo de escrita de cada requisição
+	WriteTimeout time.Duration
+
+	// Logger recebe as mensagens de diagnóstico, como frames descartados, quando não é nil
+	Logger *log.Logger
+
+	// Recorder grava as requisições e respostas em uma captura quando não é nil, identificadas por Backend
+	Recorder *Recorder
+	Backend  string
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[uint32]pendingCall
+	nextID  uint32
+	err     error
+	done    chan struct{}
+}
+
+type pendingCall struct {
+	opcode uint32
+	ch     chan Frame
+}
+
+// NewMuxConn inicia a multiplexação sobre uma conexão já aberta
+func NewMuxConn(conn net.Conn) *MuxConn {
+	m := &MuxConn{
+		conn:         conn,
+		Timeout:      DefaultCallTimeout,
+		WriteTimeout: DefaultWriteTimeout,
+		pending:      map[uint32]pendingCall{},
+		done:         make(chan struct{}),
+	}
+	go m.readLoop()
+	return m
+}
+
+// Call envia uma RPC e aguarda a resposta correspondente
+//
+// Parâmetros:
+//
+//	ctx: context.Context - Contexto da chamada, seu prazo limita a escrita e a espera pela resposta
+//	opcode: uint32 - Opcode da RPC, a resposta utiliza o mesmo opcode
+//	payload: []byte - Argumento da RPC já empacotado, começando pelos 4 bytes do xid
+//
+// Retorno:
+//
+//	RPCResponse - Resposta da RPC
+//	error - Erro de escrita, ErrMuxClosed ou o erro do contexto
+func (m *MuxConn) Call(ctx context.Context, opcode uint32, payload []byte) (RPCResponse, error) {
+	if len(payload) < 4 {
+		return RPCResponse{}, fmt.Errorf("%w: payload da rpc sem xid", ErrShortBuffer)
+	}
+
+	if _, ok := ctx.Deadline(); !ok && m.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+
+	id, ch, err := m.register(opcode)
+	if err != nil {
+		return RPCResponse{}, err
+	}
+
+	req := append([]byte{}, payload...)
+	binary.BigEndian.PutUint32(req, id|xidRequest)
+	// A requisição é gravada antes do envio para que fique antes da resposta na captura
+	if m.Recorder != nil {
+		m.Recorder.Record(m.Backend, CaptureRequest, Frame{Opcode: opcode, Payload: req})
+	}
+	if err := m.write(ctx, EncodeFrame(opcode, req)); err != nil {
+		m.unregister(id)
+		return RPCResponse{}, err
+	}
+
+	select {
+	case frame := <-ch:
+		return parseRPCResponse(frame)
+	case <-m.done:
+		return RPCResponse{}, m.err
+	case <-ctx.Done():
+		m.unregister(id)
+		return RPCResponse{}, fmt.Errorf("erro ao aguardar a resposta 0x%X: %w", opcode, ctx.Err())
+	}
+}
+
+// Close encerra a conexão, chamadas pendentes recebem ErrMuxClosed
+func (m *MuxConn) Close() error {
+	m.fail(ErrMuxClosed)
+	return nil
+}
+
+// Closed indica se a conexão já foi encerrada
+func (m *MuxConn) Closed() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// InFlight retorna a quantidade de chamadas aguardando resposta
+func (m *MuxConn) InFlight() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.pending)
+}
+
+func (m *MuxConn) register(opcode uint32) (uint32, chan Frame, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return 0, nil, m.err
+	}
+
+	// 0x7FFFFFFF é o xid devolvido para o Handler -1 utilizado por chamadas sem multiplexação
+	for {
+		m.nextID = (m.nextID + 1) &^ xidRequest
+		if _, busy := m.pending[m.nextID]; !busy && m.nextID != 0x7FFFFFFF {
+			break
+		}
+	}
+
+	ch := make(chan Frame, 1)
+	m.pending[m.nextID] = pendingCall{opcode: opcode, ch: ch}
+	return m.nextID, ch, nil
+}
+
+func (m *MuxConn) unregister(id uint32) {
+	m.mu.Lock()
+	delete(m.pending, id)
+	m.mu.Unlock()
+}
+
+func (m *MuxConn) write(ctx context.Context, frame []byte) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+
+	m.conn.SetWriteDeadline(deadlineFor(ctx, m.WriteTimeout))
+	if _, err := m.conn.Write(frame); err != nil {
+		// Uma escrita parcial deixa o fluxo inconsistente, a conexão não pode mais ser utilizada
+		m.fail(err)
+		return fmt.Errorf("erro ao enviar para o socket: %w", err)
+	}
+	return nil
+}
+
+func (m *MuxConn) readLoop() {
+	for {
+		frame, err := ReadFrame(m.conn)
+		if err != nil {
+			m.fail(err)
+			return
+		}
+		if m.Recorder != nil {
+			m.Recorder.Record(m.Backend, CaptureResponse, frame)
+		}
+		if len(frame.Payload) < 4 {
+			continue
+		}
+
+		id := binary.BigEndian.Uint32(frame.Payload) &^ xidRequest
+		m.mu.Lock()
+		call, ok := m.pending[id]
+		if ok && call.opcode == frame.Opcode {
+			delete(m.pending, id)
+		}
+		m.mu.Unlock()
+
+		if ok && call.opcode == frame.Opcode {
+			call.ch <- frame
+		} else if m.Logger != nil {
+			m.Logger.Printf("Descartando frame 0x%X sem chamada correspondente (xid %d)", frame.Opcode, id)
+		}
+	}
+}
+
+// fail encerra a conexão com o erro informado, apenas o primeiro erro é mantido
+func (m *MuxConn) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return
+	}
+	if errors.Is(err, ErrMuxClosed) {
+		m.err = err
+	} else {
+		m.err = fmt.Errorf("%w: %w", ErrMuxClosed, err)
+	}
+	m.pending = map[uint32]pendingCall{}
+	m.conn.Close()
+	close(m.done)
+}
+
+// parseRPCResponse separa o xid e o código de retorno dos dados de uma resposta RPC
+func parseRPCResponse(frame Frame) (RPCResponse, error) {
+	if len(frame.Payload) < 8 {
+		return RPCResponse{}, &CodecError{Op: "unmarshal", Field: "header.rpc",
+			Err: fmt.Errorf("%w: precisa de 8 bytes, restam %d", ErrShortBuffer, len(frame.Payload))}
+	}
+	return RPCResponse{
+		XID:     binary.BigEndian.Uint32(frame.Payload),
+		RetCode: int32(binary.BigEndian.Uint32(frame.Payload[4:])),
+		Data:    frame.Payload[8:],
+	}, nil
+}
+
+// muxFor retorna a conexão multiplexada do serviço informado, abrindo uma nova caso a anterior tenha sido encerrada
+func (c *Client) muxFor(ctx context.Context, backend string) (*MuxConn, error) {
+	addr := c.addr(backend)
+	timeouts := c.timeoutsFor(backend)
+
+	c.muxesMu.Lock()
+	defer c.muxesMu.Unlock()
+
+	if m, ok := c.muxes[addr]; ok && !m.Closed() {
+		return m, nil
+	}
+
+	dialCtx, cancel := context.WithDeadline(ctx, deadlineFor(ctx, timeouts.Conexao))
+	defer cancel()
+	conn, err := dialContext(dialCtx, addr)
+	if err != nil {
+		return nil, err
+	}
+	m := NewMuxConn(conn)
+	m.Timeout = timeouts.Leitura
+	m.WriteTimeout = timeouts.Escrita
+	m.Logger = c.logger
+	m.Recorder = c.rec
+	m.Backend = backend
+	c.muxes[addr] = m
+	return m, nil
+}
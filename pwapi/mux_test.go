@@ -0,0 +1,171 @@
+package pwapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rpcReply monta a resposta de uma RPC com o xid da requisição, código de retorno 0 e os dados informados
+func rpcReply(opcode uint32, req Frame, data []byte) []byte {
+	payload := binary.BigEndian.AppendUint32(nil, binary.BigEndian.Uint32(req.Payload)&^xidRequest)
+	payload = binary.BigEndian.AppendUint32(payload, 0)
+	return EncodeFrame(opcode, append(payload, data...))
+}
+
+// dialMux abre uma MuxConn com o servidor falso
+func dialMux(t *testing.T, addr string) *MuxConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMuxConn(conn)
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestMuxConnConcurrentCalls(t *testing.T) {
+	const calls = 16
+	const opcode = 0x1F43
+
+	// O servidor lê todas as requisições e responde na ordem inversa, precedida de frames sem chamada correspondente
+	addr := fakeBackend(t, func(conn net.Conn) {
+		var reqs []Frame
+		for len(reqs) < calls {
+			frame, err := ReadFrame(conn)
+			if err != nil {
+				return
+			}
+			reqs = append(reqs, frame)
+		}
+		for i := len(reqs) - 1; i >= 0; i-- {
+			unknown := Frame{Payload: binary.BigEndian.AppendUint32(nil, 0x7FFFFFFE)}
+			conn.Write(rpcReply(opcode, unknown, []byte("desconhecido")))
+			conn.Write(rpcReply(opcode+1, reqs[i], []byte("outro opcode")))
+			conn.Write(rpcReply(opcode, reqs[i], reqs[i].Payload[4:]))
+		}
+		ReadFrame(conn)
+	})
+	m := dialMux(t, addr)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			want := []byte(fmt.Sprintf("chamada %d", i))
+			resp, err := m.Call(context.Background(), opcode, append([]byte{0xFF, 0xFF, 0xFF, 0xFF}, want...))
+			switch {
+			case err != nil:
+				errs <- err
+			case !bytes.Equal(resp.Data, want) || resp.RetCode != 0:
+				errs <- fmt.Errorf("chamada %d recebeu %+v", i, resp)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if n := m.InFlight(); n != 0 {
+		t.Errorf("InFlight = %d após todas as respostas", n)
+	}
+	if m.Closed() {
+		t.Error("frames descartados encerraram a conexão")
+	}
+}
+
+func TestMuxConnTimeout(t *testing.T) {
+	addr := fakeBackend(t, func(conn net.Conn) {
+		for {
+			if _, err := ReadFrame(conn); err != nil {
+				return
+			}
+		}
+	})
+	m := dialMux(t, addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := m.Call(ctx, 0x1F43, make([]byte, 8))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call sem resposta = %v, esperado context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Call retornou após %v", elapsed)
+	}
+
+	// Sem prazo no contexto vale MuxConn.Timeout
+	m.Timeout = 50 * time.Millisecond
+	if _, err := m.Call(context.Background(), 0x1F43, make([]byte, 8)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call com Timeout = %v, esperado context.DeadlineExceeded", err)
+	}
+
+	if n := m.InFlight(); n != 0 {
+		t.Errorf("InFlight = %d, chamadas expiradas devem ser removidas", n)
+	}
+	if m.Closed() {
+		t.Error("o prazo de uma chamada encerrou a conexão")
+	}
+}
+
+func TestMuxConnClosedMidCall(t *testing.T) {
+	// O servidor lê a primeira requisição e fecha a conexão sem responder
+	addr := fakeBackend(t, func(conn net.Conn) {
+		ReadFrame(conn)
+	})
+	m := dialMux(t, addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := m.Call(ctx, 0x1F43, make([]byte, 8)); !errors.Is(err, ErrMuxClosed) {
+		t.Errorf("Call com a conexão encerrada = %v, esperado ErrMuxClosed", err)
+	}
+	if !m.Closed() || m.InFlight() != 0 {
+		t.Errorf("Closed = %v, InFlight = %d após a queda da conexão", m.Closed(), m.InFlight())
+	}
+	if _, err := m.Call(ctx, 0x1F43, make([]byte, 8)); !errors.Is(err, ErrMuxClosed) {
+		t.Errorf("Call após a queda = %v, esperado ErrMuxClosed", err)
+	}
+}
+
+func TestMuxConnClose(t *testing.T) {
+	addr := fakeBackend(t, func(conn net.Conn) {
+		for {
+			if _, err := ReadFrame(conn); err != nil {
+				return
+			}
+		}
+	})
+	m := dialMux(t, addr)
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := m.Call(context.Background(), 0x1F43, make([]byte, 8))
+		errc <- err
+	}()
+	for m.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	m.Close()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrMuxClosed) {
+			t.Errorf("Call pendente após Close = %v, esperado ErrMuxClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Call pendente não retornou após Close")
+	}
+}
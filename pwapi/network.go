@@ -1,529 +1,296 @@
 package pwapi
 
 import (
-	"bytes"
+	"context"
 	"encoding/binary"
-	"errors"
 	"fmt"
-	"io"
 	"net"
-	"os"
-	"reflect"
 	"strconv"
-
-	"golang.org/x/text/encoding/unicode"
-	"golang.org/x/text/transform"
+	"time"
 )
 
-type PwCuint int32
-type Cuint PwCuint
+// SendToDelivery envia um pacote para o gdeliveryd
+//
+// Parâmetros:
+//
+//	data: []byte - Pacote completo, com cabeçalho
+//	respOpcode: uint32 - Opcode da resposta esperada
+//	justSend: bool - Quando true, apenas envia o pacote sem aguardar resposta
+//
+// Retorno:
+//
+//	[]byte - Frame completo da resposta, com cabeçalho
+//	error - Erro de conexão, escrita, leitura ou prazo excedido
+func SendToDelivery(data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	return SendToDeliveryCtx(context.Background(), data, respOpcode, justSend)
+}
 
-func (c *Cuint) UnmarshalBinary(reader *bytes.Reader) (int, error) {
-	// Read the first byte to determine the size
-	var b byte
-	b, err := reader.ReadByte()
+// SendToDeliveryCtx é a variante de SendToDelivery que respeita o cancelamento e o prazo de ctx
+func SendToDeliveryCtx(ctx context.Context, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	c, err := loadDefault()
 	if err != nil {
-		return 0, err // Handle error gracefully
+		return nil, err
 	}
+	return c.SendToDelivery(ctx, data, respOpcode, justSend)
+}
 
-	//volta o reader para o inicio
-	reader.Seek(-1, io.SeekCurrent)
-
-	size := 1
-	var min int64
-	min = 0
-	if b >= 0x80 {
-		if b < 0xC0 {
-			size = 2
-			min = 0x8000
-		} else if b < 0xE0 {
-			size = 4
-			min = 0xC0000000
-
-		} else {
-			size = 5
-		}
-	}
+// SendToProvider envia um pacote para o glinkd/provider, veja SendToDelivery
+func SendToProvider(data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	return SendToProviderCtx(context.Background(), data, respOpcode, justSend)
+}
 
-	// Ensure sufficient bytes are available
-	if reader.Len() < size {
-		return 0, errors.New("invalid cuint data: insufficient bytes")
+// SendToProviderCtx é a variante de SendToProvider que respeita o cancelamento e o prazo de ctx
+func SendToProviderCtx(ctx context.Context, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return nil, err
 	}
+	return c.SendToProvider(ctx, data, respOpcode, justSend)
+}
 
-	// Read the remaining bytes of the Cuint value
-	data := make([]byte, size)
+// SendToGamedBD envia um pacote para o gamedbd, veja SendToDelivery
+func SendToGamedBD(data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	return SendToGamedBDCtx(context.Background(), data, respOpcode, justSend)
+}
 
-	_, err = reader.Read(data) // Read the remaining bytes
+// SendToGamedBDCtx é a variante de SendToGamedBD que respeita o cancelamento e o prazo de ctx
+func SendToGamedBDCtx(ctx context.Context, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	c, err := loadDefault()
 	if err != nil {
-		if err == io.EOF {
-			return 0, err // Handle error gracefully
-		}
-		fmt.Printf("err1: %v\n", err)
-		return 0, err // Handle error gracefully
+		return nil, err
 	}
-	buf := bytes.NewReader(data)
-
-	var value int32
-	switch size {
-	case 1:
-		var tmp int8
-		err = binary.Read(buf, binary.BigEndian, &tmp)
-		if err != nil {
-			fmt.Printf("err1: %v\n", err)
-		}
+	return c.SendToGamedBD(ctx, data, respOpcode, justSend)
+}
 
-		value = int32(tmp)
-	case 2:
-		var tmp uint16
-		err = binary.Read(buf, binary.BigEndian, &tmp)
-		if err != nil {
-			fmt.Printf("err2: %v\n", err)
-		}
-		value = int32(tmp)
+// CallGamedBD envia uma RPC ao gamedbd pela conexão multiplexada e aguarda a resposta
+//
+// Parâmetros:
+//
+//	opcode: uint32 - Opcode da RPC
+//	payload: []byte - Argumento da RPC empacotado, sem cabeçalho, começando pelo campo Handler
+//
+// Retorno:
+//
+//	RPCResponse - Resposta da RPC, o código de retorno não é verificado
+//	error - Erro de conexão, escrita, leitura ou prazo excedido
+//
+// Observações:
+//
+//	Pode ser chamada de várias goroutines ao mesmo tempo, as respostas são correlacionadas pelo xid
+func CallGamedBD(opcode uint32, payload []byte) (RPCResponse, error) {
+	return CallGamedBDCtx(context.Background(), opcode, payload)
+}
 
-	case 4:
-		err = binary.Read(buf, binary.BigEndian, &value)
-		if err != nil {
-			fmt.Printf("err4: %v\n", err)
-		}
-	case 5:
-		fmt.Println("Tamanho de 5 bytes não suportado neste exemplo.")
-		return 0, nil
-	default:
-		fmt.Println("Tamanho inválido.")
-		return 0, nil
+// CallGamedBDCtx é a variante de CallGamedBD que respeita o cancelamento e o prazo de ctx
+func CallGamedBDCtx(ctx context.Context, opcode uint32, payload []byte) (RPCResponse, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return RPCResponse{}, err
 	}
-
-	// Assign the integer value to the Cuint
-	*c = Cuint(value - int32(min))
-	return size, nil
+	return c.CallGamedBD(ctx, opcode, payload)
 }
 
-func createPack(args ...interface{}) []byte {
-	var packBuffer bytes.Buffer
+// SendToSocket envia um pacote para um serviço e aguarda a resposta
+//
+// Parâmetros:
+//
+//	data: []byte - Pacote completo, com cabeçalho
+//	port: int - Porta do serviço no IP do cliente padrão
+//	respOpcode: uint32 - Opcode da resposta esperada
+//	justSend: bool - Quando true, apenas envia o pacote sem aguardar resposta
+//
+// Retorno:
+//
+//	[]byte - Frame completo da resposta, com cabeçalho
+//	error - Erro de conexão, escrita, leitura ou prazo excedido
+//
+// Observações:
+//
+//	A resposta é lida frame a frame a partir do opcode e do tamanho no cabeçalho, frames com outro opcode
+//	(challenge e keepalive do gdeliveryd, por exemplo) são descartados até a resposta esperada chegar
+//	A conexão é obtida do pool do serviço e devolvida ao final, caso a escrita falhe em uma conexão reaproveitada
+//	que o serviço fechou o envio é repetido em uma conexão nova. Falhas após a escrita não são repetidas
+//	São utilizados os timeouts padrão, para os timeouts configurados por serviço use SendToDelivery e similares
+func SendToSocket(data []byte, port int, respOpcode uint32, justSend bool) ([]byte, error) {
+	return SendToSocketCtx(context.Background(), data, port, respOpcode, justSend)
+}
 
-	for _, arg := range args {
-		v := reflect.ValueOf(arg)
-		if v.Kind() != reflect.Struct {
-			fmt.Println("Erro: fornecido um valor inválido para a função. Deve ser um ponteiro não nulo.")
-			os.Exit(1)
-		}
-		// Iterate through the fields of the struct
-		for i := 0; i < v.NumField(); i++ {
-			field := v.Field(i)
-			switch field.Kind() {
-			case reflect.Uint8:
-				binary.Write(&packBuffer, binary.BigEndian, field.Interface().(uint8))
-			case reflect.Int:
-				binary.Write(&packBuffer, binary.BigEndian, int32(field.Interface().(int)))
-			case reflect.String:
-				packString(&packBuffer, field.Interface().(string))
-			case reflect.Slice:
-				size := field.Len()
-
-				var sizebytes []byte
-
-				if size < 64 {
-					sizebytes = []byte{byte(size)}
-				} else if size < 16384 {
-					sizebytes = []byte{byte((size >> 8) | 0x80), byte(size)}
-				} else if size < 536870912 {
-					sizebytes = []byte{byte((size >> 24) | 0xC0), byte((size >> 16) & 0xFF), byte((size >> 8) & 0xFF), byte(size & 0xFF)}
-				} else {
-					sizebytes = []byte{byte(0xE0), byte((size >> 24) & 0xFF), byte((size >> 16) & 0xFF), byte((size >> 8) & 0xFF), byte(size & 0xFF)}
-				}
-
-				binary.Write(&packBuffer, binary.BigEndian, sizebytes)
-				binary.Write(&packBuffer, binary.BigEndian, field.Interface())
-			case reflect.Struct:
-				var bytes []byte
-				bytes = createPack(field.Interface())
-				binary.Write(&packBuffer, binary.BigEndian, bytes)
-			default:
-				fmt.Printf("Tipo de campo inválido: %v\n", field.Kind())
-				os.Exit(1)
-
-			}
-		}
+// SendToSocketCtx é a variante de SendToSocket que respeita o cancelamento e o prazo de ctx
+func SendToSocketCtx(ctx context.Context, data []byte, port int, respOpcode uint32, justSend bool) ([]byte, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return nil, err
 	}
-
-	return packBuffer.Bytes()
+	return c.SendToSocket(ctx, data, port, respOpcode, justSend)
 }
 
-func packString(buf *bytes.Buffer, s string) {
-
-	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder()
-	transformed, _, _ := transform.String(utf16le, s)
-	//transforma em bytes
-	bytes := []byte(transformed)
-	size := len(bytes)
-	var sizebytes []byte
+// SendToDelivery envia um pacote para o gdeliveryd do servidor, veja a função SendToDelivery
+func (c *Client) SendToDelivery(ctx context.Context, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	return c.sendToBackend(ctx, "gdeliveryd", data, respOpcode, justSend)
+}
 
-	if size < 64 {
-		sizebytes = []byte{byte(size)}
-	} else if size < 16384 {
-		sizebytes = []byte{byte((size >> 8) | 0x80), byte(size)}
+// SendToProvider envia um pacote para o provider do servidor, veja a função SendToProvider
+func (c *Client) SendToProvider(ctx context.Context, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	return c.sendToBackend(ctx, "provider", data, respOpcode, justSend)
+}
 
-	} else if size < 536870912 {
-		sizebytes = []byte{byte((size >> 24) | 0xC0), byte((size >> 16) & 0xFF), byte((size >> 8) & 0xFF), byte(size & 0xFF)}
-	} else {
-		sizebytes = []byte{byte(0xE0), byte((size >> 24) & 0xFF), byte((size >> 16) & 0xFF), byte((size >> 8) & 0xFF), byte(size & 0xFF)}
-	}
-	buf.Write(sizebytes)
-	buf.Write(bytes)
+// SendToGamedBD envia um pacote para o gamedbd do servidor, veja a função SendToGamedBD
+func (c *Client) SendToGamedBD(ctx context.Context, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	return c.sendToBackend(ctx, "gamedbd", data, respOpcode, justSend)
 }
 
-func unpackData(data []byte, structure interface{}) []byte {
-	buf := bytes.NewReader(data)
-	destValue := reflect.ValueOf(structure)
-	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
-		fmt.Println("Erro: fornecido um valor inválido para a função. Deve ser um ponteiro não nulo.")
-		return nil
-	}
+// CallGamedBD envia uma RPC ao gamedbd do servidor, veja a função CallGamedBD
+func (c *Client) CallGamedBD(ctx context.Context, opcode uint32, payload []byte) (RPCResponse, error) {
+	return c.callRPC(ctx, "gamedbd", opcode, payload)
+}
 
-	destValue = destValue.Elem()
-
-	var cycleCount int
-	cycleCount = -1
-	for i := 0; i < destValue.NumField(); i++ {
-		field := destValue.Field(i)
-		fieldType := field.Type()
-
-		if cycleCount > -1 {
-			if cycleCount > 0 {
-				for j := 0; j < cycleCount; j++ {
-					//posicao do ponteiro
-					item := reflect.New(fieldType.Elem()).Elem()
-					outradata := unpackData(data, item.Addr().Interface())
-					data = outradata
-					field.Set(reflect.Append(field, item))
-				}
-
-				cycleCount = -1
-
-			} else {
-
-				//posicao do ponteiro
-				item := reflect.New(fieldType.Elem()).Elem()
-				outradata := unpackData(data, item.Addr().Interface())
-				data = outradata
-				field.Set(reflect.Append(field, item))
-
-			}
-		} else {
-			switch fieldType {
-			case reflect.TypeOf(int(0)):
-				value := int(binary.BigEndian.Uint32(data[:4]))
-				field.SetInt(int64(value))
-				data = data[4:]
-			case reflect.TypeOf(UserID(0)):
-				value := int(binary.BigEndian.Uint32(data[:4]))
-				field.SetInt(int64(value))
-				data = data[4:]
-			case reflect.TypeOf(int64(0)):
-				var value int64
-				error := binary.Read(buf, binary.BigEndian, &value)
-				if error != nil {
-					fmt.Printf("Erro ao ler: %v\n", error)
-				}
-
-				field.SetInt(value)
-				data = data[int(binary.Size(value)):]
-			case reflect.TypeOf(byte(0)):
-				var value byte
-				value = data[0]
-				data = data[1:]
-
-				field.SetUint(uint64(value))
-
-			case reflect.TypeOf(Cuint(0)):
-				buf = bytes.NewReader(data)
-				var cui Cuint
-				size, error := cui.UnmarshalBinary(buf)
-				if error != nil {
-					fmt.Printf("Erro ao ler: %v\n", error)
-					os.Exit(1)
-				}
-				// Save the position of the pointer after reading the value
-				field.Set(reflect.ValueOf(cui))
-
-				data = data[size:]
-				if cui > 0 {
-					cycleCount = int(cui)
-				} else {
-					cycleCount = -1
-				}
-			case reflect.TypeOf(float32(0)):
-				var value float32
-				error := binary.Read(buf, binary.BigEndian, &value)
-				if error != nil {
-					fmt.Printf("Erro ao ler: %v\n", error)
-				}
-				field.SetFloat(float64(value))
-
-				data = data[4:]
-			case reflect.TypeOf([]byte{}):
-				buf = bytes.NewReader(data)
-				var length Cuint
-				size, error := length.UnmarshalBinary(buf)
-				if error != nil {
-					fmt.Printf("Erro ao ler: %v\n", error)
-					os.Exit(1)
-				}
-
-				// Read the remaining bytes of the string
-				value := make([]byte, length)
-
-				err := binary.Read(buf, binary.BigEndian, &value)
-				if err != nil {
-					fmt.Printf("Erro ao ler: %v\n", err)
-
-				}
-
-				field.Set(reflect.ValueOf(value))
-				data = data[size+int(length):]
-			case reflect.TypeOf(uint16(0)):
-				var value uint16
-				error := binary.Read(buf, binary.BigEndian, &value)
-				if error != nil {
-					fmt.Printf("Erro ao ler: %v\n", error)
-				}
-				field.SetUint(uint64(value))
-				data = data[2:]
-			case reflect.TypeOf(uint(0)):
-				var value uint32
-				error := binary.Read(buf, binary.BigEndian, &value)
-				if error != nil {
-					fmt.Printf("Erro ao ler: %v\n", error)
-				}
-				field.SetUint(uint64(value))
-				data = data[4:]
-
-			case reflect.TypeOf(string("")):
-				size := 1
-				if data[0] >= 0x80 {
-					if data[0] < 0xC0 {
-						size = 2
-					} else if data[0] < 0xE0 {
-						size = 4
-					} else {
-						size = 5
-					}
-				}
-				var octetlen int
-				octetlen = 0
-				if size == 1 {
-					octetlen = int(data[0])
-				} else {
-					octetlen = int(data[0]) - 0x80 + int(data[1])
-				}
-
-				//discart size
-				data = data[size:]
-				// Read the remaining bytes of the string
-				str := string(data[:octetlen])
-				//change encoding
-				utf16le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
-				transformed, _, _ := transform.String(utf16le, str)
-				str = transformed
-				// Save the position of the pointer after reading the value
-
-				field.SetString(str)
-				data = data[octetlen:]
-
-			}
+// callRPC envia uma RPC pela conexão multiplexada do serviço
+func (c *Client) callRPC(ctx context.Context, backend string, opcode uint32, payload []byte) (RPCResponse, error) {
+	if c.replay != nil {
+		frame, err := c.replay.exchange(backend, Frame{Opcode: opcode, Payload: payload}, opcode, false, true)
+		if err != nil {
+			return RPCResponse{}, err
 		}
+		return parseRPCResponse(frame)
 	}
 
-	return data
-}
-
-// cuint converte um número inteiro não negativo em um slice de bytes
-// no formato compact uint.
-func cuint(data uint32) []byte {
-	if data < 64 {
-		return []byte{byte(data)}
-	} else if data < 16384 {
-		return []byte{byte((data >> 8) | 0x80), byte(data)}
-	} else if data < 536870912 {
-		return []byte{byte((data >> 24) | 0xC0), byte((data >> 16) & 0xFF), byte((data >> 8) & 0xFF), byte(data & 0xFF)}
+	done, err := c.breakerFor(backend).allow(ctx)
+	if err != nil {
+		return RPCResponse{}, err
 	}
 
-	return []byte{byte(0xE0), byte((data >> 24) & 0xFF), byte((data >> 16) & 0xFF), byte((data >> 8) & 0xFF), byte(data & 0xFF)}
-}
-
-func createHeader(opcodeHex string, data []byte) []byte {
-	// Converte a string hexadecimal para um inteiro
-	opcode, err := strconv.ParseInt(opcodeHex, 16, 32)
+	m, err := c.muxFor(ctx, backend)
 	if err != nil {
-		fmt.Printf("Erro ao converter o opcode: %v\n", err)
-		os.Exit(1)
+		done(err)
+		return RPCResponse{}, fmt.Errorf("erro ao conectar ao socket: %w", contextErr(ctx, err))
 	}
-	// Empacota o opcode como um inteiro de 32 bits
-	opcodeBytes := cuint(uint32(opcode))
-
-	// Empacota o comprimento do pacote como um inteiro de 16 bits
-	lengthBytes := cuint(uint32(len(data)))
-
-	// Cria um buffer para armazenar os bytes do cabeçalho
-	var headerBuffer bytes.Buffer
-
-	// Escreve os bytes do opcode
-	headerBuffer.Write(opcodeBytes)
-	// Escreve os bytes do comprimento do pacote
-	headerBuffer.Write(lengthBytes)
-	// Escreve os bytes do pacote
-	headerBuffer.Write(data)
-
-	// Retorna os bytes do cabeçalho empacotado
-	return headerBuffer.Bytes()
-}
-
-func SendToDelivery(data []byte, recvAfterSend bool, justSend bool) ([]byte, error) {
-
-	port := AppConfig.Ports["gdeliveryd"]
-	return SendToSocket(data, port, recvAfterSend, nil, justSend)
+	response, err := m.Call(ctx, opcode, payload)
+	done(err)
+	return response, err
 }
 
-func SendToProvider(data []byte, recvAfterSend bool, justSend bool) ([]byte, error) {
-
-	port := AppConfig.Ports["provider"]
-	buf := make([]byte, 8196)
-
-	return SendToSocket(data, port, recvAfterSend, buf, justSend)
+// SendToSocket envia um pacote para uma porta qualquer do servidor, veja a função SendToSocket
+func (c *Client) SendToSocket(ctx context.Context, data []byte, port int, respOpcode uint32, justSend bool) ([]byte, error) {
+	addr := net.JoinHostPort(c.ip, strconv.Itoa(port))
+	return c.sendToAddr(ctx, c.backendFor(port), addr, c.timeoutsFor(""), data, respOpcode, justSend)
 }
-func SendToGamedBD(data []byte, recvAfterSend bool, justSend bool) ([]byte, error) {
-	port := AppConfig.Ports["gamedbd"]
-	buf := make([]byte, 8196)
 
-	return SendToSocket(data, port, recvAfterSend, buf, justSend)
+func (c *Client) sendToBackend(ctx context.Context, backend string, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	return c.sendToAddr(ctx, backend, c.addr(backend), c.timeoutsFor(backend), data, respOpcode, justSend)
 }
 
-func SendToSocket(data []byte, port int, recvAfterSend bool, buf []byte, justSend bool) ([]byte, error) {
-
-	conn, err := net.Dial("tcp", net.JoinHostPort(AppConfig.IP, strconv.Itoa(port)))
-	if err != nil {
-		fmt.Printf("Erro ao conectar ao socket: %v\n", err)
-		return nil, err
+func (c *Client) sendToAddr(ctx context.Context, backend, addr string, timeouts TimeoutConfig, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	if c.replay != nil {
+		return c.replayPacket(backend, data, respOpcode, justSend)
 	}
-	defer conn.Close()
 
-	_, err = conn.Write(data)
+	done, err := c.breakerFor(backend).allow(ctx)
 	if err != nil {
-		fmt.Printf("Erro ao enviar para o socket: %v\n", err)
 		return nil, err
 	}
+	response, err := c.sendPooled(ctx, backend, addr, timeouts, data, respOpcode, justSend)
+	done(err)
+	return response, err
+}
 
-	if justSend {
-		return nil, nil
-	}
-	var tmp []byte
-
-	// Leia e descarte um número fixo de bytes após o envio
-	var readLen int
-	readLen = 23
-	tmp = make([]byte, readLen)
-	_, err23 := io.ReadFull(conn, tmp)
-	if err23 != nil {
-		return nil, err23
-	}
-
-	// Reproduz o comportamento de leitura da função PHP, atendendo aos diferentes casos
-	switch 3 {
-	case 1:
-		// Leia um número fixo de bytes
-		tmp := make([]byte, 8192)
-
-		_, err = io.ReadFull(conn, tmp)
+// sendPooled envia o pacote por uma conexão do pool, repetindo-o uma vez quando a conexão reaproveitada estava quebrada
+//
+// Observações:
+//
+//	O envio só é repetido quando a escrita falhou. Após a escrita o serviço pode ter recebido e processado o
+//	pacote, um erro na leitura da resposta é retornado para não entregar um SysSendMail duas vezes
+func (c *Client) sendPooled(ctx context.Context, backend, addr string, timeouts TimeoutConfig, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	pool := c.poolFor(addr)
+	for {
+		dialCtx, cancel := context.WithDeadline(ctx, deadlineFor(ctx, timeouts.Conexao))
+		conn, err := pool.Get(dialCtx)
+		cancel()
 		if err != nil {
-			return nil, err
-		}
-	case 2:
-		// Leia até encontrar um buffer com menos de 1024 bytes
-		var buffer []byte
-		for {
-			buffer = make([]byte, 1024)
-			n, err := conn.Read(buffer)
-			if err != nil {
-				return nil, err
-			}
-			buf = append(buf, buffer[:n]...)
-			if n < 1024 {
-				break
-			}
+			return nil, fmt.Errorf("erro ao conectar ao socket: %w", contextErr(ctx, err))
 		}
-	case 3:
-		// Leia o cabeçalho de 8 bytes contendo o tamanho da mensagem
-		var buf []byte
-		if !recvAfterSend {
-			buf = tmp
-		} else {
-			buf = make([]byte, 16)
-			_, err = conn.Read(buf)
-			if err != nil {
-				return nil, err
-			}
-			readLen = 16
-		}
-
-		bufReader := bytes.NewReader(buf)
 
-		var cui Cuint
-		var size int
-		size, error := cui.UnmarshalBinary(bufReader)
-		if error != nil {
-			fmt.Printf("Erro ao ler: %v\n", error)
+		response, written, err := c.exchange(ctx, backend, conn, timeouts, data, respOpcode, justSend)
+		if err == nil {
+			pool.Put(conn, true)
+			return response, nil
 		}
 
-		var length Cuint
-		var size2 int
-		size2, error = length.UnmarshalBinary(bufReader)
-
-		if error != nil {
-			fmt.Printf("Erro ao ler: %v\n", error)
+		pool.Put(conn, false)
+		if conn.reused && !written && isBrokenConn(err) && ctx.Err() == nil {
+			continue
 		}
-		restante := int64(length) - int64(readLen) + int64(size) + int64(size2)
-		if restante > 0 {
-			restobuf := make([]byte, restante)
-			_, err := conn.Read(restobuf)
-			if err != nil {
-				return nil, err
-			}
-
-			//juntar os buffers
-			buf = append(buf, restobuf...)
-		}
-
-		return buf, nil
+		return nil, contextErr(ctx, err)
 	}
-
-	return buf, nil
 }
 
-func deleteHeader(data []byte) []byte {
+// exchange escreve o pacote na conexão e lê a resposta esperada
+//
+// Retorno:
+//
+//	[]byte - Frame completo da resposta, nil quando justSend
+//	bool - true quando o pacote foi escrito, mesmo que a leitura da resposta tenha falhado
+//	error - Erro de escrita, leitura ou prazo excedido
+func (c *Client) exchange(ctx context.Context, backend string, conn *poolConn, timeouts TimeoutConfig, data []byte, respOpcode uint32, justSend bool) ([]byte, bool, error) {
+	stop := watchContext(ctx, conn)
+	defer stop()
+
+	conn.SetWriteDeadline(deadlineFor(ctx, timeouts.Escrita))
+	_, err := conn.Write(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("erro ao enviar para o socket: %w", err)
+	}
+	if c.rec != nil {
+		c.rec.recordPacket(backend, data)
+	}
 
-	length := 8
-	var cuint1 Cuint
-	var size int
-	size, error := cuint1.UnmarshalBinary(bytes.NewReader(data))
-	if error != nil {
-		fmt.Printf("Erro ao ler: %v\n", error)
+	if justSend {
+		conn.SetDeadline(time.Time{})
+		return nil, true, nil
 	}
 
-	//remove size bytes
-	data = data[size:]
+	conn.SetReadDeadline(deadlineFor(ctx, timeouts.Leitura))
+	frame, err := readFrameFor(conn, respOpcode, func(skipped Frame) {
+		if c.rec != nil {
+			c.rec.Record(backend, CaptureResponse, skipped)
+		}
+		c.debugf("Descartando frame 0x%X (%d bytes) aguardando 0x%X", skipped.Opcode, len(skipped.Payload), respOpcode)
+	})
+	if err != nil {
+		return nil, true, fmt.Errorf("erro ao ler a resposta 0x%X: %w", respOpcode, err)
+	}
+	conn.SetDeadline(time.Time{})
+	if c.rec != nil {
+		c.rec.Record(backend, CaptureResponse, frame)
+	}
+
+	return frame.Raw, true, nil
+}
 
-	var cuint2 Cuint
-	size, error = cuint2.UnmarshalBinary(bytes.NewReader(data))
-	if error != nil {
-		fmt.Printf("Erro ao ler: %v\n", error)
+// replayPacket devolve a resposta gravada na captura para o pacote, no lugar de enviá-lo ao serviço
+func (c *Client) replayPacket(backend string, data []byte, respOpcode uint32, justSend bool) ([]byte, error) {
+	req, _, err := ParseFrame(data)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := c.replay.exchange(backend, req, respOpcode, justSend, false)
+	if err != nil || justSend {
+		return nil, err
 	}
+	return frame.Raw, nil
+}
 
-	//remove size bytes
-	data = data[size:]
+// deleteHeader remove o cabeçalho do frame e o cabeçalho RPC (xid e retcode) de uma resposta do gamedbd
+func deleteHeader(data []byte) ([]byte, error) {
+	frame, _, err := ParseFrame(data)
+	if err != nil {
+		return nil, err
+	}
 
-	data = data[length:]
-	return data
+	response, err := parseRPCResponse(frame)
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
 }
 
 func ConvertToBytes(inputString string) ([]byte, error) {
@@ -0,0 +1,64 @@
+package pwapi
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// PacketType descreve um pacote conhecido do protocolo, utilizado para decodificar frames capturados
+type PacketType struct {
+	Name    string       // nome do pacote, ex: GetRoleBaseArg
+	Opcode  uint32       // opcode do frame
+	Dir     string       // CaptureRequest ou CaptureResponse
+	Backend string       // serviço que recebe ou envia o pacote
+	RPC     bool         // resposta RPC, o payload começa com xid e retcode antes dos dados
+	Type    reflect.Type // struct com o formato do payload
+}
+
+type packetKey struct {
+	opcode uint32
+	dir    string
+}
+
+var (
+	packetsMu sync.RWMutex
+	packets   = map[packetKey]PacketType{}
+)
+
+// RegisterPacket adiciona um pacote ao registro, substituindo o registro anterior do mesmo opcode e direção
+//
+// Parâmetros:
+//
+//	p: PacketType - Pacote a ser registrado, Type pode ser informado com reflect.TypeOf(Struct{})
+func RegisterPacket(p PacketType) {
+	packetsMu.Lock()
+	packets[packetKey{p.Opcode, p.Dir}] = p
+	packetsMu.Unlock()
+}
+
+// LookupPacket retorna o pacote registrado para o opcode e a direção informados
+func LookupPacket(opcode uint32, dir string) (PacketType, bool) {
+	packetsMu.RLock()
+	defer packetsMu.RUnlock()
+	p, ok := packets[packetKey{opcode, dir}]
+	return p, ok
+}
+
+// Packets retorna todos os pacotes registrados, ordenados por opcode e direção
+func Packets() []PacketType {
+	packetsMu.RLock()
+	list := make([]PacketType, 0, len(packets))
+	for _, p := range packets {
+		list = append(list, p)
+	}
+	packetsMu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Opcode != list[j].Opcode {
+			return list[i].Opcode < list[j].Opcode
+		}
+		return list[i].Dir < list[j].Dir
+	})
+	return list
+}
@@ -0,0 +1,312 @@
+package pwapi
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Vocabulário da tag `pw` reconhecido pelo codec
+//
+// Observações:
+//
+//	A tag define como o campo é representado no pacote, independente do tipo Go utilizado no struct
+//	Campos sem tag usam a representação padrão do seu tipo (int → int32, string → utf16, []byte → octets, ...)
+//
+//	pw:"-"                     campo ignorado
+//	pw:"int8" ... pw:"uint64"  inteiro big-endian com a largura indicada
+//	pw:"float32", "float64"    ponto flutuante big-endian
+//	pw:"cuint"                 inteiro no formato compact uint
+//	pw:"octets"                bytes precedidos pelo tamanho em cuint ([]byte ou string)
+//	pw:"utf16"                 string UTF-16LE precedida pelo tamanho em cuint (alias: "string")
+//	pw:"gbk"                   string GBK precedida pelo tamanho em cuint
+//	pw:"fixed=N"               octets/string com exatamente N bytes, sem prefixo de tamanho
+//	pw:"array"                 slice precedido pela quantidade de elementos em cuint
+//	pw:"array,len=Campo"       slice cuja quantidade de elementos está no campo inteiro anterior Campo
+type wireType int
+
+const (
+	wireInvalid wireType = iota
+	wireInt8
+	wireUint8
+	wireInt16
+	wireUint16
+	wireInt32
+	wireUint32
+	wireInt64
+	wireUint64
+	wireFloat32
+	wireFloat64
+	wireCuint
+	wireOctets
+	wireUTF16
+	wireGBK
+	wireArray
+	wireStruct
+)
+
+var wireNames = map[string]wireType{
+	"int8":    wireInt8,
+	"uint8":   wireUint8,
+	"byte":    wireUint8,
+	"int16":   wireInt16,
+	"uint16":  wireUint16,
+	"int32":   wireInt32,
+	"uint32":  wireUint32,
+	"int64":   wireInt64,
+	"uint64":  wireUint64,
+	"float32": wireFloat32,
+	"float64": wireFloat64,
+	"cuint":   wireCuint,
+	"octets":  wireOctets,
+	"utf16":   wireUTF16,
+	"string":  wireUTF16,
+	"gbk":     wireGBK,
+	"array":   wireArray,
+}
+
+// fieldCodec descreve como um valor de um determinado tipo é lido e gravado no pacote
+type fieldCodec struct {
+	wire  wireType
+	fixed int         // tamanho fixo em bytes (fixed=N), 0 quando o tamanho vem do prefixo cuint
+	elem  *fieldCodec // codec dos elementos de slices e arrays
+	plan  *structPlan // plano de structs aninhados
+}
+
+// fieldPlan descreve um campo de um struct de protocolo
+type fieldPlan struct {
+	index   int
+	name    string
+	codec   *fieldCodec
+	lenFrom int // índice do campo que carrega a quantidade de elementos deste slice, -1 quando prefixado
+	lenFor  int // índice do slice cuja quantidade este campo carrega, -1 quando nenhum
+}
+
+// structPlan é a lista de campos de um struct já resolvida a partir das tags
+type structPlan struct {
+	name   string
+	fields []fieldPlan
+}
+
+var (
+	planMu    sync.Mutex
+	planCache = map[reflect.Type]*structPlan{}
+
+	// planReady contém apenas planos completos e é lido sem planMu, que fica restrito à construção
+	planReady sync.Map // reflect.Type → *structPlan
+)
+
+// planFor retorna o plano de um tipo struct, construindo e armazenando em cache na primeira chamada
+func planFor(t reflect.Type) (*structPlan, error) {
+	if p, ok := planReady.Load(t); ok {
+		return p.(*structPlan), nil
+	}
+
+	planMu.Lock()
+	defer planMu.Unlock()
+	p, err := buildPlan(t)
+	if err == nil {
+		planReady.Store(t, p)
+	}
+	return p, err
+}
+
+// buildPlan deve ser chamado com planMu bloqueado
+func buildPlan(t reflect.Type) (*structPlan, error) {
+	if p, ok := planCache[t]; ok {
+		return p, nil
+	}
+
+	// O plano é registrado antes dos campos para suportar tipos recursivos
+	p := &structPlan{name: t.Name()}
+	planCache[t] = p
+
+	byName := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("pw")
+		if tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		codec, lenName, err := parseFieldTag(sf.Type, tag)
+		if err != nil {
+			delete(planCache, t)
+			return nil, &CodecError{Field: t.Name() + "." + sf.Name, Err: err}
+		}
+
+		fp := fieldPlan{index: i, name: sf.Name, codec: codec, lenFrom: -1, lenFor: -1}
+		if lenName != "" {
+			j, ok := byName[lenName]
+			if !ok || !isIntegerWire(p.fields[j].codec.wire) {
+				delete(planCache, t)
+				return nil, &CodecError{Field: t.Name() + "." + sf.Name,
+					Err: fmt.Errorf("%w: len=%s deve referenciar um campo inteiro anterior", ErrBadTag, lenName)}
+			}
+			fp.lenFrom = p.fields[j].index
+			p.fields[j].lenFor = i
+		}
+
+		byName[sf.Name] = len(p.fields)
+		p.fields = append(p.fields, fp)
+	}
+	return p, nil
+}
+
+// parseFieldTag interpreta a tag `pw` de um campo
+func parseFieldTag(t reflect.Type, tag string) (*fieldCodec, string, error) {
+	wire := wireInvalid
+	fixed := 0
+	lenName := ""
+
+	if tag != "" {
+		for _, opt := range strings.Split(tag, ",") {
+			opt = strings.TrimSpace(opt)
+			switch {
+			case strings.HasPrefix(opt, "fixed="):
+				n, err := strconv.Atoi(strings.TrimPrefix(opt, "fixed="))
+				if err != nil || n <= 0 {
+					return nil, "", fmt.Errorf("%w: %q", ErrBadTag, opt)
+				}
+				fixed = n
+			case strings.HasPrefix(opt, "len="):
+				lenName = strings.TrimPrefix(opt, "len=")
+			default:
+				w, ok := wireNames[opt]
+				if !ok {
+					return nil, "", fmt.Errorf("%w: opção desconhecida %q", ErrBadTag, opt)
+				}
+				wire = w
+			}
+		}
+	}
+
+	codec, err := codecFor(t, wire, fixed)
+	if err != nil {
+		return nil, "", err
+	}
+	if lenName != "" && codec.wire != wireArray {
+		return nil, "", fmt.Errorf("%w: len= só é válido em arrays", ErrBadTag)
+	}
+	return codec, lenName, nil
+}
+
+// codecFor resolve o codec de um tipo Go, aplicando a representação da tag quando informada
+func codecFor(t reflect.Type, wire wireType, fixed int) (*fieldCodec, error) {
+	if wire == wireInvalid {
+		wire = defaultWire(t)
+		if wire == wireInvalid {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedKind, t)
+		}
+	}
+
+	c := &fieldCodec{wire: wire, fixed: fixed}
+	if !wireAccepts(wire, t) {
+		return nil, fmt.Errorf("%w: %s não pode ser representado como %s", ErrBadTag, t, wireLabel(wire))
+	}
+	if fixed > 0 && wire != wireOctets && wire != wireUTF16 && wire != wireGBK {
+		return nil, fmt.Errorf("%w: fixed= só é válido em octets e strings", ErrBadTag)
+	}
+
+	switch wire {
+	case wireArray:
+		elem, err := codecFor(t.Elem(), wireInvalid, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.elem = elem
+	case wireOctets:
+		if t.Kind() == reflect.Array {
+			c.fixed = t.Len()
+		}
+	case wireStruct:
+		p, err := buildPlan(t)
+		if err != nil {
+			return nil, err
+		}
+		c.plan = p
+	}
+	return c, nil
+}
+
+// defaultWire retorna a representação utilizada quando o campo não possui tag
+func defaultWire(t reflect.Type) wireType {
+	if t == cuintType {
+		return wireCuint
+	}
+	switch t.Kind() {
+	case reflect.Int8:
+		return wireInt8
+	case reflect.Uint8:
+		return wireUint8
+	case reflect.Int16:
+		return wireInt16
+	case reflect.Uint16:
+		return wireUint16
+	case reflect.Int, reflect.Int32:
+		return wireInt32
+	case reflect.Uint, reflect.Uint32:
+		return wireUint32
+	case reflect.Int64:
+		return wireInt64
+	case reflect.Uint64:
+		return wireUint64
+	case reflect.Float32:
+		return wireFloat32
+	case reflect.Float64:
+		return wireFloat64
+	case reflect.String:
+		return wireUTF16
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return wireOctets
+		}
+		return wireArray
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return wireOctets
+		}
+		return wireArray
+	case reflect.Struct:
+		return wireStruct
+	}
+	return wireInvalid
+}
+
+// wireAccepts verifica se o tipo Go pode ser representado com a representação informada
+func wireAccepts(w wireType, t reflect.Type) bool {
+	k := t.Kind()
+	switch {
+	case isIntegerWire(w):
+		return k >= reflect.Int && k <= reflect.Uint64
+	case w == wireFloat32 || w == wireFloat64:
+		return k == reflect.Float32 || k == reflect.Float64
+	case w == wireOctets:
+		return k == reflect.String || ((k == reflect.Slice || k == reflect.Array) && t.Elem().Kind() == reflect.Uint8)
+	case w == wireUTF16 || w == wireGBK:
+		return k == reflect.String
+	case w == wireArray:
+		return k == reflect.Slice || k == reflect.Array
+	case w == wireStruct:
+		return k == reflect.Struct
+	}
+	return false
+}
+
+func isIntegerWire(w wireType) bool {
+	return w >= wireInt8 && w <= wireCuint && w != wireFloat32 && w != wireFloat64
+}
+
+func wireLabel(w wireType) string {
+	for name, v := range wireNames {
+		if v == w && name != "string" && name != "byte" {
+			return name
+		}
+	}
+	if w == wireStruct {
+		return "struct"
+	}
+	return "inválido"
+}
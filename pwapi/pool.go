@@ -0,0 +1,278 @@
+package pwapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Valores padrão do pool quando não informados em Config.Conexoes
+const (
+	DefaultPoolSize        = 4
+	DefaultPoolIdleTimeout = 60 * time.Second
+)
+
+// healthCheckWait é a espera máxima da leitura que verifica se uma conexão ociosa foi fechada pelo serviço
+const healthCheckWait = time.Millisecond
+
+// PoolConfig define o comportamento dos pools de conexões com os serviços
+type PoolConfig struct {
+	Tamanho     int           `yaml:"Tamanho"`     // conexões ociosas mantidas por serviço, 0 usa DefaultPoolSize e -1 desativa o pool
+	TempoOcioso time.Duration `yaml:"TempoOcioso"` // tempo máximo que uma conexão pode ficar ociosa antes de ser descartada
+}
+
+// PoolStats contém os contadores de um pool, utilizados para diagnóstico
+type PoolStats struct {
+	Addr      string // endereço do serviço
+	Dials     int    // conexões novas abertas
+	Reuses    int    // vezes que uma conexão ociosa foi reaproveitada
+	Discarded int    // conexões descartadas por erro, expiração ou falha no health check
+	Idle      int    // conexões ociosas no momento
+	InUse     int    // conexões emprestadas no momento
+}
+
+// Pool mantém conexões TCP abertas com um serviço do Perfect World para serem reaproveitadas entre chamadas
+type Pool struct {
+	addr        string
+	size        int
+	idleTimeout time.Duration
+	dial        func(ctx context.Context, addr string) (net.Conn, error)
+
+	mu     sync.Mutex
+	idle   []*poolConn
+	stats  PoolStats
+	closed bool
+}
+
+// poolConn é uma conexão emprestada por um Pool
+//
+// Observações:
+//
+//	As leituras passam por um bufio.Reader para que o health check possa espiar o socket sem consumir dados
+type poolConn struct {
+	net.Conn
+	r        *bufio.Reader
+	pool     *Pool
+	reused   bool
+	lastUsed time.Time
+}
+
+func (c *poolConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// NewPool cria um pool para o endereço informado
+//
+// Parâmetros:
+//
+//	addr: string - Endereço do serviço no formato ip:porta
+//	size: int - Quantidade máxima de conexões ociosas mantidas
+//	idleTimeout: time.Duration - Tempo máximo que uma conexão pode ficar ociosa
+//
+// Retorno:
+//
+//	*Pool - Pool pronto para uso, nenhuma conexão é aberta até a primeira chamada de Get
+func NewPool(addr string, size int, idleTimeout time.Duration) *Pool {
+	return &Pool{
+		addr:        addr,
+		size:        size,
+		idleTimeout: idleTimeout,
+		dial:        dialContext,
+		stats:       PoolStats{Addr: addr},
+	}
+}
+
+// Get retorna uma conexão ociosa saudável ou abre uma nova, o prazo de ctx limita a abertura da conexão
+func (p *Pool) Get(ctx context.Context) (*poolConn, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, net.ErrClosed
+		}
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			break
+		}
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+
+		if p.healthy(c) {
+			p.mu.Lock()
+			p.stats.Reuses++
+			p.stats.InUse++
+			p.mu.Unlock()
+			c.reused = true
+			return c, nil
+		}
+		p.discard(c)
+	}
+
+	conn, err := p.dial(ctx, p.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.stats.Dials++
+	p.stats.InUse++
+	p.mu.Unlock()
+	return &poolConn{Conn: conn, r: bufio.NewReader(conn), pool: p}, nil
+}
+
+// Put devolve uma conexão ao pool, ou a fecha caso ela não esteja saudável ou o pool esteja cheio
+func (p *Pool) Put(c *poolConn, healthy bool) {
+	p.mu.Lock()
+	p.stats.InUse--
+	if !healthy || p.closed || len(p.idle) >= p.size {
+		p.mu.Unlock()
+		p.discard(c)
+		return
+	}
+	c.lastUsed = time.Now()
+	p.idle = append(p.idle, c)
+	p.mu.Unlock()
+}
+
+// Stats retorna os contadores atuais do pool
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	s.Idle = len(p.idle)
+	return s
+}
+
+// Close fecha todas as conexões ociosas, conexões emprestadas são fechadas ao serem devolvidas
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	for _, c := range idle {
+		c.Conn.Close()
+	}
+	return nil
+}
+
+func (p *Pool) discard(c *poolConn) {
+	c.Conn.Close()
+	p.mu.Lock()
+	p.stats.Discarded++
+	p.mu.Unlock()
+}
+
+// healthy verifica se uma conexão ociosa ainda pode ser utilizada
+//
+// Observações:
+//
+//	Conexões ociosas há mais de idleTimeout são descartadas, as demais são testadas com uma leitura de até
+//	healthCheckWait: EOF indica que o serviço fechou a conexão, timeout indica que ela continua aberta e dados
+//	pendentes (keepalive do gdeliveryd, por exemplo) ficam no buffer para serem descartados pelo ReadFrameFor
+//	O prazo precisa estar no futuro, com um prazo já vencido o Go retorna timeout sem ler o socket
+func (p *Pool) healthy(c *poolConn) bool {
+	if p.idleTimeout > 0 && time.Since(c.lastUsed) > p.idleTimeout {
+		return false
+	}
+	if err := c.SetReadDeadline(time.Now().Add(healthCheckWait)); err != nil {
+		return false
+	}
+	_, err := c.r.Peek(1)
+	c.SetReadDeadline(time.Time{})
+	return err == nil || errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// dialContext abre uma conexão TCP respeitando o cancelamento e o prazo de ctx
+func dialContext(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// isBrokenConn indica se o erro significa que o serviço fechou a conexão, caso em que vale tentar uma conexão nova
+func isBrokenConn(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, net.ErrClosed)
+}
+
+// poolFor retorna o pool do endereço informado, criando-o a partir da configuração do cliente quando necessário
+func (c *Client) poolFor(addr string) *Pool {
+	c.poolsMu.Lock()
+	defer c.poolsMu.Unlock()
+
+	if p, ok := c.pools[addr]; ok {
+		return p
+	}
+
+	size := c.poolCfg.Tamanho
+	if size == 0 {
+		size = DefaultPoolSize
+	} else if size < 0 {
+		size = 0
+	}
+	idleTimeout := c.poolCfg.TempoOcioso
+	if idleTimeout == 0 {
+		idleTimeout = DefaultPoolIdleTimeout
+	}
+
+	p := NewPool(addr, size, idleTimeout)
+	c.pools[addr] = p
+	return p
+}
+
+// PoolStats retorna os contadores de todos os pools abertos pelo cliente
+//
+// Retorno:
+//
+//	[]PoolStats - Um item por serviço utilizado desde a criação do cliente
+func (c *Client) PoolStats() []PoolStats {
+	c.poolsMu.Lock()
+	defer c.poolsMu.Unlock()
+
+	stats := make([]PoolStats, 0, len(c.pools))
+	for _, p := range c.pools {
+		stats = append(stats, p.Stats())
+	}
+	return stats
+}
+
+// closeConns fecha todas as conexões mantidas pelos pools e pelas conexões multiplexadas do cliente
+func (c *Client) closeConns() {
+	c.poolsMu.Lock()
+	for addr, p := range c.pools {
+		p.Close()
+		delete(c.pools, addr)
+	}
+	c.poolsMu.Unlock()
+
+	c.muxesMu.Lock()
+	for addr, m := range c.muxes {
+		m.Close()
+		delete(c.muxes, addr)
+	}
+	c.muxesMu.Unlock()
+}
+
+// GetPoolStats retorna os contadores de todos os pools abertos pelo cliente padrão
+func GetPoolStats() []PoolStats {
+	c, err := loadDefault()
+	if err != nil {
+		return nil
+	}
+	return c.PoolStats()
+}
+
+// ClosePools fecha todas as conexões mantidas pelos pools e pelas conexões multiplexadas do cliente padrão
+func ClosePools() {
+	if c, err := loadDefault(); err == nil {
+		c.closeConns()
+	}
+}
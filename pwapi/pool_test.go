@@ -0,0 +1,299 @@
+package pwapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeBackend aceita conexões em 127.0.0.1 e executa handle para cada uma, retornando o endereço do listener
+func fakeBackend(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var conns []net.Conn
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	t.Cleanup(func() {
+		l.Close()
+		mu.Lock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	})
+	return l.Addr().String()
+}
+
+// echoBackend responde cada frame com um frame de opcode opQueryOnlineRe, contando as requisições recebidas
+//
+// Observações:
+//
+//	Com closeAfter maior que 0, a requisição número closeAfter é recebida e a conexão é fechada sem resposta
+func echoBackend(t *testing.T, requests *atomic.Int32, closeAfter int32) string {
+	return fakeBackend(t, func(conn net.Conn) {
+		for {
+			frame, err := ReadFrame(conn)
+			if err != nil {
+				return
+			}
+			if n := requests.Add(1); n == closeAfter {
+				return
+			}
+			conn.Write(EncodeFrame(opQueryOnlineRe, frame.Payload))
+		}
+	})
+}
+
+// brokenConn falha as escritas depois que broken é marcado, como um socket que o serviço fechou
+type brokenConn struct {
+	net.Conn
+	broken *atomic.Bool
+}
+
+func (c brokenConn) Write(b []byte) (int, error) {
+	if c.broken.Load() {
+		return 0, &net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE}
+	}
+	return c.Conn.Write(b)
+}
+
+func checkStats(t *testing.T, p *Pool, want PoolStats) {
+	t.Helper()
+	want.Addr = p.addr
+	if got := p.Stats(); got != want {
+		t.Errorf("Stats = %+v, esperado %+v", got, want)
+	}
+}
+
+func TestPoolReuseAndLimits(t *testing.T) {
+	var requests atomic.Int32
+	p := NewPool(echoBackend(t, &requests, 0), 1, time.Minute)
+	defer p.Close()
+	ctx := context.Background()
+
+	c1, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, p, PoolStats{Dials: 2, InUse: 2})
+
+	// Com o pool cheio a segunda conexão devolvida é fechada
+	p.Put(c1, true)
+	p.Put(c2, true)
+	checkStats(t, p, PoolStats{Dials: 2, Discarded: 1, Idle: 1})
+
+	c3, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c3 != c1 || !c3.reused {
+		t.Error("Get não reaproveitou a conexão ociosa")
+	}
+	checkStats(t, p, PoolStats{Dials: 2, Reuses: 1, Discarded: 1, InUse: 1})
+
+	// Uma conexão devolvida com erro não volta ao pool
+	p.Put(c3, false)
+	checkStats(t, p, PoolStats{Dials: 2, Reuses: 1, Discarded: 2})
+
+	c4, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if _, err := p.Get(ctx); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Get após Close = %v, esperado net.ErrClosed", err)
+	}
+	p.Put(c4, true)
+	checkStats(t, p, PoolStats{Dials: 3, Reuses: 1, Discarded: 3})
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	var requests atomic.Int32
+	p := NewPool(echoBackend(t, &requests, 0), 4, 50*time.Millisecond)
+	defer p.Close()
+
+	c, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(c, true)
+	time.Sleep(100 * time.Millisecond)
+
+	c2, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(c2, true)
+	if c2 == c || c2.reused {
+		t.Error("Get reaproveitou uma conexão ociosa há mais de TempoOcioso")
+	}
+	checkStats(t, p, PoolStats{Dials: 2, Discarded: 1, InUse: 1})
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	// O serviço fecha cada conexão logo após aceitá-la, como um gdeliveryd reiniciado
+	var accepted atomic.Int32
+	addr := fakeBackend(t, func(conn net.Conn) {
+		accepted.Add(1)
+	})
+	p := NewPool(addr, 4, time.Minute)
+	defer p.Close()
+
+	c, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(c, true)
+	for accepted.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	c2, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(c2, false)
+	if c2 == c {
+		t.Error("Get reaproveitou uma conexão fechada pelo serviço")
+	}
+	checkStats(t, p, PoolStats{Dials: 2, Discarded: 1, InUse: 1})
+}
+
+func TestPoolReconnectOnlyBeforeWrite(t *testing.T) {
+	var requests atomic.Int32
+	addr := echoBackend(t, &requests, 0)
+	_, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	c, err := NewClient(WithPort("gdeliveryd", p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var broken atomic.Bool
+	pool := c.poolFor(addr)
+	pool.dial = func(ctx context.Context, addr string) (net.Conn, error) {
+		conn, err := dialContext(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		// Apenas a primeira conexão quebra
+		var flag *atomic.Bool
+		if pool.Stats().Dials == 0 {
+			flag = &broken
+		} else {
+			flag = &atomic.Bool{}
+		}
+		return brokenConn{Conn: conn, broken: flag}, nil
+	}
+	req := EncodeFrame(opQueryOnline, []byte{0, 0, 0, 1})
+	ctx := context.Background()
+
+	if _, err := c.SendToDelivery(ctx, req, opQueryOnlineRe, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// A escrita na conexão reaproveitada falha e o pacote é enviado uma única vez em uma conexão nova
+	broken.Store(true)
+	if _, err := c.SendToDelivery(ctx, req, opQueryOnlineRe, false); err != nil {
+		t.Fatalf("SendToDelivery após a escrita falhar: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requisições recebidas = %d, esperado 2", n)
+	}
+	checkStats(t, pool, PoolStats{Dials: 2, Reuses: 1, Discarded: 1, Idle: 1})
+
+	if stats := c.PoolStats(); len(stats) != 1 || stats[0] != pool.Stats() {
+		t.Errorf("Client.PoolStats = %+v", stats)
+	}
+}
+
+func TestPoolNoReconnectAfterWrite(t *testing.T) {
+	// O serviço recebe a segunda requisição e fecha a conexão sem responder
+	var requests atomic.Int32
+	addr := echoBackend(t, &requests, 2)
+	_, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	c, err := NewClient(WithPort("gdeliveryd", p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	req := EncodeFrame(opQueryOnline, []byte{0, 0, 0, 1})
+	ctx := context.Background()
+
+	if _, err := c.SendToDelivery(ctx, req, opQueryOnlineRe, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SendToDelivery(ctx, req, opQueryOnlineRe, false); !isBrokenConn(err) {
+		t.Errorf("SendToDelivery sem resposta = %v, esperado o erro da conexão fechada", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requisições recebidas = %d, esperado 2: a requisição sem resposta não pode ser repetida", n)
+	}
+	checkStats(t, c.poolFor(addr), PoolStats{Dials: 1, Reuses: 1, Discarded: 1})
+}
+
+func TestClosePools(t *testing.T) {
+	var requests atomic.Int32
+	addr := echoBackend(t, &requests, 0)
+	_, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	c, err := NewClient(WithPort("gdeliveryd", p))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := Default()
+	SetDefault(c)
+	defer SetDefault(previous)
+
+	if _, err := SendToDelivery(EncodeFrame(opQueryOnline, []byte{0, 0, 0, 1}), opQueryOnlineRe, false); err != nil {
+		t.Fatal(err)
+	}
+	if stats := GetPoolStats(); len(stats) != 1 || stats[0].Addr != addr || stats[0].Idle != 1 {
+		t.Errorf("GetPoolStats = %+v, esperado uma conexão ociosa em %s", stats, addr)
+	}
+
+	ClosePools()
+	if stats := GetPoolStats(); len(stats) != 0 {
+		t.Errorf("GetPoolStats após ClosePools = %+v", stats)
+	}
+}
@@ -0,0 +1,382 @@
+package pwapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ProfileAuto detecta o perfil de versão pelo byte de versão de cada resposta, veja WithProfile
+const ProfileAuto = "auto"
+
+// ErrUnknownVersion indica um perfil de versão não cadastrado ou um pacote com byte de versão desconhecido
+var ErrUnknownVersion = errors.New("versão do servidor desconhecida")
+
+// Profile descreve o layout de RoleBase e RoleStatus de uma versão do servidor
+//
+// Observações:
+//
+//	Os perfis conhecidos são declarados em protocol.yaml, os structs Go possuem todos os campos e os campos
+//	ausentes na versão permanecem com o valor zero
+type Profile struct {
+	Name     string              // versão do servidor, ex: 1.3.6
+	Versions map[string]byte     // byte de versão (primeiro campo) de cada struct com layout próprio
+	Omit     map[string][]string // campos de cada struct que não existem no pacote desta versão
+}
+
+// VersionError indica que o byte de versão de um pacote não corresponde ao perfil configurado ou a nenhum perfil
+type VersionError struct {
+	Struct  string // RoleBase ou RoleStatus
+	Version byte   // byte de versão recebido
+	Profile string // perfil configurado, vazio na detecção automática
+}
+
+func (e *VersionError) Error() string {
+	if e.Profile != "" {
+		return fmt.Sprintf("pwapi: %s versão %d não corresponde ao perfil %s: %v", e.Struct, e.Version, e.Profile, ErrUnknownVersion)
+	}
+	return fmt.Sprintf("pwapi: %s versão %d não corresponde a nenhum perfil (%s): %v", e.Struct, e.Version,
+		strings.Join(profileNames(), ", "), ErrUnknownVersion)
+}
+
+func (e *VersionError) Unwrap() error {
+	return ErrUnknownVersion
+}
+
+// Profiles retorna os perfis de versão conhecidos
+func Profiles() []Profile {
+	return append([]Profile(nil), profiles...)
+}
+
+// LookupProfile retorna o perfil de versão pelo nome, ex: 1.3.6
+func LookupProfile(name string) (Profile, error) {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("%w: %q, utilize %s ou %s", ErrUnknownVersion, name, strings.Join(profileNames(), ", "), ProfileAuto)
+}
+
+func profileNames() []string {
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// MarshalProfile empacota v com o layout do perfil, veja Marshal
+//
+// Observações:
+//
+//	Structs sem layout no perfil são empacotados com todos os campos, como em Marshal
+//	Structs que contêm RoleBase ou RoleStatus, como GRoleData, empacotam esses campos com o layout do perfil
+func MarshalProfile(v interface{}, profile Profile) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() || rv.Kind() != reflect.Struct {
+		return nil, &CodecError{Op: "marshal", Field: fmt.Sprintf("%T", v), Err: ErrUnsupportedKind}
+	}
+	if !profileCovers(rv.Type(), profile) {
+		return Marshal(v)
+	}
+
+	p, err := profilePlan(rv.Type(), profile)
+	if err != nil {
+		return nil, withOp(err, "marshal")
+	}
+	e := &encoder{}
+	if err := e.encodeStruct(p, rv); err != nil {
+		return nil, prefixField(err, p.name)
+	}
+	return e.buf, nil
+}
+
+// UnmarshalProfile desempacota data em v com o layout do perfil, veja Unmarshal
+//
+// Observações:
+//
+//	O byte de versão do pacote não é verificado, veja WithProfile para a verificação e a detecção automática
+func UnmarshalProfile(data []byte, v interface{}, profile Profile) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return data, &CodecError{Op: "unmarshal", Field: fmt.Sprintf("%T", v), Err: ErrUnsupportedKind}
+	}
+	if !profileCovers(rv.Elem().Type(), profile) {
+		return Unmarshal(data, v)
+	}
+
+	p, err := profilePlan(rv.Elem().Type(), profile)
+	if err != nil {
+		return data, withOp(err, "unmarshal")
+	}
+	d := &decoder{data: data}
+	if err := d.decodeStruct(p, rv.Elem()); err != nil {
+		return data[d.off:], prefixField(err, p.name)
+	}
+	return data[d.off:], nil
+}
+
+type profilePlanKey struct {
+	t       reflect.Type
+	profile string
+}
+
+var (
+	profilePlans = map[profilePlanKey]*structPlan{}
+
+	// profilePlanReady é lido sem planMu, veja planReady
+	profilePlanReady sync.Map // profilePlanKey → *structPlan
+)
+
+// profileCovers indica se o perfil define o layout de t ou de algum struct contido diretamente em t
+func profileCovers(t reflect.Type, profile Profile) bool {
+	if _, ok := profile.Versions[t.Name()]; ok {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.IsExported() && f.Type.Kind() == reflect.Struct && profileCovers(f.Type, profile) {
+			return true
+		}
+	}
+	return false
+}
+
+// profilePlan retorna o plano de t sem os campos omitidos pelo perfil
+//
+// Observações:
+//
+//	Os campos struct cobertos pelo perfil (RoleBase dentro de GRoleData, por exemplo) utilizam o plano do perfil
+func profilePlan(t reflect.Type, profile Profile) (*structPlan, error) {
+	key := profilePlanKey{t, profile.Name}
+	if p, ok := profilePlanReady.Load(key); ok {
+		return p.(*structPlan), nil
+	}
+
+	planMu.Lock()
+	defer planMu.Unlock()
+	p, err := buildProfilePlan(t, profile)
+	if err == nil {
+		profilePlanReady.Store(key, p)
+	}
+	return p, err
+}
+
+// buildProfilePlan deve ser chamado com planMu bloqueado
+func buildProfilePlan(t reflect.Type, profile Profile) (*structPlan, error) {
+	key := profilePlanKey{t, profile.Name}
+	if p, ok := profilePlans[key]; ok {
+		return p, nil
+	}
+
+	full, err := buildPlan(t)
+	if err != nil {
+		return nil, err
+	}
+
+	omit := map[string]bool{}
+	for _, name := range profile.Omit[t.Name()] {
+		omit[name] = true
+	}
+	p := &structPlan{name: full.name}
+	found := 0
+	for _, f := range full.fields {
+		if omit[f.name] {
+			found++
+		}
+		// Uma lista e o campo com a sua quantidade de elementos precisam ser omitidos juntos
+		if f.lenFrom >= 0 && omit[f.name] != omit[t.Field(f.lenFrom).Name] {
+			return nil, &CodecError{Field: t.Name() + "." + f.name,
+				Err: fmt.Errorf("%w: o perfil %s precisa omitir %s junto com %s", ErrBadTag, profile.Name, f.name, t.Field(f.lenFrom).Name)}
+		}
+		if omit[f.name] {
+			continue
+		}
+		if ft := t.Field(f.index).Type; f.codec.wire == wireStruct && profileCovers(ft, profile) {
+			nested, err := buildProfilePlan(ft, profile)
+			if err != nil {
+				return nil, err
+			}
+			f.codec = &fieldCodec{wire: wireStruct, plan: nested}
+		}
+		p.fields = append(p.fields, f)
+	}
+	if found != len(omit) {
+		return nil, &CodecError{Field: t.Name(),
+			Err: fmt.Errorf("%w: o perfil %s omite campos inexistentes %v", ErrBadTag, profile.Name, profile.Omit[t.Name()])}
+	}
+
+	profilePlans[key] = p
+	return p, nil
+}
+
+// detectProfile escolhe o perfil de um pacote pelo byte de versão
+//
+// Observações:
+//
+//	Quando vários perfis usam o mesmo byte de versão, é escolhido o primeiro cujo layout consome o pacote inteiro
+func detectProfile(name string, data []byte, v interface{}) (Profile, error) {
+	if len(data) == 0 {
+		return Profile{}, &CodecError{Op: "unmarshal", Field: name, Err: ErrShortBuffer}
+	}
+
+	var candidates []Profile
+	for _, p := range profiles {
+		if version, ok := p.Versions[name]; ok && version == data[0] {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	for _, p := range candidates {
+		probe := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+		if rest, err := UnmarshalProfile(data, probe, p); err == nil && len(rest) == 0 {
+			return p, nil
+		}
+	}
+	return Profile{}, &VersionError{Struct: name, Version: data[0]}
+}
+
+// unmarshal desempacota uma resposta com o layout do perfil de versão do cliente
+func (c *Client) unmarshal(data []byte, v interface{}) error {
+	t := reflect.TypeOf(v).Elem()
+	if base, ok := prefixes[t]; ok {
+		return c.unmarshalPrefix(base.Name(), data, v)
+	}
+
+	name := t.Name()
+	if c.profile == "" || !profiled(t) {
+		_, err := Unmarshal(data, v)
+		return err
+	}
+	if !hasProfiles(name) {
+		return c.unmarshalComposite(data, v)
+	}
+
+	var profile Profile
+	var err error
+	if c.profile == ProfileAuto {
+		if profile, err = detectProfile(name, data, v); err != nil {
+			return err
+		}
+		c.debugf("%s versão %d decodificado com o perfil %s", name, data[0], profile.Name)
+	} else {
+		if profile, err = LookupProfile(c.profile); err != nil {
+			return err
+		}
+		if version, ok := profile.Versions[name]; ok && len(data) > 0 && data[0] != version {
+			return &VersionError{Struct: name, Version: data[0], Profile: profile.Name}
+		}
+	}
+
+	_, err = UnmarshalProfile(data, v, profile)
+	return err
+}
+
+// unmarshalPrefix desempacota o prefixo de uma resposta, verificando apenas o byte de versão
+//
+// Observações:
+//
+//	Os campos de um prefixo existem em todas as versões (pwgen rejeita perfis que os omitem), portanto o
+//	layout é o mesmo para qualquer perfil de base
+func (c *Client) unmarshalPrefix(base string, data []byte, v interface{}) error {
+	if c.profile != "" && hasProfiles(base) && len(data) > 0 {
+		known := false
+		for _, p := range profiles {
+			if version, ok := p.Versions[base]; ok && version == data[0] && (c.profile == ProfileAuto || c.profile == p.Name) {
+				known = true
+			}
+		}
+		if !known {
+			err := &VersionError{Struct: base, Version: data[0]}
+			if c.profile != ProfileAuto {
+				err.Profile = c.profile
+			}
+			return err
+		}
+	}
+
+	_, err := Unmarshal(data, v)
+	return err
+}
+
+// unmarshalComposite desempacota um struct que contém RoleBase ou RoleStatus, como GRoleData
+//
+// Observações:
+//
+//	Os bytes de versão ficam no meio do pacote, portanto são verificados após a leitura
+//	Na detecção automática é escolhido o primeiro perfil que consome o pacote inteiro com os bytes de versão do perfil
+func (c *Client) unmarshalComposite(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v).Elem()
+	if c.profile != ProfileAuto {
+		profile, err := LookupProfile(c.profile)
+		if err != nil {
+			return err
+		}
+		if _, err := UnmarshalProfile(data, v, profile); err != nil {
+			// Um layout de outra versão costuma falhar adiante no pacote, o byte de versão já lido explica a falha
+			var verr *VersionError
+			if errors.As(checkVersions(rv, profile), &verr) && verr.Version != 0 {
+				return verr
+			}
+			return err
+		}
+		return checkVersions(rv, profile)
+	}
+
+	for _, p := range profiles {
+		probe := reflect.New(rv.Type())
+		if rest, err := UnmarshalProfile(data, probe.Interface(), p); err == nil && len(rest) == 0 && checkVersions(probe.Elem(), p) == nil {
+			rv.Set(probe.Elem())
+			c.debugf("%s decodificado com o perfil %s", rv.Type().Name(), p.Name)
+			return nil
+		}
+	}
+	err := &VersionError{Struct: rv.Type().Name()}
+	if len(data) > 0 {
+		err.Version = data[0]
+	}
+	return err
+}
+
+// checkVersions verifica o byte de versão dos structs com layout no perfil contidos em v
+func checkVersions(v reflect.Value, profile Profile) error {
+	t := v.Type()
+	if version, ok := profile.Versions[t.Name()]; ok {
+		if got := byte(v.Field(0).Uint()); got != version {
+			return &VersionError{Struct: t.Name(), Version: got, Profile: profile.Name}
+		}
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.IsExported() && f.Type.Kind() == reflect.Struct && profileCovers(f.Type, profile) {
+			if err := checkVersions(v.Field(i), profile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// profiled indica se algum perfil define o layout de t ou de um struct contido em t
+func profiled(t reflect.Type) bool {
+	for _, p := range profiles {
+		if profileCovers(t, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasProfiles indica se algum perfil define o layout do struct
+func hasProfiles(name string) bool {
+	for _, p := range profiles {
+		if _, ok := p.Versions[name]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package pwapi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProfileLayouts(t *testing.T) {
+	status := RoleStatus{Sversion: 1, Level: 105, Level2: 22, Charactermode: []byte{1}, Coolingtime: []byte{2, 3},
+		Property: []byte{4}, Reserved4: -1}
+	full, _ := Marshal(status)
+
+	for _, p := range Profiles() {
+		data, err := MarshalProfile(status, p)
+		if err != nil {
+			t.Fatalf("%s: MarshalProfile: %v", p.Name, err)
+		}
+
+		var got RoleStatus
+		rest, err := UnmarshalProfile(data, &got, p)
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("%s: UnmarshalProfile: %v, %d bytes restantes", p.Name, err, len(rest))
+		}
+
+		// Os campos omitidos não são gravados e permanecem com o valor zero
+		want := status
+		for _, name := range p.Omit["RoleStatus"] {
+			reflect.ValueOf(&want).Elem().FieldByName(name).SetZero()
+			if !reflect.ValueOf(got).FieldByName(name).IsZero() {
+				t.Errorf("%s: campo omitido %s = %v", p.Name, name, reflect.ValueOf(got).FieldByName(name))
+			}
+		}
+		gotData, _ := Marshal(got)
+		wantData, _ := Marshal(want)
+		if string(gotData) != string(wantData) {
+			t.Errorf("%s: RoleStatus = %+v\n              esperado %+v", p.Name, got, want)
+		}
+		if len(p.Omit["RoleStatus"]) == 0 && string(data) != string(full) {
+			t.Errorf("%s: perfil sem campos omitidos difere de Marshal", p.Name)
+		}
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	if _, err := LookupProfile("1.2"); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("LookupProfile(1.2) = %v, esperado ErrUnknownVersion", err)
+	}
+
+	for _, omit := range [][]string{{"Forbid"}, {"ForbidSize"}, {"Inexistente"}} {
+		p := Profile{Name: "teste", Versions: map[string]byte{"RoleBase": 1}, Omit: map[string][]string{"RoleBase": omit}}
+		if _, err := MarshalProfile(RoleBase{}, p); !errors.Is(err, ErrBadTag) {
+			t.Errorf("omit %v: MarshalProfile = %v, esperado ErrBadTag", omit, err)
+		}
+	}
+
+	var status RoleStatus
+	if _, err := detectProfile("RoleStatus", []byte{7, 0, 0}, &status); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("detectProfile versão 7 = %v, esperado ErrUnknownVersion", err)
+	}
+}
+
+func TestCachedPlansSkipLock(t *testing.T) {
+	profile, err := LookupProfile("1.5.x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(RoleStatus{Sversion: 1, Level: 105})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v RoleStatus
+	if _, err := UnmarshalProfile(data, &v, profile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Com os planos em cache a leitura não depende de planMu, que fica bloqueado durante a construção de outro plano
+	planMu.Lock()
+	defer planMu.Unlock()
+	done := make(chan error, 1)
+	go func() {
+		var v RoleStatus
+		_, err := UnmarshalProfile(data, &v, profile)
+		if err == nil {
+			_, err = Unmarshal(data, &v)
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("UnmarshalProfile aguardou planMu com o plano em cache")
+	}
+}
+
+func TestDefaultUnknownProfile(t *testing.T) {
+	previous, previousConfig := Default(), AppConfig
+	defer func() {
+		AppConfig = previousConfig
+		SetDefault(previous)
+	}()
+
+	// O erro de NewClient chega às funções do pacote em vez de um cliente nil
+	AppConfig.VersaoServidor = "1.2.3"
+	SetDefault(nil)
+	if c, err := Default(), DefaultErr(); c != nil || !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("Default com VersaoServidor desconhecida = %v, %v; esperado nil e ErrUnknownVersion", c, err)
+	}
+	if _, err := GetRoleBase(RoleID{RoleID: 1024}); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("GetRoleBase = %v, esperado ErrUnknownVersion", err)
+	}
+	if err := SendMail(RoleID{RoleID: 1024}, "título", "texto", Item{}, 1); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("SendMail = %v, esperado ErrUnknownVersion", err)
+	}
+	if stats := GetPoolStats(); stats != nil {
+		t.Errorf("GetPoolStats = %+v", stats)
+	}
+	ClosePools()
+
+	// Corrigida a configuração, SetDefault(nil) recria o cliente
+	AppConfig.VersaoServidor = ""
+	SetDefault(nil)
+	if c, err := Default(), DefaultErr(); c == nil || err != nil {
+		t.Errorf("Default após corrigir VersaoServidor = %v, %v", c, err)
+	}
+}
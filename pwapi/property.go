@@ -0,0 +1,156 @@
+package pwapi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// RolePropertyBaseSize é o tamanho em bytes dos atributos presentes em todas as versões, até MaxAP
+const RolePropertyBaseSize = 148
+
+// RoleProperty são os atributos do personagem gravados em RoleStatus.Property
+//
+// Observações:
+//
+//	Diferente dos pacotes, o Property é uma cópia da memória do gamed e os valores são little-endian
+//	Os campos após MaxAP existem apenas nas versões que os gravam, em blobs menores permanecem com o valor zero
+//	Os cinco elementos de AddonDamageLow, AddonDamageHigh e Resistance são metal, madeira, água, fogo e terra
+type RoleProperty struct {
+	Vitality int // constituição
+	Energy   int // inteligência
+	Strength int // força
+	Agility  int // destreza
+
+	MaxHP int
+	MaxMP int
+	HPGen int
+	MPGen int
+
+	WalkSpeed   float32
+	RunSpeed    float32
+	SwimSpeed   float32
+	FlightSpeed float32
+
+	Attack      int // precisão
+	DamageLow   int // ataque físico mínimo
+	DamageHigh  int // ataque físico máximo
+	AttackSpeed int
+	AttackRange float32
+
+	AddonDamageLow  [5]int // ataque elemental mínimo
+	AddonDamageHigh [5]int // ataque elemental máximo
+	DamageMagicLow  int    // ataque mágico mínimo
+	DamageMagicHigh int    // ataque mágico máximo
+	Resistance      [5]int // defesa elemental
+
+	Defense int // defesa física
+	Armor   int // esquiva
+	MaxAP   int // chi máximo
+
+	AttackDegree int // nível de ataque
+	DefendDegree int // nível de defesa
+
+	CritRate            int
+	CritDamageBonus     int
+	InvisibleDegree     int
+	AntiInvisibleDegree int
+	Penetration         int
+	Resilience          int
+	Vigour              int // espírito
+
+	Size int // bytes do Property lidos, indica quais campos opcionais estavam presentes
+}
+
+// DecodeRoleProperty decodifica o Property de um RoleStatus
+//
+// Parâmetros:
+//
+//	data: []byte - Conteúdo de RoleStatus.Property
+//
+// Retorno:
+//
+//	RoleProperty - Atributos do personagem
+//	error - Retorna um *CodecError caso data não possua os RolePropertyBaseSize bytes presentes em todas as versões
+func DecodeRoleProperty(data []byte) (RoleProperty, error) {
+	var p RoleProperty
+	if len(data) < RolePropertyBaseSize {
+		return p, &CodecError{Op: "unmarshal", Field: "RoleProperty",
+			Err: fmt.Errorf("%w: precisa de %d bytes, restam %d", ErrShortBuffer, RolePropertyBaseSize, len(data))}
+	}
+
+	r := propertyReader{data: data}
+	for _, f := range p.fields() {
+		switch v := f.(type) {
+		case *int:
+			*v = int(int32(r.next()))
+		case *float32:
+			*v = math.Float32frombits(r.next())
+		}
+	}
+	p.Size = min(r.off, len(data))
+	return p, nil
+}
+
+// EncodeRoleProperty grava os atributos no formato de RoleStatus.Property, veja DecodeRoleProperty
+//
+// Observações:
+//
+//	São gravados todos os campos, inclusive os opcionais, e Size é ignorado
+func EncodeRoleProperty(p RoleProperty) []byte {
+	var b []byte
+	for _, f := range p.fields() {
+		switch v := f.(type) {
+		case *int:
+			b = binary.LittleEndian.AppendUint32(b, uint32(*v))
+		case *float32:
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(*v))
+		}
+	}
+	return b
+}
+
+// DecodeProperty decodifica o Property do status, veja DecodeRoleProperty
+func (s RoleStatus) DecodeProperty() (RoleProperty, error) {
+	return DecodeRoleProperty(s.Property)
+}
+
+// fields retorna os campos na ordem do Property, cada um com 4 bytes
+func (p *RoleProperty) fields() []interface{} {
+	f := []interface{}{
+		&p.Vitality, &p.Energy, &p.Strength, &p.Agility,
+		&p.MaxHP, &p.MaxMP, &p.HPGen, &p.MPGen,
+		&p.WalkSpeed, &p.RunSpeed, &p.SwimSpeed, &p.FlightSpeed,
+		&p.Attack, &p.DamageLow, &p.DamageHigh, &p.AttackSpeed, &p.AttackRange,
+	}
+	for i := range p.AddonDamageLow {
+		f = append(f, &p.AddonDamageLow[i])
+	}
+	for i := range p.AddonDamageHigh {
+		f = append(f, &p.AddonDamageHigh[i])
+	}
+	f = append(f, &p.DamageMagicLow, &p.DamageMagicHigh)
+	for i := range p.Resistance {
+		f = append(f, &p.Resistance[i])
+	}
+	return append(f,
+		&p.Defense, &p.Armor, &p.MaxAP,
+		&p.AttackDegree, &p.DefendDegree,
+		&p.CritRate, &p.CritDamageBonus, &p.InvisibleDegree, &p.AntiInvisibleDegree, &p.Penetration, &p.Resilience, &p.Vigour,
+	)
+}
+
+// propertyReader lê valores little-endian de 4 bytes, retornando zero após o fim dos dados
+type propertyReader struct {
+	data []byte
+	off  int
+}
+
+func (r *propertyReader) next() uint32 {
+	var v uint32
+	if r.off+4 <= len(r.data) {
+		v = binary.LittleEndian.Uint32(r.data[r.off:])
+	}
+	r.off += 4
+	return v
+}
@@ -0,0 +1,46 @@
+package pwapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRolePropertyRoundTrip(t *testing.T) {
+	want := RoleProperty{
+		Vitality: 5, Energy: 5, Strength: 300, Agility: 40,
+		MaxHP: 12345, MaxMP: 2300, HPGen: 10, MPGen: 8,
+		WalkSpeed: 3, RunSpeed: 5.5, SwimSpeed: 3.2, FlightSpeed: 10,
+		Attack: 900, DamageLow: 1500, DamageHigh: 2100, AttackSpeed: 20, AttackRange: 2.5,
+		AddonDamageLow: [5]int{1, 2, 3, 4, 5}, AddonDamageHigh: [5]int{6, 7, 8, 9, 10},
+		DamageMagicLow: 11, DamageMagicHigh: 12,
+		Resistance: [5]int{400, 410, 420, 430, 440},
+		Defense:    5000, Armor: 800, MaxAP: 400,
+		AttackDegree: 12, DefendDegree: -3,
+		CritRate: 5, CritDamageBonus: 200, Penetration: 30, Resilience: 40, Vigour: 150,
+	}
+
+	data := EncodeRoleProperty(want)
+	got, err := DecodeRoleProperty(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.Size = len(data)
+	if got != want {
+		t.Errorf("DecodeRoleProperty = %+v, esperado %+v", got, want)
+	}
+
+	// Versões antigas gravam apenas até MaxAP, os campos opcionais ficam zerados
+	got, err = RoleStatus{Property: data[:RolePropertyBaseSize]}.DecodeProperty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Size != RolePropertyBaseSize || got.MaxAP != 400 || got.AttackDegree != 0 || got.Vigour != 0 {
+		t.Errorf("Property base = %+v", got)
+	}
+
+	_, err = DecodeRoleProperty(data[:RolePropertyBaseSize-1])
+	var codec *CodecError
+	if !errors.As(err, &codec) || !errors.Is(err, ErrShortBuffer) {
+		t.Errorf("Property curto: err = %v, esperado *CodecError com ErrShortBuffer", err)
+	}
+}
@@ -0,0 +1,154 @@
+package pwapi
+
+//go:generate go run ../cmd/pwgen -in protocol.yaml -out protocol_gen.go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrUnknownProtocol indica que o tipo da requisição não foi registrado com RegisterProtocol
+var ErrUnknownProtocol = errors.New("protocolo não registrado")
+
+// Protocol descreve uma chamada do protocolo: o struct da requisição, o serviço que a recebe e a resposta esperada
+type Protocol struct {
+	Name       string       // nome da requisição, ex: GetRoleBaseArg
+	Opcode     uint32       // opcode da requisição
+	Backend    string       // gamedbd, gdeliveryd ou provider
+	Request    reflect.Type // struct da requisição
+	Response   reflect.Type // struct da resposta, nil quando o serviço não responde
+	RespName   string       // nome da resposta, utilizado pelo decode
+	RespOpcode uint32       // opcode da resposta, igual a Opcode nas RPCs
+
+	// RPC indica uma RPC do gamedbd: a requisição começa pelo Handler (xid) e a resposta pelo xid e o retcode
+	RPC bool
+}
+
+var (
+	protocolsMu sync.RWMutex
+	protocols   = map[reflect.Type]Protocol{}
+)
+
+// RegisterProtocol registra uma chamada do protocolo e os seus pacotes no registro do decode
+//
+// Parâmetros:
+//
+//	p: Protocol - Chamada a ser registrada, substitui o registro anterior do mesmo tipo de requisição
+//
+// Observações:
+//
+//	Após o registro, a chamada pode ser feita com Call (quando há resposta) ou Send (quando não há)
+func RegisterProtocol(p Protocol) {
+	protocolsMu.Lock()
+	protocols[p.Request] = p
+	protocolsMu.Unlock()
+
+	RegisterPacket(PacketType{Name: p.Name, Opcode: p.Opcode, Dir: CaptureRequest, Backend: p.Backend, Type: p.Request})
+	if p.Response != nil {
+		RegisterPacket(PacketType{Name: p.RespName, Opcode: p.RespOpcode, Dir: CaptureResponse, Backend: p.Backend,
+			RPC: p.RPC, Type: p.Response})
+	}
+}
+
+// ProtocolFor retorna a chamada registrada para o tipo de requisição
+func ProtocolFor(request reflect.Type) (Protocol, bool) {
+	protocolsMu.RLock()
+	defer protocolsMu.RUnlock()
+	p, ok := protocols[request]
+	return p, ok
+}
+
+// protocolOf retorna a chamada registrada para Req, verificando o tipo da resposta quando resp não é nil
+func protocolOf[Req any](resp reflect.Type) (Protocol, error) {
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	p, ok := ProtocolFor(reqType)
+	if !ok {
+		return p, fmt.Errorf("%w: %s", ErrUnknownProtocol, reqType)
+	}
+	// Structs declarados com prefix em protocol.yaml leem apenas o início da resposta
+	if base, ok := prefixes[resp]; p.Response != resp && (!ok || base != p.Response) {
+		return p, fmt.Errorf("%w: %s responde %v, não %v", ErrUnknownProtocol, p.Name, p.Response, resp)
+	}
+	return p, nil
+}
+
+// Call envia uma requisição registrada e desempacota a resposta
+//
+// Parâmetros:
+//
+//	ctx: context.Context - Contexto da chamada
+//	c: *Client - Cliente do servidor
+//	req: Req - Requisição, seu tipo define o opcode e o serviço a partir do registro
+//
+// Retorno:
+//
+//	Resp - Resposta desempacotada
+//	error - ErrUnknownProtocol, erro de comunicação, *RetCodeError quando a RPC retorna código diferente de zero
+//	*VersionError quando a versão da resposta não corresponde ao perfil do cliente ou *CodecError quando a
+//	resposta está malformada
+//
+// Observações:
+//
+//	Exemplo: base, err := Call[GetRoleBaseArg, RoleBase](ctx, client, GetRoleBaseArg{Handler: -1, RoleID: id})
+//	Resp pode ser um prefixo da resposta (ex: RoleStatusLevel no lugar de RoleStatus), os bytes restantes são ignorados
+func Call[Req, Resp any](ctx context.Context, c *Client, req Req) (Resp, error) {
+	var resp Resp
+	p, err := protocolOf[Req](reflect.TypeOf(resp))
+	if err != nil {
+		return resp, err
+	}
+
+	pack, err := Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	var data []byte
+	if p.RPC {
+		// A RPC é enviada pela conexão multiplexada, que preenche o Handler com um xid único
+		response, err := c.callRPC(ctx, p.Backend, p.Opcode, pack)
+		if err != nil {
+			return resp, fmt.Errorf("erro ao enviar para o %s: %w", p.Backend, err)
+		}
+		if response.RetCode != 0 {
+			return resp, &RetCodeError{Opcode: p.Opcode, RetCode: response.RetCode}
+		}
+		data = response.Data
+	} else {
+		response, err := c.sendToBackend(ctx, p.Backend, EncodeFrame(p.Opcode, pack), p.RespOpcode, false)
+		if err != nil {
+			return resp, fmt.Errorf("erro ao enviar para o %s: %w", p.Backend, err)
+		}
+		frame, _, err := ParseFrame(response)
+		if err != nil {
+			return resp, err
+		}
+		data = frame.Payload
+	}
+
+	// RoleBase e RoleStatus são decodificados com o layout do perfil de versão do cliente
+	if err := c.unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// Send envia uma requisição registrada sem resposta, veja Call
+func Send[Req any](ctx context.Context, c *Client, req Req) error {
+	p, err := protocolOf[Req](nil)
+	if err != nil {
+		return err
+	}
+
+	pack, err := Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := c.sendToBackend(ctx, p.Backend, EncodeFrame(p.Opcode, pack), 0, true); err != nil {
+		return fmt.Errorf("erro ao enviar para o %s: %w", p.Backend, err)
+	}
+	return nil
+}
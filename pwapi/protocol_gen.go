@@ -0,0 +1,2615 @@
+// Code generated by pwgen from protocol.yaml. DO NOT EDIT.
+
+package pwapi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Opcodes dos pacotes utilizados pelo pacote
+//
+// Observações:
+//
+//	Mais informações sobre cada opcode e o formato dos pacotes em: http://pwdev.ru/index.php/Protocols
+const (
+	OpChatBroadCast      uint32 = 0x78
+	OpGMQueryOnline      uint32 = 0x189
+	OpGMQueryOnlineRe    uint32 = 0x18A
+	OpGMListOnlineUser   uint32 = 0x160
+	OpGMListOnlineUserRe uint32 = 0x161
+	OpDebugAddCash       uint32 = 0x209
+	OpGetUser            uint32 = 0xBBA
+	OpGetRoleBase        uint32 = 0xBC5
+	OpGetRoleStatus      uint32 = 0xBC7
+	OpGetRoleID          uint32 = 0xBD9
+	OpGetUserRoles       uint32 = 0xD49
+	OpGetRole            uint32 = 0x1F43
+	OpSysSendMail        uint32 = 0x1076
+	OpSysSendMailRe      uint32 = 0x1077
+)
+
+type UserID int
+
+type RoleID struct {
+	RoleID int
+}
+
+// AppendPW acrescenta RoleID empacotado em b, implementando Packer
+func (v RoleID) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "RoleID")
+	}
+	return e.buf, nil
+}
+
+func (v *RoleID) encodePW(e *encoder) error {
+	e.uint32(uint32(v.RoleID))
+	return nil
+}
+
+// UnpackPW desempacota RoleID do início de data, implementando Unpacker
+func (v *RoleID) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "RoleID")
+	}
+	return d.off, nil
+}
+
+func (v *RoleID) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".RoleID")
+		}
+		v.RoleID = int(int32(x))
+	}
+	return nil
+}
+
+// Item é um item do jogo (GRoleInventory), anexado a e-mails e guardado no inventário, equipamento e armazém dos personagens
+type Item struct {
+	ID         int
+	Pos        int
+	Count      int
+	MaxCount   int
+	Data       []byte
+	ProcType   int
+	ExpireDate int
+	GUID1      int
+	GUID2      int
+	Mask       int
+}
+
+// AppendPW acrescenta Item empacotado em b, implementando Packer
+func (v Item) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "Item")
+	}
+	return e.buf, nil
+}
+
+func (v *Item) encodePW(e *encoder) error {
+	e.uint32(uint32(v.ID))
+	e.uint32(uint32(v.Pos))
+	e.uint32(uint32(v.Count))
+	e.uint32(uint32(v.MaxCount))
+	if err := e.octets(v.Data, 0); err != nil {
+		return prefixField(err, ".Data")
+	}
+	e.uint32(uint32(v.ProcType))
+	e.uint32(uint32(v.ExpireDate))
+	e.uint32(uint32(v.GUID1))
+	e.uint32(uint32(v.GUID2))
+	e.uint32(uint32(v.Mask))
+	return nil
+}
+
+// UnpackPW desempacota Item do início de data, implementando Unpacker
+func (v *Item) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "Item")
+	}
+	return d.off, nil
+}
+
+func (v *Item) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".ID")
+		}
+		v.ID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Pos")
+		}
+		v.Pos = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Count")
+		}
+		v.Count = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".MaxCount")
+		}
+		v.MaxCount = int(int32(x))
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Data")
+		}
+		v.Data = x
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".ProcType")
+		}
+		v.ProcType = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".ExpireDate")
+		}
+		v.ExpireDate = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".GUID1")
+		}
+		v.GUID1 = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".GUID2")
+		}
+		v.GUID2 = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Mask")
+		}
+		v.Mask = int(int32(x))
+	}
+	return nil
+}
+
+// UserOnline é um usuário online retornado por GMListOnlineUser_Re (GMPlayerInfo), com a conta e o nome do personagem
+type UserOnline struct {
+	UserID   UserID
+	RoleID   RoleID
+	LinkID   int
+	LocalSID int
+	GSID     int
+	Status   byte
+	Name     string `pw:"utf16"`
+}
+
+// AppendPW acrescenta UserOnline empacotado em b, implementando Packer
+func (v UserOnline) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "UserOnline")
+	}
+	return e.buf, nil
+}
+
+func (v *UserOnline) encodePW(e *encoder) error {
+	e.uint32(uint32(v.UserID))
+	if err := v.RoleID.encodePW(e); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	e.uint32(uint32(v.LinkID))
+	e.uint32(uint32(v.LocalSID))
+	e.uint32(uint32(v.GSID))
+	e.uint8(uint8(v.Status))
+	if err := e.text(v.Name, wireUTF16, 0); err != nil {
+		return prefixField(err, ".Name")
+	}
+	return nil
+}
+
+// UnpackPW desempacota UserOnline do início de data, implementando Unpacker
+func (v *UserOnline) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "UserOnline")
+	}
+	return d.off, nil
+}
+
+func (v *UserOnline) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".UserID")
+		}
+		v.UserID = UserID(int32(x))
+	}
+	if err := v.RoleID.decodePW(d); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".LinkID")
+		}
+		v.LinkID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".LocalSID")
+		}
+		v.LocalSID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".GSID")
+		}
+		v.GSID = int(int32(x))
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Status")
+		}
+		v.Status = byte(x)
+	}
+	{
+		x, err := d.text(wireUTF16, 0)
+		if err != nil {
+			return prefixField(err, ".Name")
+		}
+		v.Name = x
+	}
+	return nil
+}
+
+type RoleStatus struct {
+	Sversion         byte
+	Level            int
+	Level2           int
+	Exp              int
+	Sp               int
+	Pp               int
+	Hp               int
+	Mp               int
+	Posx             float32
+	Posy             float32
+	Posz             float32
+	Worldtag         int
+	InvaderState     int
+	InvaderTime      int
+	PariahTime       int
+	Reputation       int
+	CustomStatus     []byte
+	FilterData       []byte
+	Charactermode    []byte
+	Instancekeylist  []byte
+	DbltimeExpire    int
+	DbltimeMode      int
+	DbltimeBegin     int
+	DbltimeUsed      int
+	DbltimeMax       int
+	TimeUsed         int
+	DbltimeData      []byte
+	Storesize        uint16
+	Petcorral        []byte
+	Property         []byte
+	VarData          []byte
+	Skills           []byte
+	Storehousepasswd []byte
+	Waypointlist     []byte
+	Coolingtime      []byte
+	Reserved1        uint
+	Reserved2        int
+	Reserved3        int
+	Reserved4        int
+}
+
+// AppendPW acrescenta RoleStatus empacotado em b, implementando Packer
+func (v RoleStatus) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "RoleStatus")
+	}
+	return e.buf, nil
+}
+
+func (v *RoleStatus) encodePW(e *encoder) error {
+	e.uint8(uint8(v.Sversion))
+	e.uint32(uint32(v.Level))
+	e.uint32(uint32(v.Level2))
+	e.uint32(uint32(v.Exp))
+	e.uint32(uint32(v.Sp))
+	e.uint32(uint32(v.Pp))
+	e.uint32(uint32(v.Hp))
+	e.uint32(uint32(v.Mp))
+	e.float32(float32(v.Posx))
+	e.float32(float32(v.Posy))
+	e.float32(float32(v.Posz))
+	e.uint32(uint32(v.Worldtag))
+	e.uint32(uint32(v.InvaderState))
+	e.uint32(uint32(v.InvaderTime))
+	e.uint32(uint32(v.PariahTime))
+	e.uint32(uint32(v.Reputation))
+	if err := e.octets(v.CustomStatus, 0); err != nil {
+		return prefixField(err, ".CustomStatus")
+	}
+	if err := e.octets(v.FilterData, 0); err != nil {
+		return prefixField(err, ".FilterData")
+	}
+	if err := e.octets(v.Charactermode, 0); err != nil {
+		return prefixField(err, ".Charactermode")
+	}
+	if err := e.octets(v.Instancekeylist, 0); err != nil {
+		return prefixField(err, ".Instancekeylist")
+	}
+	e.uint32(uint32(v.DbltimeExpire))
+	e.uint32(uint32(v.DbltimeMode))
+	e.uint32(uint32(v.DbltimeBegin))
+	e.uint32(uint32(v.DbltimeUsed))
+	e.uint32(uint32(v.DbltimeMax))
+	e.uint32(uint32(v.TimeUsed))
+	if err := e.octets(v.DbltimeData, 0); err != nil {
+		return prefixField(err, ".DbltimeData")
+	}
+	e.uint16(uint16(v.Storesize))
+	if err := e.octets(v.Petcorral, 0); err != nil {
+		return prefixField(err, ".Petcorral")
+	}
+	if err := e.octets(v.Property, 0); err != nil {
+		return prefixField(err, ".Property")
+	}
+	if err := e.octets(v.VarData, 0); err != nil {
+		return prefixField(err, ".VarData")
+	}
+	if err := e.octets(v.Skills, 0); err != nil {
+		return prefixField(err, ".Skills")
+	}
+	if err := e.octets(v.Storehousepasswd, 0); err != nil {
+		return prefixField(err, ".Storehousepasswd")
+	}
+	if err := e.octets(v.Waypointlist, 0); err != nil {
+		return prefixField(err, ".Waypointlist")
+	}
+	if err := e.octets(v.Coolingtime, 0); err != nil {
+		return prefixField(err, ".Coolingtime")
+	}
+	e.uint32(uint32(v.Reserved1))
+	e.uint32(uint32(v.Reserved2))
+	e.uint32(uint32(v.Reserved3))
+	e.uint32(uint32(v.Reserved4))
+	return nil
+}
+
+// UnpackPW desempacota RoleStatus do início de data, implementando Unpacker
+func (v *RoleStatus) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "RoleStatus")
+	}
+	return d.off, nil
+}
+
+func (v *RoleStatus) decodePW(d *decoder) error {
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Sversion")
+		}
+		v.Sversion = byte(x)
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Level")
+		}
+		v.Level = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Level2")
+		}
+		v.Level2 = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Exp")
+		}
+		v.Exp = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Sp")
+		}
+		v.Sp = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Pp")
+		}
+		v.Pp = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Hp")
+		}
+		v.Hp = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Mp")
+		}
+		v.Mp = int(int32(x))
+	}
+	{
+		x, err := d.float32()
+		if err != nil {
+			return prefixField(err, ".Posx")
+		}
+		v.Posx = float32(x)
+	}
+	{
+		x, err := d.float32()
+		if err != nil {
+			return prefixField(err, ".Posy")
+		}
+		v.Posy = float32(x)
+	}
+	{
+		x, err := d.float32()
+		if err != nil {
+			return prefixField(err, ".Posz")
+		}
+		v.Posz = float32(x)
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Worldtag")
+		}
+		v.Worldtag = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".InvaderState")
+		}
+		v.InvaderState = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".InvaderTime")
+		}
+		v.InvaderTime = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".PariahTime")
+		}
+		v.PariahTime = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Reputation")
+		}
+		v.Reputation = int(int32(x))
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".CustomStatus")
+		}
+		v.CustomStatus = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".FilterData")
+		}
+		v.FilterData = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Charactermode")
+		}
+		v.Charactermode = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Instancekeylist")
+		}
+		v.Instancekeylist = x
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".DbltimeExpire")
+		}
+		v.DbltimeExpire = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".DbltimeMode")
+		}
+		v.DbltimeMode = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".DbltimeBegin")
+		}
+		v.DbltimeBegin = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".DbltimeUsed")
+		}
+		v.DbltimeUsed = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".DbltimeMax")
+		}
+		v.DbltimeMax = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".TimeUsed")
+		}
+		v.TimeUsed = int(int32(x))
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".DbltimeData")
+		}
+		v.DbltimeData = x
+	}
+	{
+		x, err := d.uint16()
+		if err != nil {
+			return prefixField(err, ".Storesize")
+		}
+		v.Storesize = uint16(x)
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Petcorral")
+		}
+		v.Petcorral = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Property")
+		}
+		v.Property = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".VarData")
+		}
+		v.VarData = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Skills")
+		}
+		v.Skills = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Storehousepasswd")
+		}
+		v.Storehousepasswd = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Waypointlist")
+		}
+		v.Waypointlist = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Coolingtime")
+		}
+		v.Coolingtime = x
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Reserved1")
+		}
+		v.Reserved1 = uint(x)
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Reserved2")
+		}
+		v.Reserved2 = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Reserved3")
+		}
+		v.Reserved3 = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Reserved4")
+		}
+		v.Reserved4 = int(int32(x))
+	}
+	return nil
+}
+
+// RoleStatusLevel é o início de RoleStatus, lido sem os demais campos ao verificar level e cultivo
+type RoleStatusLevel struct {
+	Sversion byte
+	Level    int
+	Level2   int
+}
+
+// AppendPW acrescenta RoleStatusLevel empacotado em b, implementando Packer
+func (v RoleStatusLevel) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "RoleStatusLevel")
+	}
+	return e.buf, nil
+}
+
+func (v *RoleStatusLevel) encodePW(e *encoder) error {
+	e.uint8(uint8(v.Sversion))
+	e.uint32(uint32(v.Level))
+	e.uint32(uint32(v.Level2))
+	return nil
+}
+
+// UnpackPW desempacota RoleStatusLevel do início de data, implementando Unpacker
+func (v *RoleStatusLevel) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "RoleStatusLevel")
+	}
+	return d.off, nil
+}
+
+func (v *RoleStatusLevel) decodePW(d *decoder) error {
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Sversion")
+		}
+		v.Sversion = byte(x)
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Level")
+		}
+		v.Level = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Level2")
+		}
+		v.Level2 = int(int32(x))
+	}
+	return nil
+}
+
+type GetRoleBaseArg struct {
+	Handler int // xid da RPC, substituído pela conexão multiplexada
+	RoleID  RoleID
+}
+
+// AppendPW acrescenta GetRoleBaseArg empacotado em b, implementando Packer
+func (v GetRoleBaseArg) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GetRoleBaseArg")
+	}
+	return e.buf, nil
+}
+
+func (v *GetRoleBaseArg) encodePW(e *encoder) error {
+	e.uint32(uint32(v.Handler))
+	if err := v.RoleID.encodePW(e); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	return nil
+}
+
+// UnpackPW desempacota GetRoleBaseArg do início de data, implementando Unpacker
+func (v *GetRoleBaseArg) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GetRoleBaseArg")
+	}
+	return d.off, nil
+}
+
+func (v *GetRoleBaseArg) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Handler")
+		}
+		v.Handler = int(int32(x))
+	}
+	if err := v.RoleID.decodePW(d); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	return nil
+}
+
+type GRoleForbid struct {
+	Type       byte
+	Time       int
+	CreateTime int
+	Reason     string `pw:"utf16"`
+}
+
+// AppendPW acrescenta GRoleForbid empacotado em b, implementando Packer
+func (v GRoleForbid) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GRoleForbid")
+	}
+	return e.buf, nil
+}
+
+func (v *GRoleForbid) encodePW(e *encoder) error {
+	e.uint8(uint8(v.Type))
+	e.uint32(uint32(v.Time))
+	e.uint32(uint32(v.CreateTime))
+	if err := e.text(v.Reason, wireUTF16, 0); err != nil {
+		return prefixField(err, ".Reason")
+	}
+	return nil
+}
+
+// UnpackPW desempacota GRoleForbid do início de data, implementando Unpacker
+func (v *GRoleForbid) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GRoleForbid")
+	}
+	return d.off, nil
+}
+
+func (v *GRoleForbid) decodePW(d *decoder) error {
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Type")
+		}
+		v.Type = byte(x)
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Time")
+		}
+		v.Time = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".CreateTime")
+		}
+		v.CreateTime = int(int32(x))
+	}
+	{
+		x, err := d.text(wireUTF16, 0)
+		if err != nil {
+			return prefixField(err, ".Reason")
+		}
+		v.Reason = x
+	}
+	return nil
+}
+
+type RoleBase struct {
+	Version       byte
+	ID            int
+	Name          string `pw:"utf16"`
+	Race          int
+	CLS           int
+	Gender        byte
+	CustomData    []byte
+	ConfigData    []byte
+	CustomStamp   int
+	Status        byte
+	DeleteTime    int
+	CreateTime    int
+	LastLoginTime int
+	ForbidSize    Cuint         `pw:"cuint"`
+	Forbid        []GRoleForbid `pw:"array,len=ForbidSize"`
+	HelpStates    []byte
+	Spouse        int
+	UserID        UserID
+	CrossData     []byte
+	Reserved2     byte
+	Reserved3     byte
+	Reserved4     byte
+}
+
+// AppendPW acrescenta RoleBase empacotado em b, implementando Packer
+func (v RoleBase) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "RoleBase")
+	}
+	return e.buf, nil
+}
+
+func (v *RoleBase) encodePW(e *encoder) error {
+	e.uint8(uint8(v.Version))
+	e.uint32(uint32(v.ID))
+	if err := e.text(v.Name, wireUTF16, 0); err != nil {
+		return prefixField(err, ".Name")
+	}
+	e.uint32(uint32(v.Race))
+	e.uint32(uint32(v.CLS))
+	e.uint8(uint8(v.Gender))
+	if err := e.octets(v.CustomData, 0); err != nil {
+		return prefixField(err, ".CustomData")
+	}
+	if err := e.octets(v.ConfigData, 0); err != nil {
+		return prefixField(err, ".ConfigData")
+	}
+	e.uint32(uint32(v.CustomStamp))
+	e.uint8(uint8(v.Status))
+	e.uint32(uint32(v.DeleteTime))
+	e.uint32(uint32(v.CreateTime))
+	e.uint32(uint32(v.LastLoginTime))
+	e.cuint(uint32(len(v.Forbid)))
+	for i := range v.Forbid {
+		if err := v.Forbid[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".Forbid[%d]", i))
+		}
+	}
+	if err := e.octets(v.HelpStates, 0); err != nil {
+		return prefixField(err, ".HelpStates")
+	}
+	e.uint32(uint32(v.Spouse))
+	e.uint32(uint32(v.UserID))
+	if err := e.octets(v.CrossData, 0); err != nil {
+		return prefixField(err, ".CrossData")
+	}
+	e.uint8(uint8(v.Reserved2))
+	e.uint8(uint8(v.Reserved3))
+	e.uint8(uint8(v.Reserved4))
+	return nil
+}
+
+// UnpackPW desempacota RoleBase do início de data, implementando Unpacker
+func (v *RoleBase) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "RoleBase")
+	}
+	return d.off, nil
+}
+
+func (v *RoleBase) decodePW(d *decoder) error {
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Version")
+		}
+		v.Version = byte(x)
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".ID")
+		}
+		v.ID = int(int32(x))
+	}
+	{
+		x, err := d.text(wireUTF16, 0)
+		if err != nil {
+			return prefixField(err, ".Name")
+		}
+		v.Name = x
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Race")
+		}
+		v.Race = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".CLS")
+		}
+		v.CLS = int(int32(x))
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Gender")
+		}
+		v.Gender = byte(x)
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".CustomData")
+		}
+		v.CustomData = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".ConfigData")
+		}
+		v.ConfigData = x
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".CustomStamp")
+		}
+		v.CustomStamp = int(int32(x))
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Status")
+		}
+		v.Status = byte(x)
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".DeleteTime")
+		}
+		v.DeleteTime = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".CreateTime")
+		}
+		v.CreateTime = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".LastLoginTime")
+		}
+		v.LastLoginTime = int(int32(x))
+	}
+	{
+		x, err := d.cuint()
+		if err != nil {
+			return prefixField(err, ".ForbidSize")
+		}
+		v.ForbidSize = Cuint(x)
+	}
+	{
+		n, err := d.count(int(v.ForbidSize))
+		if err != nil {
+			return prefixField(err, ".Forbid")
+		}
+		v.Forbid = make([]GRoleForbid, n)
+		for i := range v.Forbid {
+			if err := v.Forbid[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".Forbid[%d]", i))
+			}
+		}
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".HelpStates")
+		}
+		v.HelpStates = x
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Spouse")
+		}
+		v.Spouse = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".UserID")
+		}
+		v.UserID = UserID(int32(x))
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".CrossData")
+		}
+		v.CrossData = x
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Reserved2")
+		}
+		v.Reserved2 = byte(x)
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Reserved3")
+		}
+		v.Reserved3 = byte(x)
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Reserved4")
+		}
+		v.Reserved4 = byte(x)
+	}
+	return nil
+}
+
+// GRolePocket é o inventário (bolsa) do personagem
+type GRolePocket struct {
+	Capacity  int
+	Timestamp int
+	Money     int
+	Items     []Item
+	Reserved1 int
+	Reserved2 int
+}
+
+// AppendPW acrescenta GRolePocket empacotado em b, implementando Packer
+func (v GRolePocket) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GRolePocket")
+	}
+	return e.buf, nil
+}
+
+func (v *GRolePocket) encodePW(e *encoder) error {
+	e.uint32(uint32(v.Capacity))
+	e.uint32(uint32(v.Timestamp))
+	e.uint32(uint32(v.Money))
+	e.cuint(uint32(len(v.Items)))
+	for i := range v.Items {
+		if err := v.Items[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".Items[%d]", i))
+		}
+	}
+	e.uint32(uint32(v.Reserved1))
+	e.uint32(uint32(v.Reserved2))
+	return nil
+}
+
+// UnpackPW desempacota GRolePocket do início de data, implementando Unpacker
+func (v *GRolePocket) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GRolePocket")
+	}
+	return d.off, nil
+}
+
+func (v *GRolePocket) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Capacity")
+		}
+		v.Capacity = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Timestamp")
+		}
+		v.Timestamp = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Money")
+		}
+		v.Money = int(int32(x))
+	}
+	{
+		n, err := d.cuint()
+		if err == nil {
+			n, err = d.count(n)
+		}
+		if err != nil {
+			return prefixField(err, ".Items")
+		}
+		v.Items = make([]Item, n)
+		for i := range v.Items {
+			if err := v.Items[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".Items[%d]", i))
+			}
+		}
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Reserved1")
+		}
+		v.Reserved1 = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Reserved2")
+		}
+		v.Reserved2 = int(int32(x))
+	}
+	return nil
+}
+
+// GRoleEquipment são os itens equipados, Pos é o slot do equipamento
+type GRoleEquipment struct {
+	Items []Item
+}
+
+// AppendPW acrescenta GRoleEquipment empacotado em b, implementando Packer
+func (v GRoleEquipment) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GRoleEquipment")
+	}
+	return e.buf, nil
+}
+
+func (v *GRoleEquipment) encodePW(e *encoder) error {
+	e.cuint(uint32(len(v.Items)))
+	for i := range v.Items {
+		if err := v.Items[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".Items[%d]", i))
+		}
+	}
+	return nil
+}
+
+// UnpackPW desempacota GRoleEquipment do início de data, implementando Unpacker
+func (v *GRoleEquipment) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GRoleEquipment")
+	}
+	return d.off, nil
+}
+
+func (v *GRoleEquipment) decodePW(d *decoder) error {
+	{
+		n, err := d.cuint()
+		if err == nil {
+			n, err = d.count(n)
+		}
+		if err != nil {
+			return prefixField(err, ".Items")
+		}
+		v.Items = make([]Item, n)
+		for i := range v.Items {
+			if err := v.Items[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".Items[%d]", i))
+			}
+		}
+	}
+	return nil
+}
+
+// GRoleStorehouse é o armazém do personagem, com as abas de roupas (Dress) e de materiais (Material)
+type GRoleStorehouse struct {
+	Capacity int
+	Money    int
+	Items    []Item
+	Size1    byte // capacidade da aba de roupas
+	Size2    byte // capacidade da aba de materiais
+	Dress    []Item
+	Material []Item
+}
+
+// AppendPW acrescenta GRoleStorehouse empacotado em b, implementando Packer
+func (v GRoleStorehouse) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GRoleStorehouse")
+	}
+	return e.buf, nil
+}
+
+func (v *GRoleStorehouse) encodePW(e *encoder) error {
+	e.uint32(uint32(v.Capacity))
+	e.uint32(uint32(v.Money))
+	e.cuint(uint32(len(v.Items)))
+	for i := range v.Items {
+		if err := v.Items[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".Items[%d]", i))
+		}
+	}
+	e.uint8(uint8(v.Size1))
+	e.uint8(uint8(v.Size2))
+	e.cuint(uint32(len(v.Dress)))
+	for i := range v.Dress {
+		if err := v.Dress[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".Dress[%d]", i))
+		}
+	}
+	e.cuint(uint32(len(v.Material)))
+	for i := range v.Material {
+		if err := v.Material[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".Material[%d]", i))
+		}
+	}
+	return nil
+}
+
+// UnpackPW desempacota GRoleStorehouse do início de data, implementando Unpacker
+func (v *GRoleStorehouse) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GRoleStorehouse")
+	}
+	return d.off, nil
+}
+
+func (v *GRoleStorehouse) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Capacity")
+		}
+		v.Capacity = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Money")
+		}
+		v.Money = int(int32(x))
+	}
+	{
+		n, err := d.cuint()
+		if err == nil {
+			n, err = d.count(n)
+		}
+		if err != nil {
+			return prefixField(err, ".Items")
+		}
+		v.Items = make([]Item, n)
+		for i := range v.Items {
+			if err := v.Items[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".Items[%d]", i))
+			}
+		}
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Size1")
+		}
+		v.Size1 = byte(x)
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Size2")
+		}
+		v.Size2 = byte(x)
+	}
+	{
+		n, err := d.cuint()
+		if err == nil {
+			n, err = d.count(n)
+		}
+		if err != nil {
+			return prefixField(err, ".Dress")
+		}
+		v.Dress = make([]Item, n)
+		for i := range v.Dress {
+			if err := v.Dress[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".Dress[%d]", i))
+			}
+		}
+	}
+	{
+		n, err := d.cuint()
+		if err == nil {
+			n, err = d.count(n)
+		}
+		if err != nil {
+			return prefixField(err, ".Material")
+		}
+		v.Material = make([]Item, n)
+		for i := range v.Material {
+			if err := v.Material[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".Material[%d]", i))
+			}
+		}
+	}
+	return nil
+}
+
+// GRoleTask são os dados das missões do personagem, mantidos no formato interno do gamedbd
+type GRoleTask struct {
+	TaskData       []byte
+	TaskComplete   []byte
+	TaskFinishTime []byte
+	TaskInventory  []Item
+}
+
+// AppendPW acrescenta GRoleTask empacotado em b, implementando Packer
+func (v GRoleTask) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GRoleTask")
+	}
+	return e.buf, nil
+}
+
+func (v *GRoleTask) encodePW(e *encoder) error {
+	if err := e.octets(v.TaskData, 0); err != nil {
+		return prefixField(err, ".TaskData")
+	}
+	if err := e.octets(v.TaskComplete, 0); err != nil {
+		return prefixField(err, ".TaskComplete")
+	}
+	if err := e.octets(v.TaskFinishTime, 0); err != nil {
+		return prefixField(err, ".TaskFinishTime")
+	}
+	e.cuint(uint32(len(v.TaskInventory)))
+	for i := range v.TaskInventory {
+		if err := v.TaskInventory[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".TaskInventory[%d]", i))
+		}
+	}
+	return nil
+}
+
+// UnpackPW desempacota GRoleTask do início de data, implementando Unpacker
+func (v *GRoleTask) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GRoleTask")
+	}
+	return d.off, nil
+}
+
+func (v *GRoleTask) decodePW(d *decoder) error {
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".TaskData")
+		}
+		v.TaskData = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".TaskComplete")
+		}
+		v.TaskComplete = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".TaskFinishTime")
+		}
+		v.TaskFinishTime = x
+	}
+	{
+		n, err := d.cuint()
+		if err == nil {
+			n, err = d.count(n)
+		}
+		if err != nil {
+			return prefixField(err, ".TaskInventory")
+		}
+		v.TaskInventory = make([]Item, n)
+		for i := range v.TaskInventory {
+			if err := v.TaskInventory[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".TaskInventory[%d]", i))
+			}
+		}
+	}
+	return nil
+}
+
+type GetRoleArg struct {
+	Handler int // xid da RPC, substituído pela conexão multiplexada
+	RoleID  RoleID
+}
+
+// AppendPW acrescenta GetRoleArg empacotado em b, implementando Packer
+func (v GetRoleArg) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GetRoleArg")
+	}
+	return e.buf, nil
+}
+
+func (v *GetRoleArg) encodePW(e *encoder) error {
+	e.uint32(uint32(v.Handler))
+	if err := v.RoleID.encodePW(e); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	return nil
+}
+
+// UnpackPW desempacota GetRoleArg do início de data, implementando Unpacker
+func (v *GetRoleArg) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GetRoleArg")
+	}
+	return d.off, nil
+}
+
+func (v *GetRoleArg) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Handler")
+		}
+		v.Handler = int(int32(x))
+	}
+	if err := v.RoleID.decodePW(d); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	return nil
+}
+
+// GRoleData é o personagem completo retornado por GetRole
+type GRoleData struct {
+	Base       RoleBase
+	Status     RoleStatus
+	Pocket     GRolePocket
+	Equipment  GRoleEquipment
+	Storehouse GRoleStorehouse
+	Task       GRoleTask
+}
+
+// AppendPW acrescenta GRoleData empacotado em b, implementando Packer
+func (v GRoleData) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GRoleData")
+	}
+	return e.buf, nil
+}
+
+func (v *GRoleData) encodePW(e *encoder) error {
+	if err := v.Base.encodePW(e); err != nil {
+		return prefixField(err, ".Base")
+	}
+	if err := v.Status.encodePW(e); err != nil {
+		return prefixField(err, ".Status")
+	}
+	if err := v.Pocket.encodePW(e); err != nil {
+		return prefixField(err, ".Pocket")
+	}
+	if err := v.Equipment.encodePW(e); err != nil {
+		return prefixField(err, ".Equipment")
+	}
+	if err := v.Storehouse.encodePW(e); err != nil {
+		return prefixField(err, ".Storehouse")
+	}
+	if err := v.Task.encodePW(e); err != nil {
+		return prefixField(err, ".Task")
+	}
+	return nil
+}
+
+// UnpackPW desempacota GRoleData do início de data, implementando Unpacker
+func (v *GRoleData) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GRoleData")
+	}
+	return d.off, nil
+}
+
+func (v *GRoleData) decodePW(d *decoder) error {
+	if err := v.Base.decodePW(d); err != nil {
+		return prefixField(err, ".Base")
+	}
+	if err := v.Status.decodePW(d); err != nil {
+		return prefixField(err, ".Status")
+	}
+	if err := v.Pocket.decodePW(d); err != nil {
+		return prefixField(err, ".Pocket")
+	}
+	if err := v.Equipment.decodePW(d); err != nil {
+		return prefixField(err, ".Equipment")
+	}
+	if err := v.Storehouse.decodePW(d); err != nil {
+		return prefixField(err, ".Storehouse")
+	}
+	if err := v.Task.decodePW(d); err != nil {
+		return prefixField(err, ".Task")
+	}
+	return nil
+}
+
+type ChatBroadCast struct {
+	Channel   byte
+	Emotion   byte
+	SrcRoleID int
+	Msg       string `pw:"utf16"`
+	Data      []byte
+}
+
+// AppendPW acrescenta ChatBroadCast empacotado em b, implementando Packer
+func (v ChatBroadCast) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "ChatBroadCast")
+	}
+	return e.buf, nil
+}
+
+func (v *ChatBroadCast) encodePW(e *encoder) error {
+	e.uint8(uint8(v.Channel))
+	e.uint8(uint8(v.Emotion))
+	e.uint32(uint32(v.SrcRoleID))
+	if err := e.text(v.Msg, wireUTF16, 0); err != nil {
+		return prefixField(err, ".Msg")
+	}
+	if err := e.octets(v.Data, 0); err != nil {
+		return prefixField(err, ".Data")
+	}
+	return nil
+}
+
+// UnpackPW desempacota ChatBroadCast do início de data, implementando Unpacker
+func (v *ChatBroadCast) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "ChatBroadCast")
+	}
+	return d.off, nil
+}
+
+func (v *ChatBroadCast) decodePW(d *decoder) error {
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Channel")
+		}
+		v.Channel = byte(x)
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Emotion")
+		}
+		v.Emotion = byte(x)
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".SrcRoleID")
+		}
+		v.SrcRoleID = int(int32(x))
+	}
+	{
+		x, err := d.text(wireUTF16, 0)
+		if err != nil {
+			return prefixField(err, ".Msg")
+		}
+		v.Msg = x
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Data")
+		}
+		v.Data = x
+	}
+	return nil
+}
+
+type GMQueryOnline struct {
+	QType int
+}
+
+// AppendPW acrescenta GMQueryOnline empacotado em b, implementando Packer
+func (v GMQueryOnline) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GMQueryOnline")
+	}
+	return e.buf, nil
+}
+
+func (v *GMQueryOnline) encodePW(e *encoder) error {
+	e.uint32(uint32(v.QType))
+	return nil
+}
+
+// UnpackPW desempacota GMQueryOnline do início de data, implementando Unpacker
+func (v *GMQueryOnline) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GMQueryOnline")
+	}
+	return d.off, nil
+}
+
+func (v *GMQueryOnline) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".QType")
+		}
+		v.QType = int(int32(x))
+	}
+	return nil
+}
+
+type GMQueryOnlineRe struct {
+	QType      int
+	UsersCount Cuint    `pw:"cuint"`
+	RoleIDS    []RoleID `pw:"array,len=UsersCount"`
+}
+
+// AppendPW acrescenta GMQueryOnlineRe empacotado em b, implementando Packer
+func (v GMQueryOnlineRe) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GMQueryOnlineRe")
+	}
+	return e.buf, nil
+}
+
+func (v *GMQueryOnlineRe) encodePW(e *encoder) error {
+	e.uint32(uint32(v.QType))
+	e.cuint(uint32(len(v.RoleIDS)))
+	for i := range v.RoleIDS {
+		if err := v.RoleIDS[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".RoleIDS[%d]", i))
+		}
+	}
+	return nil
+}
+
+// UnpackPW desempacota GMQueryOnlineRe do início de data, implementando Unpacker
+func (v *GMQueryOnlineRe) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GMQueryOnlineRe")
+	}
+	return d.off, nil
+}
+
+func (v *GMQueryOnlineRe) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".QType")
+		}
+		v.QType = int(int32(x))
+	}
+	{
+		x, err := d.cuint()
+		if err != nil {
+			return prefixField(err, ".UsersCount")
+		}
+		v.UsersCount = Cuint(x)
+	}
+	{
+		n, err := d.count(int(v.UsersCount))
+		if err != nil {
+			return prefixField(err, ".RoleIDS")
+		}
+		v.RoleIDS = make([]RoleID, n)
+		for i := range v.RoleIDS {
+			if err := v.RoleIDS[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".RoleIDS[%d]", i))
+			}
+		}
+	}
+	return nil
+}
+
+type GMListOnlineUser struct {
+	GMRoleID int // personagem do GM que consulta, -1 para consultas do sistema
+	LocalSID int
+	Handler  int // posição da página, 0 na primeira e o Handler da resposta anterior nas seguintes
+	Cond     []byte
+}
+
+// AppendPW acrescenta GMListOnlineUser empacotado em b, implementando Packer
+func (v GMListOnlineUser) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GMListOnlineUser")
+	}
+	return e.buf, nil
+}
+
+func (v *GMListOnlineUser) encodePW(e *encoder) error {
+	e.uint32(uint32(v.GMRoleID))
+	e.uint32(uint32(v.LocalSID))
+	e.uint32(uint32(v.Handler))
+	if err := e.octets(v.Cond, 0); err != nil {
+		return prefixField(err, ".Cond")
+	}
+	return nil
+}
+
+// UnpackPW desempacota GMListOnlineUser do início de data, implementando Unpacker
+func (v *GMListOnlineUser) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GMListOnlineUser")
+	}
+	return d.off, nil
+}
+
+func (v *GMListOnlineUser) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".GMRoleID")
+		}
+		v.GMRoleID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".LocalSID")
+		}
+		v.LocalSID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Handler")
+		}
+		v.Handler = int(int32(x))
+	}
+	{
+		x, err := d.octets(0)
+		if err != nil {
+			return prefixField(err, ".Cond")
+		}
+		v.Cond = x
+	}
+	return nil
+}
+
+type GMListOnlineUserRe struct {
+	RetCode  int
+	GMRoleID int
+	LocalSID int
+	Handler  int // posição da próxima página, -1 na última
+	Users    []UserOnline
+}
+
+// AppendPW acrescenta GMListOnlineUserRe empacotado em b, implementando Packer
+func (v GMListOnlineUserRe) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GMListOnlineUserRe")
+	}
+	return e.buf, nil
+}
+
+func (v *GMListOnlineUserRe) encodePW(e *encoder) error {
+	e.uint32(uint32(v.RetCode))
+	e.uint32(uint32(v.GMRoleID))
+	e.uint32(uint32(v.LocalSID))
+	e.uint32(uint32(v.Handler))
+	e.cuint(uint32(len(v.Users)))
+	for i := range v.Users {
+		if err := v.Users[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".Users[%d]", i))
+		}
+	}
+	return nil
+}
+
+// UnpackPW desempacota GMListOnlineUserRe do início de data, implementando Unpacker
+func (v *GMListOnlineUserRe) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GMListOnlineUserRe")
+	}
+	return d.off, nil
+}
+
+func (v *GMListOnlineUserRe) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".RetCode")
+		}
+		v.RetCode = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".GMRoleID")
+		}
+		v.GMRoleID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".LocalSID")
+		}
+		v.LocalSID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Handler")
+		}
+		v.Handler = int(int32(x))
+	}
+	{
+		n, err := d.cuint()
+		if err == nil {
+			n, err = d.count(n)
+		}
+		if err != nil {
+			return prefixField(err, ".Users")
+		}
+		v.Users = make([]UserOnline, n)
+		for i := range v.Users {
+			if err := v.Users[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".Users[%d]", i))
+			}
+		}
+	}
+	return nil
+}
+
+type GetRoleStatusArg struct {
+	Handler int // xid da RPC, substituído pela conexão multiplexada
+	RoleID  RoleID
+}
+
+// AppendPW acrescenta GetRoleStatusArg empacotado em b, implementando Packer
+func (v GetRoleStatusArg) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GetRoleStatusArg")
+	}
+	return e.buf, nil
+}
+
+func (v *GetRoleStatusArg) encodePW(e *encoder) error {
+	e.uint32(uint32(v.Handler))
+	if err := v.RoleID.encodePW(e); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	return nil
+}
+
+// UnpackPW desempacota GetRoleStatusArg do início de data, implementando Unpacker
+func (v *GetRoleStatusArg) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GetRoleStatusArg")
+	}
+	return d.off, nil
+}
+
+func (v *GetRoleStatusArg) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Handler")
+		}
+		v.Handler = int(int32(x))
+	}
+	if err := v.RoleID.decodePW(d); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	return nil
+}
+
+type GetRoleIDArg struct {
+	Handler int    // xid da RPC, substituído pela conexão multiplexada
+	Name    string `pw:"utf16"`
+	Reason  byte
+}
+
+// AppendPW acrescenta GetRoleIDArg empacotado em b, implementando Packer
+func (v GetRoleIDArg) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GetRoleIDArg")
+	}
+	return e.buf, nil
+}
+
+func (v *GetRoleIDArg) encodePW(e *encoder) error {
+	e.uint32(uint32(v.Handler))
+	if err := e.text(v.Name, wireUTF16, 0); err != nil {
+		return prefixField(err, ".Name")
+	}
+	e.uint8(uint8(v.Reason))
+	return nil
+}
+
+// UnpackPW desempacota GetRoleIDArg do início de data, implementando Unpacker
+func (v *GetRoleIDArg) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GetRoleIDArg")
+	}
+	return d.off, nil
+}
+
+func (v *GetRoleIDArg) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Handler")
+		}
+		v.Handler = int(int32(x))
+	}
+	{
+		x, err := d.text(wireUTF16, 0)
+		if err != nil {
+			return prefixField(err, ".Name")
+		}
+		v.Name = x
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".Reason")
+		}
+		v.Reason = byte(x)
+	}
+	return nil
+}
+
+type GetRoleIDRes struct {
+	RoleID RoleID
+}
+
+// AppendPW acrescenta GetRoleIDRes empacotado em b, implementando Packer
+func (v GetRoleIDRes) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GetRoleIDRes")
+	}
+	return e.buf, nil
+}
+
+func (v *GetRoleIDRes) encodePW(e *encoder) error {
+	if err := v.RoleID.encodePW(e); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	return nil
+}
+
+// UnpackPW desempacota GetRoleIDRes do início de data, implementando Unpacker
+func (v *GetRoleIDRes) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GetRoleIDRes")
+	}
+	return d.off, nil
+}
+
+func (v *GetRoleIDRes) decodePW(d *decoder) error {
+	if err := v.RoleID.decodePW(d); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	return nil
+}
+
+type GetUserRolesArg struct {
+	Handler int // xid da RPC, substituído pela conexão multiplexada
+	UserID  UserID
+}
+
+// AppendPW acrescenta GetUserRolesArg empacotado em b, implementando Packer
+func (v GetUserRolesArg) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GetUserRolesArg")
+	}
+	return e.buf, nil
+}
+
+func (v *GetUserRolesArg) encodePW(e *encoder) error {
+	e.uint32(uint32(v.Handler))
+	e.uint32(uint32(v.UserID))
+	return nil
+}
+
+// UnpackPW desempacota GetUserRolesArg do início de data, implementando Unpacker
+func (v *GetUserRolesArg) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GetUserRolesArg")
+	}
+	return d.off, nil
+}
+
+func (v *GetUserRolesArg) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Handler")
+		}
+		v.Handler = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".UserID")
+		}
+		v.UserID = UserID(int32(x))
+	}
+	return nil
+}
+
+// UserRole é um personagem de uma conta retornado por GetUserRoles
+type UserRole struct {
+	RoleID RoleID
+	Name   string `pw:"utf16"`
+}
+
+// AppendPW acrescenta UserRole empacotado em b, implementando Packer
+func (v UserRole) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "UserRole")
+	}
+	return e.buf, nil
+}
+
+func (v *UserRole) encodePW(e *encoder) error {
+	if err := v.RoleID.encodePW(e); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	if err := e.text(v.Name, wireUTF16, 0); err != nil {
+		return prefixField(err, ".Name")
+	}
+	return nil
+}
+
+// UnpackPW desempacota UserRole do início de data, implementando Unpacker
+func (v *UserRole) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "UserRole")
+	}
+	return d.off, nil
+}
+
+func (v *UserRole) decodePW(d *decoder) error {
+	if err := v.RoleID.decodePW(d); err != nil {
+		return prefixField(err, ".RoleID")
+	}
+	{
+		x, err := d.text(wireUTF16, 0)
+		if err != nil {
+			return prefixField(err, ".Name")
+		}
+		v.Name = x
+	}
+	return nil
+}
+
+type GetUserRolesRes struct {
+	Roles []UserRole
+}
+
+// AppendPW acrescenta GetUserRolesRes empacotado em b, implementando Packer
+func (v GetUserRolesRes) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GetUserRolesRes")
+	}
+	return e.buf, nil
+}
+
+func (v *GetUserRolesRes) encodePW(e *encoder) error {
+	e.cuint(uint32(len(v.Roles)))
+	for i := range v.Roles {
+		if err := v.Roles[i].encodePW(e); err != nil {
+			return prefixField(err, fmt.Sprintf(".Roles[%d]", i))
+		}
+	}
+	return nil
+}
+
+// UnpackPW desempacota GetUserRolesRes do início de data, implementando Unpacker
+func (v *GetUserRolesRes) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GetUserRolesRes")
+	}
+	return d.off, nil
+}
+
+func (v *GetUserRolesRes) decodePW(d *decoder) error {
+	{
+		n, err := d.cuint()
+		if err == nil {
+			n, err = d.count(n)
+		}
+		if err != nil {
+			return prefixField(err, ".Roles")
+		}
+		v.Roles = make([]UserRole, n)
+		for i := range v.Roles {
+			if err := v.Roles[i].decodePW(d); err != nil {
+				return prefixField(err, fmt.Sprintf(".Roles[%d]", i))
+			}
+		}
+	}
+	return nil
+}
+
+type DebugAddCash struct {
+	UserID UserID
+	Cash   int
+}
+
+// AppendPW acrescenta DebugAddCash empacotado em b, implementando Packer
+func (v DebugAddCash) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "DebugAddCash")
+	}
+	return e.buf, nil
+}
+
+func (v *DebugAddCash) encodePW(e *encoder) error {
+	e.uint32(uint32(v.UserID))
+	e.uint32(uint32(v.Cash))
+	return nil
+}
+
+// UnpackPW desempacota DebugAddCash do início de data, implementando Unpacker
+func (v *DebugAddCash) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "DebugAddCash")
+	}
+	return d.off, nil
+}
+
+func (v *DebugAddCash) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".UserID")
+		}
+		v.UserID = UserID(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Cash")
+		}
+		v.Cash = int(int32(x))
+	}
+	return nil
+}
+
+type GetUserArg struct {
+	Handler int // xid da RPC, substituído pela conexão multiplexada
+	UserID  UserID
+}
+
+// AppendPW acrescenta GetUserArg empacotado em b, implementando Packer
+func (v GetUserArg) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "GetUserArg")
+	}
+	return e.buf, nil
+}
+
+func (v *GetUserArg) encodePW(e *encoder) error {
+	e.uint32(uint32(v.Handler))
+	e.uint32(uint32(v.UserID))
+	return nil
+}
+
+// UnpackPW desempacota GetUserArg do início de data, implementando Unpacker
+func (v *GetUserArg) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "GetUserArg")
+	}
+	return d.off, nil
+}
+
+func (v *GetUserArg) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Handler")
+		}
+		v.Handler = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".UserID")
+		}
+		v.UserID = UserID(int32(x))
+	}
+	return nil
+}
+
+// UserCash é o início do User retornado por GetUser, com os saldos de cash da conta, os demais campos do User são ignorados
+type UserCash struct {
+	LogicUID  int
+	RoleList  int
+	Cash      int
+	Money     int
+	CashAdd   int // cash creditado na conta, incrementado por DebugAddCash e pelas linhas consumidas de usecashnow
+	CashBuy   int
+	CashSell  int
+	CashUsed  int
+	AddSerial int
+	UseSerial int
+}
+
+// AppendPW acrescenta UserCash empacotado em b, implementando Packer
+func (v UserCash) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "UserCash")
+	}
+	return e.buf, nil
+}
+
+func (v *UserCash) encodePW(e *encoder) error {
+	e.uint32(uint32(v.LogicUID))
+	e.uint32(uint32(v.RoleList))
+	e.uint32(uint32(v.Cash))
+	e.uint32(uint32(v.Money))
+	e.uint32(uint32(v.CashAdd))
+	e.uint32(uint32(v.CashBuy))
+	e.uint32(uint32(v.CashSell))
+	e.uint32(uint32(v.CashUsed))
+	e.uint32(uint32(v.AddSerial))
+	e.uint32(uint32(v.UseSerial))
+	return nil
+}
+
+// UnpackPW desempacota UserCash do início de data, implementando Unpacker
+func (v *UserCash) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "UserCash")
+	}
+	return d.off, nil
+}
+
+func (v *UserCash) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".LogicUID")
+		}
+		v.LogicUID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".RoleList")
+		}
+		v.RoleList = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Cash")
+		}
+		v.Cash = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".Money")
+		}
+		v.Money = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".CashAdd")
+		}
+		v.CashAdd = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".CashBuy")
+		}
+		v.CashBuy = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".CashSell")
+		}
+		v.CashSell = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".CashUsed")
+		}
+		v.CashUsed = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".AddSerial")
+		}
+		v.AddSerial = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".UseSerial")
+		}
+		v.UseSerial = int(int32(x))
+	}
+	return nil
+}
+
+type SysSendMail struct {
+	TID         int
+	SysID       int
+	SysType     byte
+	Receiver    RoleID
+	Title       string `pw:"utf16"`
+	Content     string `pw:"utf16"`
+	AttachObj   Item
+	AttachMoney int
+}
+
+// AppendPW acrescenta SysSendMail empacotado em b, implementando Packer
+func (v SysSendMail) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "SysSendMail")
+	}
+	return e.buf, nil
+}
+
+func (v *SysSendMail) encodePW(e *encoder) error {
+	e.uint32(uint32(v.TID))
+	e.uint32(uint32(v.SysID))
+	e.uint8(uint8(v.SysType))
+	if err := v.Receiver.encodePW(e); err != nil {
+		return prefixField(err, ".Receiver")
+	}
+	if err := e.text(v.Title, wireUTF16, 0); err != nil {
+		return prefixField(err, ".Title")
+	}
+	if err := e.text(v.Content, wireUTF16, 0); err != nil {
+		return prefixField(err, ".Content")
+	}
+	if err := v.AttachObj.encodePW(e); err != nil {
+		return prefixField(err, ".AttachObj")
+	}
+	e.uint32(uint32(v.AttachMoney))
+	return nil
+}
+
+// UnpackPW desempacota SysSendMail do início de data, implementando Unpacker
+func (v *SysSendMail) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "SysSendMail")
+	}
+	return d.off, nil
+}
+
+func (v *SysSendMail) decodePW(d *decoder) error {
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".TID")
+		}
+		v.TID = int(int32(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".SysID")
+		}
+		v.SysID = int(int32(x))
+	}
+	{
+		x, err := d.uint8()
+		if err != nil {
+			return prefixField(err, ".SysType")
+		}
+		v.SysType = byte(x)
+	}
+	if err := v.Receiver.decodePW(d); err != nil {
+		return prefixField(err, ".Receiver")
+	}
+	{
+		x, err := d.text(wireUTF16, 0)
+		if err != nil {
+			return prefixField(err, ".Title")
+		}
+		v.Title = x
+	}
+	{
+		x, err := d.text(wireUTF16, 0)
+		if err != nil {
+			return prefixField(err, ".Content")
+		}
+		v.Content = x
+	}
+	if err := v.AttachObj.decodePW(d); err != nil {
+		return prefixField(err, ".AttachObj")
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".AttachMoney")
+		}
+		v.AttachMoney = int(int32(x))
+	}
+	return nil
+}
+
+type SysSendMailRe struct {
+	RetCode int16 // 0 quando o e-mail foi entregue, veja RetCodeMailboxFull e RetCodeInvalidAttachment
+	TID     int   // TID do SysSendMail respondido
+}
+
+// AppendPW acrescenta SysSendMailRe empacotado em b, implementando Packer
+func (v SysSendMailRe) AppendPW(b []byte) ([]byte, error) {
+	e := encoder{buf: b}
+	if err := (&v).encodePW(&e); err != nil {
+		return b, prefixField(err, "SysSendMailRe")
+	}
+	return e.buf, nil
+}
+
+func (v *SysSendMailRe) encodePW(e *encoder) error {
+	e.uint16(uint16(v.RetCode))
+	e.uint32(uint32(v.TID))
+	return nil
+}
+
+// UnpackPW desempacota SysSendMailRe do início de data, implementando Unpacker
+func (v *SysSendMailRe) UnpackPW(data []byte) (int, error) {
+	d := decoder{data: data}
+	if err := v.decodePW(&d); err != nil {
+		return d.off, prefixField(err, "SysSendMailRe")
+	}
+	return d.off, nil
+}
+
+func (v *SysSendMailRe) decodePW(d *decoder) error {
+	{
+		x, err := d.uint16()
+		if err != nil {
+			return prefixField(err, ".RetCode")
+		}
+		v.RetCode = int16(int16(x))
+	}
+	{
+		x, err := d.uint32()
+		if err != nil {
+			return prefixField(err, ".TID")
+		}
+		v.TID = int(int32(x))
+	}
+	return nil
+}
+
+func init() {
+	for _, p := range []Protocol{
+		{Name: "ChatBroadCast", Opcode: OpChatBroadCast, Backend: "provider", Request: reflect.TypeOf(ChatBroadCast{})},
+		{Name: "GMQueryOnline", Opcode: OpGMQueryOnline, Backend: "gdeliveryd", Request: reflect.TypeOf(GMQueryOnline{}),
+			Response: reflect.TypeOf(GMQueryOnlineRe{}), RespName: "GMQueryOnline_Re", RespOpcode: OpGMQueryOnlineRe},
+		{Name: "GMListOnlineUser", Opcode: OpGMListOnlineUser, Backend: "gdeliveryd", Request: reflect.TypeOf(GMListOnlineUser{}),
+			Response: reflect.TypeOf(GMListOnlineUserRe{}), RespName: "GMListOnlineUser_Re", RespOpcode: OpGMListOnlineUserRe},
+		{Name: "DebugAddCash", Opcode: OpDebugAddCash, Backend: "gamedbd", Request: reflect.TypeOf(DebugAddCash{})},
+		{Name: "GetUserArg", Opcode: OpGetUser, Backend: "gamedbd", Request: reflect.TypeOf(GetUserArg{}),
+			Response: reflect.TypeOf(UserCash{}), RespName: "GetUserRes", RespOpcode: OpGetUser, RPC: true},
+		{Name: "GetRoleBaseArg", Opcode: OpGetRoleBase, Backend: "gamedbd", Request: reflect.TypeOf(GetRoleBaseArg{}),
+			Response: reflect.TypeOf(RoleBase{}), RespName: "GetRoleBaseRes", RespOpcode: OpGetRoleBase, RPC: true},
+		{Name: "GetRoleStatusArg", Opcode: OpGetRoleStatus, Backend: "gamedbd", Request: reflect.TypeOf(GetRoleStatusArg{}),
+			Response: reflect.TypeOf(RoleStatus{}), RespName: "GetRoleStatusRes", RespOpcode: OpGetRoleStatus, RPC: true},
+		{Name: "GetRoleIDArg", Opcode: OpGetRoleID, Backend: "gamedbd", Request: reflect.TypeOf(GetRoleIDArg{}),
+			Response: reflect.TypeOf(GetRoleIDRes{}), RespName: "GetRoleIDRes", RespOpcode: OpGetRoleID, RPC: true},
+		{Name: "GetUserRolesArg", Opcode: OpGetUserRoles, Backend: "gamedbd", Request: reflect.TypeOf(GetUserRolesArg{}),
+			Response: reflect.TypeOf(GetUserRolesRes{}), RespName: "GetUserRolesRes", RespOpcode: OpGetUserRoles, RPC: true},
+		{Name: "GetRoleArg", Opcode: OpGetRole, Backend: "gamedbd", Request: reflect.TypeOf(GetRoleArg{}),
+			Response: reflect.TypeOf(GRoleData{}), RespName: "GetRoleRes", RespOpcode: OpGetRole, RPC: true},
+		{Name: "SysSendMail", Opcode: OpSysSendMail, Backend: "gdeliveryd", Request: reflect.TypeOf(SysSendMail{}),
+			Response: reflect.TypeOf(SysSendMailRe{}), RespName: "SysSendMail_Re", RespOpcode: OpSysSendMailRe},
+	} {
+		RegisterProtocol(p)
+	}
+}
+
+// prefixes associa os structs declarados com prefix ao struct completo
+var prefixes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(RoleStatusLevel{}): reflect.TypeOf(RoleStatus{}),
+}
+
+var profiles = []Profile{
+	{Name: "1.3.6", Versions: map[string]byte{"RoleBase": 1, "RoleStatus": 1},
+		Omit: map[string][]string{
+			"RoleBase":   {"CrossData", "Reserved2", "Reserved3", "Reserved4"},
+			"RoleStatus": {"Charactermode", "Instancekeylist", "Coolingtime"},
+		}},
+	{Name: "1.4.x", Versions: map[string]byte{"RoleBase": 1, "RoleStatus": 2},
+		Omit: map[string][]string{
+			"RoleBase":   {"CrossData"},
+			"RoleStatus": {"Coolingtime"},
+		}},
+	{Name: "1.5.x", Versions: map[string]byte{"RoleBase": 2, "RoleStatus": 2}},
+}
@@ -0,0 +1,61 @@
+package pwapi
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Os métodos gerados pelo pwgen precisam produzir os mesmos bytes e erros que o codec por reflexão,
+// utilizado pelo decode para registrar a posição dos campos
+func TestGeneratedCodecMatchesReflection(t *testing.T) {
+	values := []interface{}{
+		RoleBase{Version: 1, ID: -5, Name: "Fulano", CustomData: []byte{1, 2}, UserID: 1024,
+			Forbid: []GRoleForbid{{Type: 100, Time: -1, Reason: "spam"}, {Reason: "bot"}}, Reserved4: 0xFF},
+		RoleStatus{Sversion: 3, Level: 105, Level2: 22, Posx: 1.5, Posz: -2.25, Storesize: 0xFFFF,
+			Property: make([]byte, 300), Reserved1: 0xFFFFFFFF, Reserved4: -1},
+		GMQueryOnlineRe{QType: 1, RoleIDS: []RoleID{{1024}, {2048}, {-1}}},
+		SysSendMail{TID: 344, SysID: 1025, SysType: 3, Receiver: RoleID{1024}, Title: "Sorteio", Content: "Parabéns",
+			AttachObj: Item{ID: 11208, Count: 1, MaxCount: 9999, Data: []byte{0xDE, 0xAD}}, AttachMoney: 100},
+		ChatBroadCast{Channel: 9, SrcRoleID: 0, Msg: "olá"},
+		UserOnline{UserID: 32, RoleID: RoleID{1024}, Status: 1, Name: "Beltrano"},
+		DebugAddCash{UserID: 32, Cash: 1000},
+	}
+
+	for _, v := range values {
+		name := reflect.TypeOf(v).Name()
+		want, err := marshalValue(reflect.ValueOf(v))
+		if err != nil {
+			t.Fatalf("%s: marshalValue: %v", name, err)
+		}
+		got, err := v.(Packer).AppendPW(nil)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("%s: AppendPW = %x, %v\n             esperado %x", name, got, err, want)
+		}
+
+		// Todos os prefixos do pacote, inclusive truncados, são lidos da mesma forma pelos dois caminhos
+		for n := 0; n <= len(want); n++ {
+			viaReflect := reflect.New(reflect.TypeOf(v))
+			rest, errReflect := unmarshalValue(want[:n], viaReflect.Elem())
+
+			generated := reflect.New(reflect.TypeOf(v))
+			used, errGen := generated.Interface().(Unpacker).UnpackPW(want[:n])
+
+			if used != n-len(rest) || (errReflect == nil) != (errGen == nil) {
+				t.Fatalf("%s[:%d]: UnpackPW consumiu %d bytes (%v), reflexão %d bytes (%v)",
+					name, n, used, errGen, n-len(rest), errReflect)
+			}
+			if errGen != nil {
+				var ce, cr *CodecError
+				if !errors.As(errGen, &ce) || !errors.As(errReflect, &cr) || ce.Field != cr.Field || ce.Offset != cr.Offset {
+					t.Fatalf("%s[:%d]: erro %v, reflexão %v", name, n, errGen, errReflect)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(generated.Interface(), viaReflect.Interface()) {
+				t.Fatalf("%s: UnpackPW = %+v\n             reflexão %+v", name, generated.Elem(), viaReflect.Elem())
+			}
+		}
+	}
+}
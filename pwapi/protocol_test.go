@@ -0,0 +1,53 @@
+package pwapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCallRequiresRegisteredProtocol(t *testing.T) {
+	c, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	type naoRegistrado struct{ Handler int }
+	if _, err := Call[naoRegistrado, RoleBase](context.Background(), c, naoRegistrado{}); !errors.Is(err, ErrUnknownProtocol) {
+		t.Errorf("Call com requisição não registrada: %v", err)
+	}
+
+	// GetRoleBaseArg responde RoleBase, pedir RoleStatus é um erro de programação
+	if _, err := Call[GetRoleBaseArg, RoleStatus](context.Background(), c, GetRoleBaseArg{}); !errors.Is(err, ErrUnknownProtocol) {
+		t.Errorf("Call com resposta incompatível: %v", err)
+	}
+
+	// ChatBroadCast não possui resposta, apenas Send pode ser utilizado
+	if _, err := Call[ChatBroadCast, RoleBase](context.Background(), c, ChatBroadCast{}); !errors.Is(err, ErrUnknownProtocol) {
+		t.Errorf("Call de requisição sem resposta: %v", err)
+	}
+	if err := Send(context.Background(), c, GetRoleBaseArg{}); !errors.Is(err, ErrUnknownProtocol) {
+		t.Errorf("Send de requisição com resposta: %v", err)
+	}
+}
+
+func TestRegisterProtocolRegistersPackets(t *testing.T) {
+	for _, p := range []Protocol{
+		{Name: "GetRoleBaseArg", Opcode: OpGetRoleBase, Request: reflect.TypeOf(GetRoleBaseArg{})},
+		{Name: "GMQueryOnline", Opcode: OpGMQueryOnline, Request: reflect.TypeOf(GMQueryOnline{})},
+	} {
+		registered, ok := ProtocolFor(p.Request)
+		if !ok || registered.Opcode != p.Opcode {
+			t.Errorf("ProtocolFor(%s) = %+v, %v", p.Name, registered, ok)
+			continue
+		}
+		if req, ok := LookupPacket(registered.Opcode, CaptureRequest); !ok || req.Type != p.Request {
+			t.Errorf("requisição %s não registrada para o decode", p.Name)
+		}
+		if resp, ok := LookupPacket(registered.RespOpcode, CaptureResponse); !ok || resp.Type != registered.Response {
+			t.Errorf("resposta de %s não registrada para o decode", p.Name)
+		}
+	}
+}
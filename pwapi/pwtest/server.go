@@ -0,0 +1,635 @@
+// Package pwtest implementa um servidor falso do Perfect World para testes
+//
+// O Server abre listeners locais para o gamedbd, o gdeliveryd e o provider falando o mesmo formato de frames
+// dos serviços reais, responde às consultas de personagens online, RoleBase e RoleStatus a partir dos
+// personagens cadastrados pelo teste e registra os e-mails aceitos e o cash e as mensagens de chat recebidos.
+package pwtest
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"pwapi/pwapi"
+)
+
+// xidRequest é o bit que marca o xid de uma requisição RPC, não exportado pelo pacote pwapi
+const xidRequest = 0x80000000
+
+// DefaultPageSize é a quantidade de usuários por página de GMListOnlineUser_Re, veja SetPageSize
+const DefaultPageSize = 256
+
+// Role é um personagem cadastrado no servidor falso
+type Role struct {
+	Base   pwapi.RoleBase
+	Status pwapi.RoleStatus
+	Items  pwapi.GRoleData // inventário, equipamento, armazém e missões, Base e Status são ignorados
+}
+
+// Data retorna o personagem completo respondido em GetRole
+func (r Role) Data() pwapi.GRoleData {
+	data := r.Items
+	data.Base = r.Base
+	data.Status = r.Status
+	return data
+}
+
+// Server é um servidor falso com gamedbd, gdeliveryd e provider
+//
+// Observações:
+//
+//	Todos os métodos podem ser chamados de várias goroutines, inclusive enquanto o cliente testado está conectado
+type Server struct {
+	listeners map[string]net.Listener
+
+	mu      sync.Mutex
+	roles   map[int]Role
+	online  []pwapi.RoleID
+	mails   []pwapi.SysSendMail
+	mailRet map[int]int16
+	drops   int
+	cash    []pwapi.DebugAddCash
+	chats   []pwapi.ChatBroadCast
+	frames  []pwapi.Frame
+	profile pwapi.Profile
+	fails   map[string]int
+	mute    map[string]int
+	page    int
+	changed chan struct{}
+	conns   map[net.Conn]struct{}
+	closed  bool
+
+	wg sync.WaitGroup
+}
+
+// NewServer inicia o servidor falso em portas livres de 127.0.0.1
+//
+// Retorno:
+//
+//	*Server - Servidor aceitando conexões, deve ser encerrado com Close
+//	error - Erro ao abrir algum dos listeners
+func NewServer() (*Server, error) {
+	s := &Server{
+		listeners: map[string]net.Listener{},
+		roles:     map[int]Role{},
+		fails:     map[string]int{},
+		mute:      map[string]int{},
+		mailRet:   map[int]int16{},
+		page:      DefaultPageSize,
+		changed:   make(chan struct{}),
+		conns:     map[net.Conn]struct{}{},
+	}
+
+	handlers := map[string]func(net.Conn, pwapi.Frame) error{
+		"gamedbd":    s.handleGamedBD,
+		"gdeliveryd": s.handleDelivery,
+		"provider":   s.handleProvider,
+	}
+	for backend, handle := range handlers {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("pwtest: erro ao abrir o %s: %w", backend, err)
+		}
+		s.listeners[backend] = l
+
+		s.wg.Add(1)
+		go s.serve(l, backend, handle)
+	}
+	return s, nil
+}
+
+// Close encerra os listeners e as conexões abertas, aguardando as goroutines do servidor terminarem
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	for _, l := range s.listeners {
+		l.Close()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// Ports retorna a porta de cada serviço, com as mesmas chaves de Config.Ports
+func (s *Server) Ports() map[string]int {
+	ports := map[string]int{}
+	for backend, l := range s.listeners {
+		ports[backend] = l.Addr().(*net.TCPAddr).Port
+	}
+	return ports
+}
+
+// Options retorna as opções que apontam um pwapi.Client para o servidor falso
+func (s *Server) Options() []pwapi.Option {
+	opts := []pwapi.Option{pwapi.WithAddress("127.0.0.1")}
+	for backend, port := range s.Ports() {
+		opts = append(opts, pwapi.WithPort(backend, port))
+	}
+	return opts
+}
+
+// NewClient cria um pwapi.Client conectado ao servidor falso, opts são aplicadas depois das opções do servidor
+func (s *Server) NewClient(opts ...pwapi.Option) (*pwapi.Client, error) {
+	return pwapi.NewClient(append(s.Options(), opts...)...)
+}
+
+// AddRole cadastra um personagem online, o RoleID é o campo ID de base
+func (s *Server) AddRole(base pwapi.RoleBase, status pwapi.RoleStatus) {
+	s.addRole(base, status, true)
+}
+
+// AddOfflineRole cadastra um personagem que responde a RoleBase e RoleStatus mas não aparece na lista de online
+func (s *Server) AddOfflineRole(base pwapi.RoleBase, status pwapi.RoleStatus) {
+	s.addRole(base, status, false)
+}
+
+func (s *Server) addRole(base pwapi.RoleBase, status pwapi.RoleStatus, online bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roles[base.ID] = Role{Base: base, Status: status}
+	if online {
+		s.online = append(s.online, pwapi.RoleID{RoleID: base.ID})
+	}
+}
+
+// SetRoleItems define o inventário, o equipamento, o armazém e as missões de um personagem cadastrado
+//
+// Observações:
+//
+//	Base e Status de items são ignorados, o personagem mantém os dados de AddRole
+//	Personagens não cadastrados são ignorados
+func (s *Server) SetRoleItems(id pwapi.RoleID, items pwapi.GRoleData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if role, ok := s.roles[id.RoleID]; ok {
+		role.Items = items
+		s.roles[id.RoleID] = role
+	}
+}
+
+// SetProfile faz o servidor responder RoleBase e RoleStatus com o layout de uma versão do servidor
+//
+// Observações:
+//
+//	O byte de versão (Version e Sversion) não é alterado, o teste decide se ele corresponde ao perfil
+func (s *Server) SetProfile(p pwapi.Profile) {
+	s.mu.Lock()
+	s.profile = p
+	s.mu.Unlock()
+}
+
+// SetMailRetCode faz o gdeliveryd recusar os e-mails do personagem com o código de retorno informado
+//
+// Observações:
+//
+//	retCode igual a 0 volta a aceitar os e-mails, por exemplo pwapi.RetCodeMailboxFull simula a caixa cheia
+//	E-mails para personagens não cadastrados são recusados com pwapi.RetCodeNotFound
+func (s *Server) SetMailRetCode(id pwapi.RoleID, retCode int16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if retCode == 0 {
+		delete(s.mailRet, id.RoleID)
+		return
+	}
+	s.mailRet[id.RoleID] = retCode
+}
+
+// DropCash faz o gamedbd descartar os próximos n DebugAddCash sem creditá-los, simulando um pacote perdido
+func (s *Server) DropCash(n int) {
+	s.mu.Lock()
+	s.drops = n
+	s.mu.Unlock()
+}
+
+// SetPageSize define a quantidade de usuários por página de GMListOnlineUser_Re
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	s.page = max(n, 1)
+	s.mu.Unlock()
+}
+
+// FailNext faz o serviço encerrar a conexão sem responder às próximas n requisições, simulando um reinício
+//
+// Observações:
+//
+//	n igual a 0 volta a responder normalmente, FailNext não afeta as requisições já respondidas
+func (s *Server) FailNext(backend string, n int) {
+	s.mu.Lock()
+	s.fails[backend] = n
+	s.mu.Unlock()
+}
+
+// DropReplyNext faz o serviço processar as próximas n requisições e encerrar a conexão sem enviar a resposta
+//
+// Observações:
+//
+//	Simula uma queda após o recebimento do pacote: um SysSendMail é registrado em Mails, mas o cliente não recebe
+//	o SysSendMail_Re e não pode saber se o e-mail foi entregue
+func (s *Server) DropReplyNext(backend string, n int) {
+	s.mu.Lock()
+	s.mute[backend] = n
+	s.mu.Unlock()
+}
+
+// Failing retorna quantas requisições do serviço ainda serão recusadas por FailNext
+func (s *Server) Failing(backend string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fails[backend]
+}
+
+// fail consome uma das falhas programadas por FailNext para o serviço
+func (s *Server) fail(backend string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fails[backend] <= 0 {
+		return false
+	}
+	s.fails[backend]--
+	return true
+}
+
+// dropReply consome uma das respostas descartadas programadas por DropReplyNext para o serviço
+func (s *Server) dropReply(backend string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mute[backend] <= 0 {
+		return false
+	}
+	s.mute[backend]--
+	return true
+}
+
+// mutedConn descarta as escritas do handler, utilizada por DropReplyNext
+type mutedConn struct {
+	net.Conn
+}
+
+func (mutedConn) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Mails retorna uma cópia dos SysSendMail aceitos, na ordem de chegada
+func (s *Server) Mails() []pwapi.SysSendMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pwapi.SysSendMail(nil), s.mails...)
+}
+
+// CashAdds retorna uma cópia dos DebugAddCash creditados, na ordem de chegada
+func (s *Server) CashAdds() []pwapi.DebugAddCash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pwapi.DebugAddCash(nil), s.cash...)
+}
+
+// Broadcasts retorna uma cópia dos ChatBroadCast recebidos, na ordem de chegada
+func (s *Server) Broadcasts() []pwapi.ChatBroadCast {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pwapi.ChatBroadCast(nil), s.chats...)
+}
+
+// Unhandled retorna os frames recebidos com opcodes que o servidor falso não conhece
+func (s *Server) Unhandled() []pwapi.Frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pwapi.Frame(nil), s.frames...)
+}
+
+// Wait aguarda até cond retornar true, reavaliando a cada pacote recebido
+//
+// Observações:
+//
+//	DebugAddCash e ChatBroadCast são enviados sem aguardar resposta, portanto o teste precisa
+//	esperar o servidor processá-los antes de verificar CashAdds e Broadcasts
+func (s *Server) Wait(ctx context.Context, cond func() bool) error {
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+
+		if cond() {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// record executa fn com o lock do servidor e acorda quem estiver em Wait
+func (s *Server) record(fn func()) {
+	s.mu.Lock()
+	fn()
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+}
+
+func (s *Server) serve(l net.Listener, backend string, handle func(net.Conn, pwapi.Frame) error) {
+	defer s.wg.Done()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(conn, backend, handle)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn, backend string, handle func(net.Conn, pwapi.Frame) error) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		frame, err := pwapi.ReadFrame(conn)
+		if err != nil || s.fail(backend) {
+			return
+		}
+		if s.dropReply(backend) {
+			handle(mutedConn{conn}, frame)
+			return
+		}
+		if err := handle(conn, frame); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handleGamedBD(conn net.Conn, frame pwapi.Frame) error {
+	switch frame.Opcode {
+	case pwapi.OpGetRoleBase:
+		var arg pwapi.GetRoleBaseArg
+		if _, err := pwapi.Unmarshal(frame.Payload, &arg); err != nil {
+			return err
+		}
+		role, ok := s.role(arg.RoleID)
+		return s.replyRPC(conn, frame, ok, role.Base)
+
+	case pwapi.OpGetRoleStatus:
+		var arg pwapi.GetRoleStatusArg
+		if _, err := pwapi.Unmarshal(frame.Payload, &arg); err != nil {
+			return err
+		}
+		role, ok := s.role(arg.RoleID)
+		return s.replyRPC(conn, frame, ok, role.Status)
+
+	case pwapi.OpGetRole:
+		var arg pwapi.GetRoleArg
+		if _, err := pwapi.Unmarshal(frame.Payload, &arg); err != nil {
+			return err
+		}
+		role, ok := s.role(arg.RoleID)
+		return s.replyRPC(conn, frame, ok, role.Data())
+
+	case pwapi.OpGetRoleID:
+		var arg pwapi.GetRoleIDArg
+		if _, err := pwapi.Unmarshal(frame.Payload, &arg); err != nil {
+			return err
+		}
+		role, ok := s.roleByName(arg.Name)
+		return s.replyRPC(conn, frame, ok, pwapi.GetRoleIDRes{RoleID: pwapi.RoleID{RoleID: role.Base.ID}})
+
+	case pwapi.OpGetUserRoles:
+		var arg pwapi.GetUserRolesArg
+		if _, err := pwapi.Unmarshal(frame.Payload, &arg); err != nil {
+			return err
+		}
+		roles, ok := s.userRoles(arg.UserID)
+		return s.replyRPC(conn, frame, ok, pwapi.GetUserRolesRes{Roles: roles})
+
+	case pwapi.OpDebugAddCash:
+		var cash pwapi.DebugAddCash
+		if _, err := pwapi.Unmarshal(frame.Payload, &cash); err != nil {
+			return err
+		}
+		s.record(func() {
+			if s.drops > 0 {
+				s.drops--
+				return
+			}
+			s.cash = append(s.cash, cash)
+		})
+		return nil
+
+	case pwapi.OpGetUser:
+		var arg pwapi.GetUserArg
+		if _, err := pwapi.Unmarshal(frame.Payload, &arg); err != nil {
+			return err
+		}
+		user, ok := s.userCash(arg.UserID)
+		return s.replyRPC(conn, frame, ok, user)
+	}
+
+	s.unhandled(frame)
+	return nil
+}
+
+func (s *Server) handleDelivery(conn net.Conn, frame pwapi.Frame) error {
+	switch frame.Opcode {
+	case pwapi.OpGMQueryOnline:
+		var query pwapi.GMQueryOnline
+		if _, err := pwapi.Unmarshal(frame.Payload, &query); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		resp := pwapi.GMQueryOnlineRe{QType: query.QType, RoleIDS: append([]pwapi.RoleID(nil), s.online...)}
+		s.mu.Unlock()
+
+		payload, err := pwapi.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		_, err = conn.Write(pwapi.EncodeFrame(pwapi.OpGMQueryOnlineRe, payload))
+		return err
+
+	case pwapi.OpGMListOnlineUser:
+		var query pwapi.GMListOnlineUser
+		if _, err := pwapi.Unmarshal(frame.Payload, &query); err != nil {
+			return err
+		}
+
+		payload, err := pwapi.Marshal(s.onlinePage(query))
+		if err != nil {
+			return err
+		}
+		_, err = conn.Write(pwapi.EncodeFrame(pwapi.OpGMListOnlineUserRe, payload))
+		return err
+
+	case pwapi.OpSysSendMail:
+		var mail pwapi.SysSendMail
+		if _, err := pwapi.Unmarshal(frame.Payload, &mail); err != nil {
+			return err
+		}
+
+		resp := pwapi.SysSendMailRe{TID: mail.TID}
+		s.record(func() {
+			if _, ok := s.roles[mail.Receiver.RoleID]; !ok {
+				resp.RetCode = pwapi.RetCodeNotFound
+			} else if retCode, ok := s.mailRet[mail.Receiver.RoleID]; ok {
+				resp.RetCode = retCode
+			} else {
+				s.mails = append(s.mails, mail)
+			}
+		})
+
+		payload, err := pwapi.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		_, err = conn.Write(pwapi.EncodeFrame(pwapi.OpSysSendMailRe, payload))
+		return err
+	}
+
+	s.unhandled(frame)
+	return nil
+}
+
+// onlinePage monta a página de usuários online iniciada no Handler da consulta, que é a posição na lista de online
+func (s *Server) onlinePage(query pwapi.GMListOnlineUser) pwapi.GMListOnlineUserRe {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := pwapi.GMListOnlineUserRe{GMRoleID: query.GMRoleID, LocalSID: query.LocalSID, Handler: -1}
+	start := max(query.Handler, 0)
+	end := min(start+s.page, len(s.online))
+	for i := start; i < end; i++ {
+		base := s.roles[s.online[i].RoleID].Base
+		resp.Users = append(resp.Users, pwapi.UserOnline{UserID: base.UserID, RoleID: s.online[i], LinkID: 1,
+			LocalSID: i + 1, GSID: 1, Name: base.Name})
+	}
+	if end < len(s.online) {
+		resp.Handler = end
+	}
+	return resp
+}
+
+func (s *Server) handleProvider(conn net.Conn, frame pwapi.Frame) error {
+	if frame.Opcode == pwapi.OpChatBroadCast {
+		var chat pwapi.ChatBroadCast
+		if _, err := pwapi.Unmarshal(frame.Payload, &chat); err != nil {
+			return err
+		}
+		s.record(func() { s.chats = append(s.chats, chat) })
+		return nil
+	}
+
+	s.unhandled(frame)
+	return nil
+}
+
+func (s *Server) unhandled(frame pwapi.Frame) {
+	s.record(func() { s.frames = append(s.frames, frame) })
+}
+
+func (s *Server) role(id pwapi.RoleID) (Role, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role, ok := s.roles[id.RoleID]
+	return role, ok
+}
+
+// roleByName procura um personagem cadastrado pelo nome
+func (s *Server) roleByName(name string) (Role, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, role := range s.roles {
+		if role.Base.Name == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+// userRoles retorna os personagens cadastrados da conta em ordem de RoleID, false quando a conta não possui nenhum
+func (s *Server) userRoles(userID pwapi.UserID) ([]pwapi.UserRole, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var roles []pwapi.UserRole
+	for _, role := range s.roles {
+		if role.Base.UserID == userID {
+			roles = append(roles, pwapi.UserRole{RoleID: pwapi.RoleID{RoleID: role.Base.ID}, Name: role.Base.Name})
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].RoleID.RoleID < roles[j].RoleID.RoleID })
+	return roles, len(roles) > 0
+}
+
+// userCash retorna o cash de uma conta com personagens cadastrados, CashAdd é a soma dos DebugAddCash creditados
+func (s *Server) userCash(userID pwapi.UserID) (pwapi.UserCash, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := pwapi.UserCash{LogicUID: int(userID)}
+	for _, cash := range s.cash {
+		if cash.UserID == userID {
+			user.CashAdd += cash.Cash
+			user.AddSerial++
+		}
+	}
+	for _, role := range s.roles {
+		if role.Base.UserID == userID {
+			return user, true
+		}
+	}
+	return user, false
+}
+
+// replyRPC responde uma RPC com o xid da requisição, o código de retorno e os dados empacotados
+func (s *Server) replyRPC(conn net.Conn, req pwapi.Frame, found bool, data interface{}) error {
+	if len(req.Payload) < 4 {
+		return errors.New("pwtest: rpc sem xid")
+	}
+
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint32(payload, binary.BigEndian.Uint32(req.Payload)&^xidRequest)
+	if found {
+		s.mu.Lock()
+		profile := s.profile
+		s.mu.Unlock()
+
+		body, err := pwapi.MarshalProfile(data, profile)
+		if err != nil {
+			return err
+		}
+		payload = append(payload, body...)
+	} else {
+		binary.BigEndian.PutUint32(payload[4:], pwapi.RetCodeNotFound)
+	}
+
+	_, err := conn.Write(pwapi.EncodeFrame(req.Opcode, payload))
+	return err
+}
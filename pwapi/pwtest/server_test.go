@@ -0,0 +1,482 @@
+package pwtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pwapi/pwapi"
+)
+
+func newTestServer(t *testing.T) (*Server, *pwapi.Client) {
+	t.Helper()
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return srv, client
+}
+
+func TestServerQueries(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32},
+		pwapi.RoleStatus{Level: 105, Level2: 22, Property: []byte{1, 2, 3}})
+	srv.AddOfflineRole(pwapi.RoleBase{ID: 2048, Name: "Ciclano", UserID: 48}, pwapi.RoleStatus{Level: 10})
+
+	ctx := context.Background()
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	online, err := client.OnlineList(ctx)
+	if err != nil {
+		t.Fatalf("OnlineList: %v", err)
+	}
+	if len(online) != 1 || online[0].RoleID != 1024 {
+		t.Fatalf("OnlineList = %v, esperado [{1024}]", online)
+	}
+
+	base, err := client.RoleBase(ctx, pwapi.RoleID{RoleID: 2048})
+	if err != nil {
+		t.Fatalf("RoleBase: %v", err)
+	}
+	if base.Name != "Ciclano" || base.UserID != 48 {
+		t.Errorf("RoleBase = %+v", base)
+	}
+
+	level, err := client.RoleLevel(ctx, pwapi.RoleID{RoleID: 1024})
+	if err != nil || level.Level != 105 || level.Level2 != 22 {
+		t.Errorf("RoleLevel = %+v, %v", level, err)
+	}
+
+	status, err := client.RoleStatus(ctx, pwapi.RoleID{RoleID: 1024})
+	if err != nil {
+		t.Fatalf("RoleStatus: %v", err)
+	}
+	if status.Level != 105 || status.Level2 != 22 || len(status.Property) != 3 {
+		t.Errorf("RoleStatus = %+v", status)
+	}
+
+	_, err = client.RoleBase(ctx, pwapi.RoleID{RoleID: 4096})
+	var retErr *pwapi.RetCodeError
+	if !errors.As(err, &retErr) || retErr.RetCode != pwapi.RetCodeNotFound {
+		t.Errorf("RoleBase de personagem inexistente: %v, esperado RetCodeError %d", err, pwapi.RetCodeNotFound)
+	}
+}
+
+func TestServerRecords(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Level: 105})
+	ctx := context.Background()
+
+	item := pwapi.Item{ID: 12345, Count: 2, MaxCount: 99, Data: []byte{0xAA}}
+	if err := client.SendMail(ctx, pwapi.RoleID{RoleID: 1024}, "Título", "Conteúdo", item, 500); err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+	if err := client.AddCash(ctx, 32, 10); err != nil {
+		t.Fatalf("AddCash: %v", err)
+	}
+	if err := client.Broadcast(ctx, 9, "olá"); err != nil {
+		t.Fatalf("Broadcast: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := srv.Wait(waitCtx, func() bool {
+		return len(srv.Mails()) == 1 && len(srv.CashAdds()) == 1 && len(srv.Broadcasts()) == 1
+	})
+	if err != nil {
+		t.Fatalf("pacotes não recebidos: mails %d, cash %d, chat %d", len(srv.Mails()), len(srv.CashAdds()), len(srv.Broadcasts()))
+	}
+
+	mail := srv.Mails()[0]
+	if mail.Receiver.RoleID != 1024 || mail.Title != "Título" || mail.AttachMoney != 500 || mail.AttachObj.ID != 12345 {
+		t.Errorf("SysSendMail = %+v", mail)
+	}
+	if cash := srv.CashAdds()[0]; cash.UserID != 32 || cash.Cash != 1000 {
+		t.Errorf("DebugAddCash = %+v, esperado cash*100", cash)
+	}
+	if chat := srv.Broadcasts()[0]; chat.Channel != 9 || chat.Msg != "olá" {
+		t.Errorf("ChatBroadCast = %+v", chat)
+	}
+	if frames := srv.Unhandled(); len(frames) != 0 {
+		t.Errorf("frames não reconhecidos: %v", frames)
+	}
+}
+
+func TestServerVersionProfiles(t *testing.T) {
+	old, err := pwapi.LookupProfile("1.3.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.SetProfile(old)
+	srv.AddRole(pwapi.RoleBase{Version: 1, ID: 1024, Name: "Fulano", UserID: 32},
+		pwapi.RoleStatus{Sversion: 1, Level: 105, Level2: 22, Coolingtime: []byte{1}})
+
+	ctx := context.Background()
+	id := pwapi.RoleID{RoleID: 1024}
+
+	for _, profile := range []string{"1.3.6", pwapi.ProfileAuto} {
+		client, err := srv.NewClient(pwapi.WithProfile(profile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+
+		status, err := client.RoleStatus(ctx, id)
+		if err != nil || status.Level != 105 || status.Level2 != 22 || status.Coolingtime != nil {
+			t.Errorf("perfil %s: RoleStatus = %+v, %v", profile, status, err)
+		}
+		if level, err := client.RoleLevel(ctx, id); err != nil || level.Level != 105 {
+			t.Errorf("perfil %s: RoleLevel = %+v, %v", profile, level, err)
+		}
+		// RoleBase 1.3.6 e 1.4.x usam o mesmo byte de versão, a detecção escolhe o layout que consome o pacote
+		base, err := client.RoleBase(ctx, id)
+		if err != nil || base.Name != "Fulano" || base.UserID != 32 {
+			t.Errorf("perfil %s: RoleBase = %+v, %v", profile, base, err)
+		}
+	}
+
+	// O layout completo lê lixo do pacote 1.3.6, com o perfil errado a versão é rejeitada
+	client, err := srv.NewClient(pwapi.WithProfile("1.5.x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var verr *pwapi.VersionError
+	if _, err := client.RoleStatus(ctx, id); !errors.As(err, &verr) || verr.Version != 1 || verr.Profile != "1.5.x" {
+		t.Errorf("RoleStatus com perfil 1.5.x = %v, esperado *VersionError", err)
+	}
+
+	if _, err := client.RoleLevel(ctx, id); !errors.As(err, &verr) || verr.Profile != "1.5.x" {
+		t.Errorf("RoleLevel com perfil 1.5.x = %v, esperado *VersionError", err)
+	}
+
+	srv.AddRole(pwapi.RoleBase{Version: 9, ID: 2048}, pwapi.RoleStatus{Sversion: 9})
+	auto, err := srv.NewClient(pwapi.WithProfile(pwapi.ProfileAuto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auto.Close()
+	if _, err := auto.RoleStatus(ctx, pwapi.RoleID{RoleID: 2048}); !errors.Is(err, pwapi.ErrUnknownVersion) {
+		t.Errorf("RoleStatus com versão 9 = %v, esperado ErrUnknownVersion", err)
+	}
+
+	if _, err := srv.NewClient(pwapi.WithProfile("1.2")); !errors.Is(err, pwapi.ErrUnknownVersion) {
+		t.Errorf("NewClient com perfil 1.2 = %v, esperado ErrUnknownVersion", err)
+	}
+}
+
+func TestServerRetryAndCircuitBreaker(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Level: 105})
+
+	client, err := srv.NewClient(
+		pwapi.WithRetry(pwapi.RetryConfig{Tentativas: 3, EsperaInicial: time.Millisecond, EsperaMaxima: 5 * time.Millisecond}),
+		pwapi.WithCircuitBreaker(pwapi.BreakerConfig{LimiteFalhas: 4, TempoAberto: 50 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	id := pwapi.RoleID{RoleID: 1024}
+
+	// Um reinício do gamedbd durante a leitura é coberto pelas novas tentativas
+	srv.FailNext("gamedbd", 2)
+	if base, err := client.RoleBase(ctx, id); err != nil || base.Name != "Fulano" {
+		t.Fatalf("RoleBase após 2 falhas = %+v, %v", base, err)
+	}
+	srv.FailNext("gdeliveryd", 1)
+	if online, err := client.OnlineList(ctx); err != nil || len(online) != 1 {
+		t.Fatalf("OnlineList após 1 falha = %v, %v", online, err)
+	}
+
+	// Com o gamedbd fora do ar as tentativas se esgotam e o circuito abre após 4 falhas seguidas
+	srv.FailNext("gamedbd", 100)
+	if _, err := client.RoleStatus(ctx, id); err == nil || errors.Is(err, pwapi.ErrCircuitOpen) {
+		t.Fatalf("RoleStatus com gamedbd fora = %v, esperado erro de comunicação", err)
+	}
+	var open *pwapi.CircuitOpenError
+	_, err = client.RoleLevel(ctx, id)
+	if !errors.As(err, &open) || open.Backend != "gamedbd" || !strings.Contains(err.Error(), "gamedbd") {
+		t.Fatalf("RoleLevel com o circuito aberto = %v, esperado *CircuitOpenError do gamedbd", err)
+	}
+	if remaining := srv.Failing("gamedbd"); remaining != 96 {
+		t.Errorf("o gamedbd recebeu %d requisições, esperado 4 antes do circuito abrir", 100-remaining)
+	}
+
+	// O circuito de um serviço não afeta os demais
+	if _, err := client.OnlineList(ctx); err != nil {
+		t.Errorf("OnlineList com o circuito do gamedbd aberto: %v", err)
+	}
+
+	// Passado TempoAberto a chamada de teste fecha o circuito quando o serviço volta
+	srv.FailNext("gamedbd", 0)
+	time.Sleep(60 * time.Millisecond)
+	if level, err := client.RoleLevel(ctx, id); err != nil || level.Level != 105 {
+		t.Fatalf("RoleLevel após o gamedbd voltar = %+v, %v", level, err)
+	}
+
+	// Códigos de retorno do serviço não são repetidos nem contam como falha
+	var retCode *pwapi.RetCodeError
+	for i := 0; i < 5; i++ {
+		if _, err := client.RoleBase(ctx, pwapi.RoleID{RoleID: 9999}); !errors.As(err, &retCode) {
+			t.Fatalf("RoleBase de personagem inexistente = %v, esperado *RetCodeError", err)
+		}
+	}
+}
+
+func TestServerOnlineUsersPages(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.SetPageSize(2)
+	for i := 1; i <= 5; i++ {
+		srv.AddRole(pwapi.RoleBase{ID: i * 1024, Name: fmt.Sprintf("Jogador%d", i), UserID: pwapi.UserID(i * 16)}, pwapi.RoleStatus{})
+	}
+	srv.AddOfflineRole(pwapi.RoleBase{ID: 9999, Name: "Offline", UserID: 999}, pwapi.RoleStatus{})
+
+	users, err := client.OnlineUsers(context.Background())
+	if err != nil {
+		t.Fatalf("OnlineUsers: %v", err)
+	}
+	if len(users) != 5 {
+		t.Fatalf("OnlineUsers retornou %d usuários em 3 páginas, esperado 5: %+v", len(users), users)
+	}
+	for i, u := range users {
+		n := i + 1
+		if u.RoleID.RoleID != n*1024 || u.UserID != pwapi.UserID(n*16) || u.Name != fmt.Sprintf("Jogador%d", n) {
+			t.Errorf("usuário %d = %+v", i, u)
+		}
+	}
+
+	// Uma falha de comunicação no meio da paginação repete apenas a página
+	srv.FailNext("gdeliveryd", 1)
+	if users, err := client.OnlineUsers(context.Background()); err != nil || len(users) != 5 {
+		t.Errorf("OnlineUsers após uma falha = %d usuários, %v", len(users), err)
+	}
+}
+
+func TestServerRoleLookups(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{})
+	srv.AddOfflineRole(pwapi.RoleBase{ID: 3072, Name: "Fulano Alt", UserID: 32}, pwapi.RoleStatus{})
+	srv.AddOfflineRole(pwapi.RoleBase{ID: 2048, Name: "Ciclano", UserID: 48}, pwapi.RoleStatus{})
+	ctx := context.Background()
+
+	if id, err := client.RoleIDByName(ctx, "Fulano Alt"); err != nil || id.RoleID != 3072 {
+		t.Errorf("RoleIDByName(Fulano Alt) = %v, %v", id, err)
+	}
+	if _, err := client.RoleIDByName(ctx, "Beltrano"); !errors.Is(err, pwapi.ErrNotFound) {
+		t.Errorf("RoleIDByName(Beltrano) = %v, esperado ErrNotFound", err)
+	}
+
+	roles, err := client.UserRoles(ctx, 32)
+	want := []pwapi.UserRole{{RoleID: pwapi.RoleID{RoleID: 1024}, Name: "Fulano"}, {RoleID: pwapi.RoleID{RoleID: 3072}, Name: "Fulano Alt"}}
+	if err != nil || !reflect.DeepEqual(roles, want) {
+		t.Errorf("UserRoles(32) = %+v, %v", roles, err)
+	}
+	if _, err := client.UserRoles(ctx, 99); !errors.Is(err, pwapi.ErrNotFound) {
+		t.Errorf("UserRoles(99) = %v, esperado ErrNotFound", err)
+	}
+}
+
+func TestServerRoleItems(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	id := pwapi.RoleID{RoleID: 1024}
+	srv.AddRole(pwapi.RoleBase{Version: 1, ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Sversion: 1, Level: 105})
+	oraculo := pwapi.GRoleInventory{ID: 7749, Pos: 3, Count: 5, MaxCount: 30, Data: []byte{0x13, 0x08, 0, 0}}
+	arma := pwapi.GRoleInventory{ID: 1234, Pos: 0, Count: 1, MaxCount: 1, ProcType: 0x13, ExpireDate: 1700000000}
+	srv.SetRoleItems(id, pwapi.GRoleData{
+		Pocket:     pwapi.GRolePocket{Capacity: 32, Money: 1000, Items: []pwapi.Item{oraculo}},
+		Equipment:  pwapi.GRoleEquipment{Items: []pwapi.Item{arma}},
+		Storehouse: pwapi.GRoleStorehouse{Capacity: 16, Items: []pwapi.Item{{ID: 7749, Count: 10}}, Size1: 8, Size2: 8},
+	})
+
+	// O RoleBase e o RoleStatus contidos na resposta seguem o layout do perfil do servidor
+	old, err := pwapi.LookupProfile("1.3.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetProfile(old)
+
+	ctx := context.Background()
+	for _, profile := range []string{"1.3.6", pwapi.ProfileAuto} {
+		client, err := srv.NewClient(pwapi.WithProfile(profile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+
+		role, err := client.Role(ctx, id)
+		if err != nil {
+			t.Fatalf("perfil %s: Role: %v", profile, err)
+		}
+		if role.Base.Name != "Fulano" || role.Status.Level != 105 || role.Pocket.Money != 1000 {
+			t.Errorf("perfil %s: Role = %+v", profile, role)
+		}
+		if !reflect.DeepEqual(role.Pocket.Items, []pwapi.Item{oraculo}) {
+			t.Errorf("perfil %s: inventário = %+v", profile, role.Pocket.Items)
+		}
+		if item, ok := role.Equipped(1234); !ok || item.ExpireDate != 1700000000 || item.ProcType != 0x13 {
+			t.Errorf("perfil %s: Equipped(1234) = %+v, %v", profile, item, ok)
+		}
+		if _, ok := role.Equipped(7749); ok {
+			t.Errorf("perfil %s: Equipped(7749) encontrou um item do inventário", profile)
+		}
+		if n := role.CountItem(7749); n != 15 {
+			t.Errorf("perfil %s: CountItem(7749) = %d, esperado 15", profile, n)
+		}
+	}
+
+	// Com o perfil errado o RoleBase versão 1 é rejeitado, mesmo que a leitura falhe apenas no armazém
+	client, err := srv.NewClient(pwapi.WithProfile("1.5.x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var verr *pwapi.VersionError
+	if _, err := client.Role(ctx, id); !errors.As(err, &verr) || verr.Struct != "RoleBase" || verr.Profile != "1.5.x" {
+		t.Errorf("Role com perfil 1.5.x = %v, esperado *VersionError de RoleBase", err)
+	}
+}
+
+func TestServerMailRetCodes(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Level: 105})
+	ctx := context.Background()
+
+	srv.SetMailRetCode(pwapi.RoleID{RoleID: 1024}, pwapi.RetCodeMailboxFull)
+	err := client.SendMail(ctx, pwapi.RoleID{RoleID: 1024}, "Título", "Conteúdo", pwapi.Item{}, 500)
+	var retErr *pwapi.RetCodeError
+	if !errors.Is(err, pwapi.ErrMailboxFull) || !errors.As(err, &retErr) || retErr.Opcode != pwapi.OpSysSendMail {
+		t.Errorf("SendMail com caixa cheia: %v", err)
+	}
+
+	srv.SetMailRetCode(pwapi.RoleID{RoleID: 1024}, pwapi.RetCodeInvalidAttachment)
+	if err := client.SendMail(ctx, pwapi.RoleID{RoleID: 1024}, "Título", "Conteúdo", pwapi.Item{ID: 1}, 0); !errors.Is(err, pwapi.ErrInvalidAttachment) {
+		t.Errorf("SendMail com item inválido: %v", err)
+	}
+
+	if err := client.SendMail(ctx, pwapi.RoleID{RoleID: 4096}, "Título", "Conteúdo", pwapi.Item{}, 500); !errors.Is(err, pwapi.ErrNotFound) {
+		t.Errorf("SendMail para personagem inexistente: %v", err)
+	}
+
+	// Apenas o e-mail aceito é registrado
+	srv.SetMailRetCode(pwapi.RoleID{RoleID: 1024}, 0)
+	if err := client.SendMail(ctx, pwapi.RoleID{RoleID: 1024}, "Título", "Conteúdo", pwapi.Item{}, 700); err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+	if mails := srv.Mails(); len(mails) != 1 || mails[0].AttachMoney != 700 {
+		t.Errorf("Mails = %+v, esperado apenas o e-mail aceito", mails)
+	}
+}
+
+func TestServerMailNotResentAfterLostReply(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Level: 105})
+	ctx := context.Background()
+
+	// O primeiro e-mail deixa a conexão ociosa no pool, o segundo a reaproveita
+	if err := client.SendMail(ctx, pwapi.RoleID{RoleID: 1024}, "Título", "Conteúdo", pwapi.Item{}, 500); err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+
+	// O gdeliveryd recebe o e-mail e fecha a conexão sem o SysSendMail_Re
+	srv.DropReplyNext("gdeliveryd", 1)
+	err := client.SendMail(ctx, pwapi.RoleID{RoleID: 1024}, "Título", "Conteúdo", pwapi.Item{}, 700)
+	if err == nil || pwapi.RewardNotDelivered(err) {
+		t.Errorf("SendMail sem resposta = %v, esperado um erro sem garantia de não entrega", err)
+	}
+
+	var moedas []int
+	for _, mail := range srv.Mails() {
+		moedas = append(moedas, mail.AttachMoney)
+	}
+	if len(moedas) != 2 || moedas[1] != 700 {
+		t.Errorf("moedas recebidas = %v, esperado o segundo e-mail exatamente uma vez", moedas)
+	}
+}
+
+func TestServerGoldDelivery(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Level: 105})
+
+	client, err := srv.NewClient(pwapi.WithGold(pwapi.GoldConfig{Multiplicador: 10, Confirmacao: 200 * time.Millisecond,
+		Intervalo: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if err := client.AddGold(ctx, 32, 5); err != nil {
+		t.Fatalf("AddGold: %v", err)
+	}
+	user, err := client.UserCash(ctx, 32)
+	if err != nil || user.CashAdd != 50 {
+		t.Errorf("UserCash = %+v, %v, esperado CashAdd 50 com o multiplicador 10", user, err)
+	}
+
+	// Um DebugAddCash perdido não é confirmado e não é reenviado
+	srv.DropCash(1)
+	if err := client.AddGold(ctx, 32, 5); !errors.Is(err, pwapi.ErrGoldNotConfirmed) {
+		t.Errorf("AddGold descartado: %v, esperado ErrGoldNotConfirmed", err)
+	}
+	if cash := srv.CashAdds(); len(cash) != 1 {
+		t.Errorf("CashAdds = %+v, esperado apenas o primeiro", cash)
+	}
+
+	// Sem a leitura inicial da conta o gold não é enviado
+	if err := client.AddGold(ctx, 48, 5); !errors.Is(err, pwapi.ErrNotFound) {
+		t.Errorf("AddGold para conta inexistente: %v, esperado ErrNotFound", err)
+	}
+
+	// usecashnow depende do banco de dados, ausente no servidor falso
+	semBanco, err := srv.NewClient(pwapi.WithGold(pwapi.GoldConfig{Entrega: pwapi.GoldUseCashNow}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer semBanco.Close()
+	if err := semBanco.AddGold(ctx, 32, 5); !errors.Is(err, pwapi.ErrNoDB) {
+		t.Errorf("AddGold por usecashnow sem banco: %v, esperado ErrNoDB", err)
+	}
+
+	invalid, err := srv.NewClient(pwapi.WithGold(pwapi.GoldConfig{Entrega: "sql"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer invalid.Close()
+	if err := invalid.AddGold(ctx, 32, 5); err == nil {
+		t.Error("AddGold com entrega desconhecida não retornou erro")
+	}
+}
@@ -0,0 +1,219 @@
+package pwapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Valores padrão aplicados quando não informados em Config.Tentativas e Config.Circuito
+const (
+	DefaultRetryAttempts    = 3
+	DefaultRetryInitialWait = 200 * time.Millisecond
+	DefaultRetryMaxWait     = 2 * time.Second
+	DefaultBreakerFailures  = 5
+	DefaultBreakerOpenTime  = 30 * time.Second
+)
+
+// ErrCircuitOpen indica que o circuito do serviço está aberto e a chamada foi recusada sem acessar a rede
+var ErrCircuitOpen = errors.New("circuito aberto")
+
+// RetryConfig define as novas tentativas das leituras idempotentes (RoleBase, RoleStatus e OnlineList)
+//
+// Observações:
+//
+//	A espera entre as tentativas começa em EsperaInicial e dobra a cada nova tentativa, limitada a EsperaMaxima
+//	Apenas falhas de comunicação são repetidas, códigos de retorno do serviço e respostas malformadas não
+//	Envios como SendMail e AddCash nunca são repetidos, já que o serviço pode ter recebido o pacote
+type RetryConfig struct {
+	Tentativas    int           `yaml:"Tentativas"`    // total de tentativas, 0 usa DefaultRetryAttempts e 1 desativa as novas tentativas
+	EsperaInicial time.Duration `yaml:"EsperaInicial"` // espera antes da segunda tentativa
+	EsperaMaxima  time.Duration `yaml:"EsperaMaxima"`  // espera máxima entre duas tentativas
+}
+
+// BreakerConfig define o circuit breaker mantido para cada serviço
+//
+// Observações:
+//
+//	Após LimiteFalhas falhas de comunicação seguidas o circuito abre e as chamadas ao serviço retornam
+//	*CircuitOpenError imediatamente durante TempoAberto. Depois disso uma única chamada é liberada como teste:
+//	se ela funcionar o circuito fecha, senão ele abre novamente
+type BreakerConfig struct {
+	LimiteFalhas int           `yaml:"LimiteFalhas"` // falhas seguidas que abrem o circuito, 0 usa DefaultBreakerFailures e -1 desativa
+	TempoAberto  time.Duration `yaml:"TempoAberto"`  // tempo que o circuito permanece aberto antes da chamada de teste
+}
+
+// CircuitOpenError indica que a chamada foi recusada porque o circuito do serviço está aberto
+type CircuitOpenError struct {
+	Backend  string        // serviço com o circuito aberto
+	Failures int           // falhas seguidas que abriram o circuito
+	Wait     time.Duration // tempo restante até a próxima chamada de teste
+	Err      error         // última falha de comunicação com o serviço
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("pwapi: %v do %s após %d falhas, nova tentativa em %v: %v", ErrCircuitOpen, e.Backend, e.Failures,
+		e.Wait.Round(time.Millisecond), e.Err)
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// retryConfig retorna as novas tentativas configuradas, completando com os valores padrão
+func (c *Client) retryConfig() RetryConfig {
+	r := c.retryCfg
+	if r.Tentativas <= 0 {
+		r.Tentativas = DefaultRetryAttempts
+	}
+	if r.EsperaInicial <= 0 {
+		r.EsperaInicial = DefaultRetryInitialWait
+	}
+	if r.EsperaMaxima <= 0 {
+		r.EsperaMaxima = DefaultRetryMaxWait
+	}
+	return r
+}
+
+// backoff retorna a espera antes da tentativa attempt (a partir de 2), dobrando a cada tentativa
+func (r RetryConfig) backoff(attempt int) time.Duration {
+	wait := r.EsperaInicial
+	for i := 2; i < attempt && wait < r.EsperaMaxima; i++ {
+		wait *= 2
+	}
+	return min(wait, r.EsperaMaxima)
+}
+
+// retryable indica se a chamada pode ser repetida após o erro
+//
+// Observações:
+//
+//	Códigos de retorno, versões desconhecidas e respostas malformadas se repetiriam em uma nova tentativa,
+//	o circuito aberto deve falhar rápido e o contexto cancelado encerra as tentativas
+func retryable(ctx context.Context, err error) bool {
+	var retCode *RetCodeError
+	var codec *CodecError
+	switch {
+	case ctx.Err() != nil:
+		return false
+	case errors.As(err, &retCode), errors.As(err, &codec):
+		return false
+	case errors.Is(err, ErrCircuitOpen), errors.Is(err, ErrUnknownVersion), errors.Is(err, ErrUnknownProtocol):
+		return false
+	}
+	return true
+}
+
+// callRetry envia uma chamada idempotente com Call, repetindo-a após falhas de comunicação
+//
+// Observações:
+//
+//	Na reprodução de uma captura a chamada não é repetida, a captura já contém o resultado de cada tentativa
+func callRetry[Req, Resp any](ctx context.Context, c *Client, req Req) (Resp, error) {
+	r := c.retryConfig()
+	if c.replay != nil {
+		r.Tentativas = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := Call[Req, Resp](ctx, c, req)
+		if err == nil || attempt >= r.Tentativas || !retryable(ctx, err) {
+			return resp, err
+		}
+
+		wait := r.backoff(attempt + 1)
+		c.debugf("Tentativa %d de %d falhou: %v, nova tentativa em %v", attempt, r.Tentativas, err, wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+	}
+}
+
+// breaker é o circuit breaker de um serviço
+type breaker struct {
+	backend string
+	cfg     BreakerConfig
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+	lastErr   error
+}
+
+// allow libera a chamada ou retorna *CircuitOpenError quando o circuito está aberto
+//
+// Retorno:
+//
+//	func(err error) - Deve ser chamada com o resultado da comunicação para atualizar o circuito
+//	error - Circuito aberto, a chamada não deve ser feita
+func (b *breaker) allow(ctx context.Context) (func(err error), error) {
+	if b == nil {
+		return func(error) {}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures >= b.cfg.LimiteFalhas {
+		now := time.Now()
+		if now.Before(b.openUntil) || b.probing {
+			return nil, &CircuitOpenError{Backend: b.backend, Failures: b.failures, Wait: max(b.openUntil.Sub(now), 0), Err: b.lastErr}
+		}
+		// Meio aberto: somente esta chamada testa o serviço até o seu resultado
+		b.probing = true
+	}
+	probe := b.probing
+
+	return func(err error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if probe {
+			b.probing = false
+		}
+
+		switch {
+		case err == nil:
+			b.failures = 0
+			b.lastErr = nil
+		case ctx.Err() != nil:
+			// O cancelamento pelo chamador não indica que o serviço está fora do ar
+		default:
+			b.failures++
+			b.lastErr = err
+			if b.failures >= b.cfg.LimiteFalhas {
+				b.openUntil = time.Now().Add(b.cfg.TempoAberto)
+			}
+		}
+	}, nil
+}
+
+// breakerFor retorna o circuit breaker do serviço, ou nil quando desativado
+func (c *Client) breakerFor(backend string) *breaker {
+	cfg := c.breakerCfg
+	if cfg.LimiteFalhas < 0 {
+		return nil
+	}
+	if cfg.LimiteFalhas == 0 {
+		cfg.LimiteFalhas = DefaultBreakerFailures
+	}
+	if cfg.TempoAberto <= 0 {
+		cfg.TempoAberto = DefaultBreakerOpenTime
+	}
+
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+
+	b, ok := c.breakers[backend]
+	if !ok {
+		b = &breaker{backend: backend, cfg: cfg}
+		c.breakers[backend] = b
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package pwapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	r := RetryConfig{Tentativas: 6, EsperaInicial: 100 * time.Millisecond, EsperaMaxima: time.Second}
+	want := []time.Duration{100, 200, 400, 800, 1000}
+	for i, w := range want {
+		if got := r.backoff(i + 2); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, esperado %v", i+2, got, w*time.Millisecond)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{context.Background(), fmt.Errorf("erro ao enviar para o gamedbd: %w", ErrMuxClosed), true},
+		{context.Background(), &RetCodeError{Opcode: OpGetRoleBase, RetCode: 3}, false},
+		{context.Background(), &CodecError{Op: "unmarshal", Err: ErrShortBuffer}, false},
+		{context.Background(), &VersionError{Struct: "RoleBase", Version: 9}, false},
+		{context.Background(), fmt.Errorf("erro ao enviar para o gamedbd: %w", &CircuitOpenError{Backend: "gamedbd"}), false},
+		{canceled, ErrMuxClosed, false},
+	}
+	for _, c := range cases {
+		if got := retryable(c.ctx, c.err); got != c.want {
+			t.Errorf("retryable(%v) = %v, esperado %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := &breaker{backend: "gamedbd", cfg: BreakerConfig{LimiteFalhas: 2, TempoAberto: 20 * time.Millisecond}}
+	ctx := context.Background()
+	down := errors.New("connection refused")
+
+	for i := 0; i < 2; i++ {
+		done, err := b.allow(ctx)
+		if err != nil {
+			t.Fatalf("falha %d: circuito aberto antes do limite: %v", i+1, err)
+		}
+		done(down)
+	}
+	if _, err := b.allow(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow com o circuito aberto = %v", err)
+	}
+
+	// Apenas uma chamada de teste é liberada após TempoAberto
+	time.Sleep(25 * time.Millisecond)
+	probe, err := b.allow(ctx)
+	if err != nil {
+		t.Fatalf("chamada de teste recusada: %v", err)
+	}
+	if _, err := b.allow(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("segunda chamada durante o teste = %v, esperado ErrCircuitOpen", err)
+	}
+	probe(down)
+	if _, err := b.allow(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("teste com falha não reabriu o circuito: %v", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	probe, err = b.allow(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe(nil)
+	if done, err := b.allow(ctx); err != nil {
+		t.Fatalf("teste com sucesso não fechou o circuito: %v", err)
+	} else {
+		done(nil)
+	}
+}
@@ -0,0 +1,290 @@
+package pwapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entregadores registrados por padrão, utilizados em Sorteio.Tipo e PremioConfig.Entrega
+const (
+	RewardMailItem  = "item"    // e-mail com o item do prêmio
+	RewardMailMoney = "moedas"  // e-mail com Quantidade moedas
+	RewardCash      = "gold"    // Quantidade gold creditado na conta com AddGold
+	RewardSQL       = "sql"     // comando SQL executado no banco de dados do cliente
+	RewardCommand   = "comando" // programa externo que recebe o ganhador e o prêmio em JSON na entrada padrão
+)
+
+// DefaultRewardMailTitle é o título dos e-mails de prêmio sem Sorteio.Titulo
+const DefaultRewardMailTitle = "Logue e ganhe"
+
+// DefaultRewardCommandTimeout é o tempo máximo de execução de RewardCommand quando o contexto não possui prazo
+const DefaultRewardCommandTimeout = 30 * time.Second
+
+// ErrRewardRejected indica que o entregador recusou o prêmio sem entregá-lo, como um SQL que não alterou nenhuma linha
+var ErrRewardRejected = errors.New("prêmio recusado")
+
+// RewardNotDelivered indica se o erro de Deliver garante que o prêmio não foi entregue
+//
+// Observações:
+//
+//	São garantidos os códigos de retorno do serviço (*RetCodeError), ErrRewardRejected, o circuito aberto, a falta
+//	do banco de dados e as conexões recusadas, casos em que o prêmio pode ser reenviado sem risco de duplicá-lo
+//	Prazos esgotados após o envio e ErrGoldNotConfirmed não são garantidos, o prêmio pode ter sido entregue
+func RewardNotDelivered(err error) bool {
+	var retCode *RetCodeError
+	var opErr *net.OpError
+	switch {
+	case err == nil, errors.Is(err, ErrGoldNotConfirmed):
+		return false
+	case errors.As(err, &retCode), errors.Is(err, ErrRewardRejected), errors.Is(err, ErrCircuitOpen), errors.Is(err, ErrNoDB):
+		return true
+	case errors.As(err, &opErr):
+		return opErr.Op == "dial"
+	}
+	return false
+}
+
+// RewardDeliverer entrega um prêmio sorteado ao ganhador
+//
+// Observações:
+//
+//	Validate é chamado ao montar a lista de prêmios, antes do sorteio, para que um prêmio mal configurado não
+//	deixe um ganhador sem prêmio
+//	Deliver só deve retornar nil quando o prêmio foi entregue, o ganhador é anunciado apenas nesse caso
+//	Erros que garantem que nada foi entregue devem envolver ErrRewardRejected, para que o prêmio possa ser reenviado
+type RewardDeliverer interface {
+	Validate(prize Sorteio) error
+	Deliver(ctx context.Context, c *Client, winner UserOnline, prize Sorteio) error
+}
+
+var (
+	deliverersMu sync.RWMutex
+	deliverers   = map[string]RewardDeliverer{
+		RewardMailItem:  mailItemDeliverer{},
+		RewardMailMoney: mailMoneyDeliverer{},
+		RewardCash:      cashDeliverer{},
+		RewardSQL:       sqlDeliverer{},
+		RewardCommand:   commandDeliverer{},
+	}
+)
+
+// RegisterRewardDeliverer registra um entregador de prêmios, substituindo o registro anterior do mesmo nome
+//
+// Observações:
+//
+//	Após o registro, prêmios de Config.Premios com Entrega igual a name são entregues por d
+func RegisterRewardDeliverer(name string, d RewardDeliverer) {
+	deliverersMu.Lock()
+	deliverers[name] = d
+	deliverersMu.Unlock()
+}
+
+// RewardDelivererFor retorna o entregador registrado com o nome informado
+func RewardDelivererFor(name string) (RewardDeliverer, bool) {
+	deliverersMu.RLock()
+	defer deliverersMu.RUnlock()
+	d, ok := deliverers[name]
+	return d, ok
+}
+
+// mailTitle retorna o título do e-mail do prêmio
+func (s Sorteio) mailTitle() string {
+	if s.Titulo != "" {
+		return s.Titulo
+	}
+	return DefaultRewardMailTitle
+}
+
+// mailItemDeliverer envia o item do prêmio por e-mail
+type mailItemDeliverer struct{}
+
+func (mailItemDeliverer) Validate(prize Sorteio) error {
+	if prize.Item.ID <= 0 {
+		return errors.New("entrega item sem o ID do item")
+	}
+	return nil
+}
+
+func (mailItemDeliverer) Deliver(ctx context.Context, c *Client, winner UserOnline, prize Sorteio) error {
+	content := prize.Conteudo
+	if content == "" {
+		content = "Parabens, você ganhou um item no logue e ganhe"
+	}
+	return c.SendMail(ctx, winner.RoleID, prize.mailTitle(), content, prize.Item, 0)
+}
+
+// mailMoneyDeliverer envia as moedas do prêmio por e-mail
+type mailMoneyDeliverer struct{}
+
+func (mailMoneyDeliverer) Validate(prize Sorteio) error {
+	if prize.Quantidade <= 0 {
+		return errors.New("entrega moedas sem quantidade")
+	}
+	return nil
+}
+
+func (mailMoneyDeliverer) Deliver(ctx context.Context, c *Client, winner UserOnline, prize Sorteio) error {
+	content := prize.Conteudo
+	if content == "" {
+		content = "Parabens, você ganhou moedas no logue e ganhe"
+	}
+	return c.SendMail(ctx, winner.RoleID, prize.mailTitle(), content, Item{}, prize.Quantidade)
+}
+
+// cashDeliverer credita o gold do prêmio na conta, veja AddGold
+type cashDeliverer struct{}
+
+func (cashDeliverer) Validate(prize Sorteio) error {
+	if prize.Quantidade <= 0 {
+		return errors.New("entrega gold sem quantidade")
+	}
+	return nil
+}
+
+func (cashDeliverer) Deliver(ctx context.Context, c *Client, winner UserOnline, prize Sorteio) error {
+	return c.AddGold(ctx, winner.UserID, prize.Quantidade)
+}
+
+// sqlDeliverer executa o comando SQL do prêmio no banco de dados do cliente
+//
+// Observações:
+//
+//	O comando pode utilizar {userid}, {roleid}, {nome} e {quantidade}, enviados como parâmetros da consulta e
+//	nunca concatenados ao SQL. O prêmio falha quando nenhuma linha é alterada
+type sqlDeliverer struct{}
+
+func (sqlDeliverer) Validate(prize Sorteio) error {
+	if strings.TrimSpace(prize.SQL) == "" {
+		return errors.New("entrega sql sem o comando SQL")
+	}
+	return nil
+}
+
+func (sqlDeliverer) Deliver(ctx context.Context, c *Client, winner UserOnline, prize Sorteio) error {
+	if c.db == nil {
+		return ErrNoDB
+	}
+
+	query, args := expandRewardSQL(prize.SQL, winner, prize)
+	result, err := c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("erro ao executar o SQL do prêmio %s: %w", prize.Nome, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: o SQL do prêmio %s não alterou nenhuma linha", ErrRewardRejected, prize.Nome)
+	}
+	return nil
+}
+
+// expandRewardSQL troca os marcadores do comando SQL por ?, retornando os valores na ordem em que aparecem
+func expandRewardSQL(query string, winner UserOnline, prize Sorteio) (string, []interface{}) {
+	values := map[string]interface{}{
+		"{userid}":     int(winner.UserID),
+		"{roleid}":     winner.RoleID.RoleID,
+		"{nome}":       winner.Name,
+		"{quantidade}": prize.Quantidade,
+	}
+
+	var b strings.Builder
+	var args []interface{}
+	for len(query) > 0 {
+		start := strings.IndexByte(query, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(query[start:], '}')
+		if end < 0 {
+			break
+		}
+		token := query[start : start+end+1]
+		b.WriteString(query[:start])
+		if v, ok := values[token]; ok {
+			b.WriteString("?")
+			args = append(args, v)
+		} else {
+			b.WriteString(token)
+		}
+		query = query[start+end+1:]
+	}
+	b.WriteString(query)
+	return b.String(), args
+}
+
+// rewardCommandInput é o JSON escrito na entrada padrão de RewardCommand
+type rewardCommandInput struct {
+	UserID     int               `json:"userid"`
+	RoleID     int               `json:"roleid"`
+	Nome       string            `json:"nome"`
+	Premio     string            `json:"premio"`
+	Quantidade int               `json:"quantidade"`
+	Item       *Item             `json:"item,omitempty"`
+	Parametros map[string]string `json:"parametros,omitempty"`
+}
+
+// commandDeliverer executa o programa do prêmio com o ganhador e o prêmio em JSON na entrada padrão
+//
+// Observações:
+//
+//	O prêmio é considerado entregue quando o programa termina com código 0, a saída de erro compõe o erro
+//	Um código diferente de 0 indica que o programa não entregou o prêmio, que pode ser reenviado
+//	Um programa encerrado por sinal (OOM killer, kill manual) pode ter entregue o prêmio antes, portanto não é recusa
+type commandDeliverer struct{}
+
+func (commandDeliverer) Validate(prize Sorteio) error {
+	if len(prize.Comando) == 0 || prize.Comando[0] == "" {
+		return errors.New("entrega comando sem o programa")
+	}
+	return nil
+}
+
+func (commandDeliverer) Deliver(ctx context.Context, c *Client, winner UserOnline, prize Sorteio) error {
+	input := rewardCommandInput{
+		UserID:     int(winner.UserID),
+		RoleID:     winner.RoleID.RoleID,
+		Nome:       winner.Name,
+		Premio:     prize.Nome,
+		Quantidade: prize.Quantidade,
+		Parametros: prize.Parametros,
+	}
+	if prize.Item.ID != 0 {
+		input.Item = &prize.Item
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRewardCommandTimeout)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, prize.Comando[0], prize.Comando[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// O código de saída e o programa inexistente garantem que nada foi entregue, o prazo esgotado e o sinal não
+		var exitErr *exec.ExitError
+		var execErr *exec.Error
+		exited := errors.As(err, &exitErr) && exitErr.Exited() && exitErr.ExitCode() > 0
+		if ctx.Err() == nil && (exited || errors.As(err, &execErr)) {
+			err = fmt.Errorf("%w: %w", ErrRewardRejected, err)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("comando %s do prêmio %s: %w: %s", prize.Comando[0], prize.Nome, err, msg)
+		}
+		return fmt.Errorf("comando %s do prêmio %s: %w", prize.Comando[0], prize.Nome, err)
+	}
+	c.debugf("Comando %s do prêmio %s executado para a conta %d", prize.Comando[0], prize.Nome, winner.UserID)
+	return nil
+}
@@ -0,0 +1,152 @@
+package pwapi
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandRewardSQL(t *testing.T) {
+	winner := UserOnline{UserID: 32, RoleID: RoleID{1024}, Name: "Fulano'; DROP TABLE vip; --"}
+	query, args := expandRewardSQL("INSERT INTO vip (userid, roleid, nome, dias) VALUES ({userid}, {roleid}, {nome}, {quantidade}) "+
+		"ON DUPLICATE KEY UPDATE dias = dias + {quantidade}, extra = '{outro}'", winner, Sorteio{Quantidade: 7})
+
+	want := "INSERT INTO vip (userid, roleid, nome, dias) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE dias = dias + ?, extra = '{outro}'"
+	if query != want {
+		t.Errorf("query = %q, esperado %q", query, want)
+	}
+	if wantArgs := []interface{}{32, 1024, winner.Name, 7, 7}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, esperado %v", args, wantArgs)
+	}
+}
+
+func TestRewardSQL(t *testing.T) {
+	var affected int64 = 1
+	fake := &fakeDB{handle: func(query string, args []driver.Value) ([][]driver.Value, int64, error) {
+		return nil, affected, nil
+	}}
+	c, err := NewClient(WithDB(fake.open(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := RewardDelivererFor(RewardSQL)
+	winner := UserOnline{UserID: 32, RoleID: RoleID{1024}, Name: "Fulano"}
+	premio := Sorteio{Tipo: RewardSQL, Nome: "VIP", Quantidade: 7, SQL: "UPDATE vip SET dias = dias + {quantidade} WHERE userid = {userid}"}
+
+	if err := d.Deliver(context.Background(), c, winner, premio); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	stmts := fake.received("UPDATE vip")
+	if len(stmts) != 1 || !reflect.DeepEqual(stmts[0].args, []driver.Value{int64(7), int64(32)}) {
+		t.Errorf("comandos recebidos = %+v", stmts)
+	}
+
+	// Nenhuma linha alterada garante que o prêmio não foi entregue
+	affected = 0
+	if err := d.Deliver(context.Background(), c, winner, premio); !errors.Is(err, ErrRewardRejected) {
+		t.Errorf("Deliver sem linhas alteradas = %v, esperado ErrRewardRejected", err)
+	}
+}
+
+func TestRewardCommand(t *testing.T) {
+	saida := filepath.Join(t.TempDir(), "premio.json")
+	premio, err := PremioConfig{
+		Nome:       "VIP",
+		Entrega:    RewardCommand,
+		Quantidade: 7,
+		Comando:    []string{"sh", "-c", `cat > "$1"`, "sh", saida},
+		Parametros: map[string]string{"plano": "ouro"},
+	}.Sorteio()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	d, _ := RewardDelivererFor(RewardCommand)
+	if err := d.Deliver(context.Background(), c, UserOnline{UserID: 32, RoleID: RoleID{1024}, Name: "Fulano"}, premio); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	data, err := os.ReadFile(saida)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input rewardCommandInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		t.Fatalf("JSON %s: %v", data, err)
+	}
+	want := rewardCommandInput{UserID: 32, RoleID: 1024, Nome: "Fulano", Premio: "VIP", Quantidade: 7,
+		Parametros: map[string]string{"plano": "ouro"}}
+	if !reflect.DeepEqual(input, want) {
+		t.Errorf("JSON = %+v, esperado %+v", input, want)
+	}
+
+	// Um código de saída diferente de zero falha a entrega com a saída de erro
+	premio.Comando = []string{"sh", "-c", "echo conta bloqueada >&2; exit 3"}
+	err = d.Deliver(context.Background(), c, UserOnline{UserID: 32}, premio)
+	if err == nil || !strings.Contains(err.Error(), "conta bloqueada") || !RewardNotDelivered(err) {
+		t.Errorf("Deliver com falha: %v", err)
+	}
+
+	// Um programa encerrado por sinal pode ter entregue o prêmio antes de morrer
+	premio.Comando = []string{"sh", "-c", "kill -KILL $$"}
+	err = d.Deliver(context.Background(), c, UserOnline{UserID: 32}, premio)
+	if err == nil || errors.Is(err, ErrRewardRejected) || RewardNotDelivered(err) {
+		t.Errorf("Deliver encerrado por sinal = %v, esperado um erro sem garantia de não entrega", err)
+	}
+}
+
+func TestRewardNotDelivered(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&RetCodeError{Opcode: OpSysSendMail, RetCode: RetCodeMailboxFull}, true},
+		{fmt.Errorf("prêmio: %w", ErrRewardRejected), true},
+		{ErrCircuitOpen, true},
+		{ErrNoDB, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset")}, false},
+		{fmt.Errorf("%w: prazo esgotado", ErrGoldNotConfirmed), false},
+		{context.DeadlineExceeded, false},
+	} {
+		if got := RewardNotDelivered(tc.err); got != tc.want {
+			t.Errorf("RewardNotDelivered(%v) = %v, esperado %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestPremioConfigValidation(t *testing.T) {
+	for _, p := range []PremioConfig{
+		{Nome: "desconhecido", Entrega: "vip"},
+		{Nome: "sem SQL", Entrega: RewardSQL},
+		{Nome: "sem programa", Entrega: RewardCommand},
+		{Nome: "sem item", Entrega: RewardMailItem},
+		{Nome: "Data inválido", Entrega: RewardMailItem, Item: ItemNome{ID: 7749, Data: "xyz"}},
+		{Nome: "sem quantidade", Entrega: RewardCash},
+	} {
+		if _, err := p.Sorteio(); err == nil {
+			t.Errorf("PremioConfig %s não retornou erro", p.Nome)
+		}
+	}
+
+	premio, err := PremioConfig{Nome: "Oráculo", Entrega: RewardMailItem, Quantidade: 1,
+		Item: ItemNome{ID: 7749, Count: 1, MaxCount: 30, Data: "13080000"}}.Sorteio()
+	if err != nil || premio.Item.ID != 7749 || len(premio.Item.Data) != 4 {
+		t.Errorf("PremioConfig item = %+v, %v", premio, err)
+	}
+}
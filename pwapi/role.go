@@ -0,0 +1,36 @@
+package pwapi
+
+// GRoleInventory é o nome do Item no protocolo, utilizado no inventário, equipamento e armazém de GRoleData
+type GRoleInventory = Item
+
+// Equipped retorna o item equipado com o ID informado
+//
+// Parâmetros:
+//
+//	itemID: int - ID do item no elements.data
+//
+// Retorno:
+//
+//	Item - Item equipado, com Pos indicando o slot do equipamento
+//	bool - false caso o personagem não tenha o item equipado
+func (r GRoleData) Equipped(itemID int) (Item, bool) {
+	for _, item := range r.Equipment.Items {
+		if item.ID == itemID {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+// CountItem retorna a quantidade do item no inventário, no equipamento e no armazém do personagem
+func (r GRoleData) CountItem(itemID int) int {
+	total := 0
+	for _, items := range [][]Item{r.Pocket.Items, r.Equipment.Items, r.Storehouse.Items, r.Storehouse.Dress, r.Storehouse.Material} {
+		for _, item := range items {
+			if item.ID == itemID {
+				total += item.Count
+			}
+		}
+	}
+	return total
+}
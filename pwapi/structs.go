@@ -1,18 +1,73 @@
 package pwapi
 
+import (
+	"encoding/hex"
+	"fmt"
+)
+
 type Config struct {
-	Debug                 bool           `yaml:"Debug"`
-	IP                    string         `yaml:"IP"`
-	Ports                 map[string]int `yaml:"Ports"`
-	MySQL                 MySQLConfig    `yaml:"MySQL"`
-	QuantidadeDeSorteados int            `yaml:"QuantidadeDeSorteados"`
-	GmReceber             bool           `yaml:"GmReceber"`
-	LevelMinimo           int            `yaml:"LevelMinimo"`
-	CultivoMinimo         int            `yaml:"CultivoMinimo"`
-	CanalMensagem         int            `yaml:"CanalMensagem"`
-	Moedas                []int          `yaml:"Moedas"`
-	Golds                 []int          `yaml:"Golds"`
-	ItensSortear          []ItemNome     `yaml:"ItensSortear"`
+	Debug                 bool                     `yaml:"Debug"`
+	IP                    string                   `yaml:"IP"`
+	Ports                 map[string]int           `yaml:"Ports"`
+	MySQL                 MySQLConfig              `yaml:"MySQL"`
+	QuantidadeDeSorteados int                      `yaml:"QuantidadeDeSorteados"`
+	GmReceber             bool                     `yaml:"GmReceber"`
+	LevelMinimo           int                      `yaml:"LevelMinimo"`
+	CultivoMinimo         int                      `yaml:"CultivoMinimo"`
+	AtributosMinimos      AtributosMinimos         `yaml:"AtributosMinimos"`
+	CanalMensagem         int                      `yaml:"CanalMensagem"`
+	Moedas                []int                    `yaml:"Moedas"`
+	Golds                 []int                    `yaml:"Golds"`
+	Gold                  GoldConfig               `yaml:"Gold"`
+	ItensSortear          []ItemNome               `yaml:"ItensSortear"`
+	Premios               []PremioConfig           `yaml:"Premios"`
+	Conexoes              PoolConfig               `yaml:"Conexoes"`
+	Timeouts              map[string]TimeoutConfig `yaml:"Timeouts"`
+	Tentativas            RetryConfig              `yaml:"Tentativas"`
+	Circuito              BreakerConfig            `yaml:"Circuito"`
+	Captura               string                   `yaml:"Captura"`
+	Entregas              string                   `yaml:"Entregas"`
+	VersaoServidor        string                   `yaml:"VersaoServidor"`
+}
+
+// AtributosMinimos são os atributos de RoleProperty exigidos para participar do sorteio, 0 desativa cada filtro
+type AtributosMinimos struct {
+	HP          int `yaml:"HP"`          // RoleProperty.MaxHP
+	MP          int `yaml:"MP"`          // RoleProperty.MaxMP
+	Defesa      int `yaml:"Defesa"`      // RoleProperty.Defense
+	NivelAtaque int `yaml:"NivelAtaque"` // RoleProperty.AttackDegree
+	NivelDefesa int `yaml:"NivelDefesa"` // RoleProperty.DefendDegree
+	Espirito    int `yaml:"Espirito"`    // RoleProperty.Vigour
+}
+
+// Ativo indica se algum filtro de atributo está configurado
+func (a AtributosMinimos) Ativo() bool {
+	return a != AtributosMinimos{}
+}
+
+// Verificar retorna o primeiro atributo de p abaixo do mínimo configurado
+//
+// Retorno:
+//
+//	string - Nome do atributo abaixo do mínimo, vazio quando p atende a todos os filtros
+func (a AtributosMinimos) Verificar(p RoleProperty) string {
+	filtros := []struct {
+		nome          string
+		valor, minimo int
+	}{
+		{"HP", p.MaxHP, a.HP},
+		{"MP", p.MaxMP, a.MP},
+		{"defesa", p.Defense, a.Defesa},
+		{"nível de ataque", p.AttackDegree, a.NivelAtaque},
+		{"nível de defesa", p.DefendDegree, a.NivelDefesa},
+		{"espírito", p.Vigour, a.Espirito},
+	}
+	for _, f := range filtros {
+		if f.minimo > 0 && f.valor < f.minimo {
+			return f.nome
+		}
+	}
+	return ""
 }
 
 type MySQLConfig struct {
@@ -22,11 +77,69 @@ type MySQLConfig struct {
 	DB      string `yaml:"DB"`
 }
 
+// Sorteio é um prêmio que pode ser sorteado
+//
+// Observações:
+//
+//	Tipo é o nome do RewardDeliverer que entrega o prêmio, os demais campos são os parâmetros da entrega
 type Sorteio struct {
 	Tipo       string
 	Quantidade int
 	Nome       string
 	Item       Item
+	Titulo     string            // título do e-mail, vazio usa DefaultRewardMailTitle
+	Conteudo   string            // conteúdo do e-mail, vazio usa um texto com o nome do prêmio
+	SQL        string            // comando de RewardSQL
+	Comando    []string          // programa e argumentos de RewardCommand
+	Parametros map[string]string // parâmetros livres, repassados a RewardCommand e aos entregadores registrados
+}
+
+// PremioConfig é um prêmio de Config.Premios, entregue pelo RewardDeliverer informado em Entrega
+type PremioConfig struct {
+	Nome       string            `yaml:"Nome"`
+	Entrega    string            `yaml:"Entrega"` // item, moedas, gold, sql, comando ou um entregador registrado
+	Quantidade int               `yaml:"Quantidade"`
+	Item       ItemNome          `yaml:"Item"`
+	Titulo     string            `yaml:"Titulo"`
+	Conteudo   string            `yaml:"Conteudo"`
+	SQL        string            `yaml:"SQL"`
+	Comando    []string          `yaml:"Comando"`
+	Parametros map[string]string `yaml:"Parametros"`
+}
+
+// Sorteio converte o prêmio configurado, verificando o entregador e os seus parâmetros
+//
+// Retorno:
+//
+//	Sorteio - Prêmio pronto para ser sorteado
+//	error - Retorna um erro caso o entregador não exista, os parâmetros sejam inválidos ou o Data do item não seja hexadecimal
+func (p PremioConfig) Sorteio() (Sorteio, error) {
+	premio := Sorteio{
+		Tipo:       p.Entrega,
+		Quantidade: p.Quantidade,
+		Nome:       p.Nome,
+		Titulo:     p.Titulo,
+		Conteudo:   p.Conteudo,
+		SQL:        p.SQL,
+		Comando:    p.Comando,
+		Parametros: p.Parametros,
+	}
+	if p.Item.ID != 0 {
+		item, err := p.Item.Item()
+		if err != nil {
+			return premio, fmt.Errorf("Erro no Data do item %s: %w", p.Nome, err)
+		}
+		premio.Item = item
+	}
+
+	d, ok := RewardDelivererFor(p.Entrega)
+	if !ok {
+		return premio, fmt.Errorf("pwapi: entrega %q do prêmio %s desconhecida", p.Entrega, p.Nome)
+	}
+	if err := d.Validate(premio); err != nil {
+		return premio, fmt.Errorf("pwapi: prêmio %s: %w", p.Nome, err)
+	}
+	return premio, nil
 }
 
 type ItemNome struct {
@@ -43,150 +156,24 @@ type ItemNome struct {
 	Mask       int    `yaml:"Mask"`
 }
 
-type Item struct {
-	ID         int
-	Pos        int
-	Count      int
-	MaxCount   int
-	Data       []byte
-	ProcType   int
-	ExpireDate int
-	GUID1      int
-	GUID2      int
-	Mask       int
-}
-
-type UserOnline struct {
-	UserID   UserID
-	RoleID   RoleID
-	LinkID   int
-	LocalSID int
-	GSID     int
-	Status   byte
-	Name     string `pw:"string"`
-}
-
-type RoleStatus struct {
-	Sversion         byte
-	Level            int
-	Level2           int
-	Exp              int
-	Sp               int
-	Pp               int
-	Hp               int
-	Mp               int
-	Posx             float32
-	Posy             float32
-	Posz             float32
-	Worldtag         int
-	InvaderState     int
-	InvaderTime      int
-	PariahTime       int
-	Reputation       int
-	CustomStatus     []byte
-	FilterData       []byte
-	Charactermode    []byte
-	Instancekeylist  []byte
-	DbltimeExpire    int
-	DbltimeMode      int
-	DbltimeBegin     int
-	DbltimeUsed      int
-	DbltimeMax       int
-	TimeUsed         int
-	DbltimeData      []byte
-	Storesize        uint16
-	Petcorral        []byte
-	Property         []byte
-	VarData          []byte
-	Skills           []byte
-	Storehousepasswd []byte
-	Waypointlist     []byte
-	Coolingtime      []byte
-	Reserved1        uint
-	Reserved2        int
-	Reserved3        int
-	Reserved4        int
-}
-
-type GetRoleBaseArg struct {
-	Handler int
-	RoleID  RoleID
-}
-
-type GRoleForbid struct {
-	Type       byte
-	Time       int
-	CreateTime int
-	Reason     string `pw:"string"`
-}
-
-type RoleBase struct {
-	Version       byte
-	ID            int
-	Name          string `pw:"string"`
-	Race          int
-	CLS           int
-	Gender        byte
-	CustomData    []byte
-	ConfigData    []byte
-	CustomStamp   int
-	Status        byte
-	DeleteTime    int
-	CreateTime    int
-	LastLoginTime int
-	ForbidSize    Cuint
-	Forbid        []GRoleForbid
-	HelpStates    []byte
-	Spouse        int
-	UserID        UserID
-	CrossData     []byte
-	Reserved2     byte
-	Reserved3     byte
-	Reserved4     byte
-}
-
-type ChatBroadCast struct {
-	Channel   byte
-	Emotion   byte
-	SrcRoleID int
-	Msg       string
-	Data      []byte
-}
-
-type UserID int
-type RoleID struct {
-	RoleID int
-}
-
-type GMQueryOnline struct {
-	QType int
-}
-type GMQueryOnlineRe struct {
-	Header     int
-	QType      int
-	UsersCount Cuint
-	RoleIDS    []RoleID
-}
-
-type GetRoleStatusArg struct {
-	Handler int
-	RoleID  RoleID
-}
-
-type DebugAddCash struct {
-	UserID UserID
-	Cash   int
-}
-
-type SysSendMail struct {
-	TID         int
-	SysID       int
-	SysType     byte
-	Receiver    RoleID
-	Title       string
-	Content     string
-	AttachObj   Item
-	AttachMoney int
+// Item converte o item configurado, com o Data em hexadecimal, no item do protocolo
+func (i ItemNome) Item() (Item, error) {
+	data, err := hex.DecodeString(i.Data)
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{
+		ID:         i.ID,
+		Pos:        i.Pos,
+		Count:      i.Count,
+		MaxCount:   i.MaxCount,
+		Data:       data,
+		ProcType:   i.ProcType,
+		ExpireDate: i.ExpireDate,
+		GUID1:      i.GUID1,
+		GUID2:      i.GUID2,
+		Mask:       i.Mask,
+	}, nil
 }
 
 type TestAPI struct {
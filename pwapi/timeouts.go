@@ -0,0 +1,78 @@
+package pwapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// Timeouts padrão aplicados quando não informados em Config.Timeouts
+const (
+	DefaultDialTimeout  = 5 * time.Second
+	DefaultWriteTimeout = 5 * time.Second
+	DefaultReadTimeout  = DefaultCallTimeout
+)
+
+// TimeoutConfig define os prazos de cada etapa da comunicação com um serviço
+//
+// Observações:
+//
+//	Os prazos são configurados por serviço em Config.Timeouts (ou WithTimeouts), com as mesmas chaves de Config.Ports
+//	Quando o contexto da chamada possui um prazo menor, o prazo do contexto prevalece
+type TimeoutConfig struct {
+	Conexao time.Duration `yaml:"Conexao"` // prazo para abrir a conexão
+	Escrita time.Duration `yaml:"Escrita"` // prazo para enviar o pacote
+	Leitura time.Duration `yaml:"Leitura"` // prazo para receber a resposta
+}
+
+// timeoutsFor retorna os prazos configurados para o serviço, completando com os valores padrão
+func (c *Client) timeoutsFor(backend string) TimeoutConfig {
+	t := c.timeouts[backend]
+	if t.Conexao <= 0 {
+		t.Conexao = DefaultDialTimeout
+	}
+	if t.Escrita <= 0 {
+		t.Escrita = DefaultWriteTimeout
+	}
+	if t.Leitura <= 0 {
+		t.Leitura = DefaultReadTimeout
+	}
+	return t
+}
+
+// deadlineFor retorna o menor prazo entre o do contexto e agora + timeout
+func deadlineFor(ctx context.Context, timeout time.Duration) time.Time {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// watchContext interrompe as operações pendentes em conn quando ctx for cancelado
+//
+// Retorno:
+//
+//	func() bool - Deve ser chamada ao final da operação para parar a observação
+func watchContext(ctx context.Context, conn net.Conn) func() bool {
+	return context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+}
+
+// contextErr retorna o erro do contexto caso ele tenha sido cancelado ou expirado, senão err
+//
+// Observações:
+//
+//	Quando o prazo do contexto é o menor, ele também é o prazo do socket, que pode expirar antes de ctx.Done
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if d, ok := ctx.Deadline(); ok && errors.Is(err, os.ErrDeadlineExceeded) && !time.Now().Before(d) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package pwapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// stuckClient retorna um cliente cujo serviço aceita a conexão e nunca responde
+//
+// Observações:
+//
+//	Com discard o serviço lê e descarta as requisições, sem ele nada é lido e as escritas grandes bloqueiam
+func stuckClient(t *testing.T, backend string, timeouts TimeoutConfig, discard bool) *Client {
+	t.Helper()
+	stop := make(chan struct{})
+	addr := fakeBackend(t, func(conn net.Conn) {
+		if discard {
+			io.Copy(io.Discard, conn)
+			return
+		}
+		<-stop
+	})
+	// Executado antes da limpeza de fakeBackend, que aguarda os handlers terminarem
+	t.Cleanup(func() { close(stop) })
+	_, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+
+	c, err := NewClient(WithPort(backend, p), WithTimeouts(backend, timeouts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// within falha o teste se fn demorar mais que limit
+func within(t *testing.T, limit time.Duration, fn func()) {
+	t.Helper()
+	start := time.Now()
+	fn()
+	if elapsed := time.Since(start); elapsed > limit {
+		t.Errorf("a chamada retornou após %v, esperado até %v", elapsed, limit)
+	}
+}
+
+// cancelAfter retorna um contexto cancelado após d
+func cancelAfter(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := time.AfterFunc(d, cancel)
+	t.Cleanup(func() {
+		timer.Stop()
+		cancel()
+	})
+	return ctx
+}
+
+func TestTimeoutReadPooled(t *testing.T) {
+	c := stuckClient(t, "gdeliveryd", TimeoutConfig{Leitura: 100 * time.Millisecond}, true)
+	req := EncodeFrame(opQueryOnline, make([]byte, 8))
+
+	within(t, time.Second, func() {
+		_, err := c.SendToDelivery(context.Background(), req, opQueryOnlineRe, false)
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("SendToDelivery com Leitura = %v, esperado os.ErrDeadlineExceeded", err)
+		}
+	})
+
+	within(t, time.Second, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := c.SendToDelivery(ctx, req, opQueryOnlineRe, false)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("SendToDelivery com prazo = %v, esperado context.DeadlineExceeded", err)
+		}
+	})
+
+	within(t, time.Second, func() {
+		_, err := c.SendToDelivery(cancelAfter(t, 50*time.Millisecond), req, opQueryOnlineRe, false)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("SendToDelivery cancelado = %v, esperado context.Canceled", err)
+		}
+	})
+}
+
+func TestTimeoutWritePooled(t *testing.T) {
+	c := stuckClient(t, "gdeliveryd", TimeoutConfig{Escrita: 100 * time.Millisecond, Leitura: time.Minute}, false)
+
+	// Sem leitura do serviço os buffers do socket enchem e a escrita bloqueia
+	req := EncodeFrame(opQueryOnline, make([]byte, 32<<20))
+	within(t, 2*time.Second, func() {
+		_, err := c.SendToDelivery(context.Background(), req, opQueryOnlineRe, false)
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("SendToDelivery com Escrita = %v, esperado os.ErrDeadlineExceeded", err)
+		}
+	})
+
+	c.timeouts["gdeliveryd"] = TimeoutConfig{Escrita: time.Minute, Leitura: time.Minute}
+	within(t, 2*time.Second, func() {
+		_, err := c.SendToDelivery(cancelAfter(t, 50*time.Millisecond), req, opQueryOnlineRe, false)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("SendToDelivery cancelado na escrita = %v, esperado context.Canceled", err)
+		}
+	})
+}
+
+func TestTimeoutDialPooled(t *testing.T) {
+	c, err := NewClient(WithTimeouts("gdeliveryd", TimeoutConfig{Conexao: 100 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// A conexão só termina com o prazo ou o cancelamento do contexto recebido pelo pool
+	pool := c.poolFor(c.addr("gdeliveryd"))
+	pool.dial = func(ctx context.Context, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	req := EncodeFrame(opQueryOnline, make([]byte, 8))
+
+	within(t, time.Second, func() {
+		_, err := c.SendToDelivery(context.Background(), req, opQueryOnlineRe, false)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("SendToDelivery com Conexao = %v, esperado context.DeadlineExceeded", err)
+		}
+	})
+
+	c.timeouts["gdeliveryd"] = TimeoutConfig{Conexao: time.Minute}
+	within(t, time.Second, func() {
+		_, err := c.SendToDelivery(cancelAfter(t, 50*time.Millisecond), req, opQueryOnlineRe, false)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("SendToDelivery cancelado na conexão = %v, esperado context.Canceled", err)
+		}
+	})
+}
+
+func TestTimeoutMux(t *testing.T) {
+	c := stuckClient(t, "gamedbd", TimeoutConfig{Leitura: 100 * time.Millisecond}, true)
+	payload := make([]byte, 8)
+
+	within(t, time.Second, func() {
+		_, err := c.CallGamedBD(context.Background(), opGetRoleBase, payload)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("CallGamedBD com Leitura = %v, esperado context.DeadlineExceeded", err)
+		}
+	})
+
+	within(t, time.Second, func() {
+		_, err := c.CallGamedBD(cancelAfter(t, 50*time.Millisecond), opGetRoleBase, payload)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("CallGamedBD cancelado = %v, esperado context.Canceled", err)
+		}
+	})
+
+	// O prazo de uma chamada não derruba a conexão multiplexada das demais
+	if m, err := c.muxFor(context.Background(), "gamedbd"); err != nil || m.Closed() {
+		t.Errorf("muxFor após os prazos = %v, %v", m, err)
+	}
+}
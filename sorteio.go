@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"pwapi/pwapi"
+)
+
+// sorteio reúne o que é necessário para realizar um sorteio
+//
+// Observações:
+//
+//	O sorteio não depende de variáveis globais, de modo que pode ser executado contra o servidor falso do pacote pwtest
+type sorteio struct {
+	client *pwapi.Client  // servidor onde o sorteio é realizado
+	cfg    pwapi.Config   // critérios e prêmios do sorteio
+	rng    *rand.Rand     // fonte dos números aleatórios
+	log    *log.Logger    // registro dos prêmios entregues
+	falhas []falhaEntrega // prêmios sorteados que não foram entregues
+	diario *diario        // diário de entregas, nil na reprodução de capturas
+}
+
+// falhaEntrega é um prêmio sorteado que o servidor recusou ou que não pôde ser enviado
+type falhaEntrega struct {
+	ganhador pwapi.UserOnline
+	premio   pwapi.Sorteio
+	err      error
+}
+
+// executar realiza a quantidade de sorteios definida em cfg.QuantidadeDeSorteados
+//
+// Parâmetros:
+//
+//	ctx: context.Context - Contexto das chamadas aos serviços do servidor
+//
+// Retorno:
+//
+//	error - Retorna um erro caso a comunicação com o servidor falhe ou a configuração seja inválida
+//
+// Observações:
+//
+//	Servidor offline e falta de usuários elegíveis não são erros, apenas encerram o sorteio
+//	Falhas na entrega de um prêmio são registradas no log e em s.falhas, o ganhador não é anunciado e o sorteio
+//	continua com o próximo ganhador, exceto
+//	quando o circuito do serviço abriu (pwapi.ErrCircuitOpen) ou o diário de entregas falhou, o que interrompe o
+//	sorteio com o erro
+//	Antes do sorteio os prêmios pendentes ou recusados de execuções anteriores são reenviados, veja reenviar
+//	Uma falha na busca dos dados de um personagem interrompe o sorteio, nenhum prêmio é entregue sem eles
+func (s *sorteio) executar(ctx context.Context) error {
+
+	// Verifica se o servidor está online
+	if err := s.client.Ping(ctx); err != nil {
+		fmt.Println("Servidor offline")
+		return nil
+	}
+
+	// Reenvia os prêmios de sorteios anteriores que não foram entregues
+	if _, err := s.reenviar(ctx, true); err != nil {
+		return fmt.Errorf("Sorteio interrompido: %w", err)
+	}
+
+	// Busca a lista de usuários online com a conta e o nome de cada personagem
+	onlineList, err := s.client.OnlineUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("Erro ao buscar a lista de usuários online: %w", err)
+	}
+
+	// Verifica se existem usuários online
+	if len(onlineList) == 0 {
+		fmt.Println("nenhum usuário online")
+		return nil
+	}
+
+	// Monta a lista de prêmios antes do sorteio para que uma configuração inválida não deixe um ganhador sem prêmio
+	premios, err := s.premios()
+	if err != nil {
+		return err
+	}
+	if len(premios) == 0 {
+		return fmt.Errorf("nenhum prêmio configurado")
+	}
+
+	// Exibe a quantidade de usuários online caso o modo debug esteja ativado
+	if s.cfg.Debug {
+		fmt.Printf("Total de usuários online: %d\n", len(onlineList))
+		fmt.Printf("Quantidade de usuários a sortear: %d\n", s.cfg.QuantidadeDeSorteados)
+	}
+
+	// Realiza o sorteio da quantidade de usuários definida no arquivo de configuração
+	for i := 0; i < s.cfg.QuantidadeDeSorteados; i++ {
+
+		// Exibe o número do sorteio caso o modo debug esteja ativado
+		if s.cfg.Debug {
+			fmt.Printf("\nSorteando usuário %d\n", i+1)
+		}
+
+		var ganhador pwapi.UserOnline
+		var ok bool
+		ganhador, onlineList, ok, err = s.sortearUsuario(ctx, onlineList)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Nenhum usuário restante")
+			return nil
+		}
+
+		// Sorteia um item da lista de prêmios
+		Sorteado := premios[s.rng.Intn(len(premios))]
+
+		// Exibe o item sorteado caso o modo debug esteja ativado
+		if s.cfg.Debug {
+			fmt.Println("Item sorteado:", Sorteado)
+		}
+
+		err := s.entregar(ctx, ganhador, Sorteado)
+		if err == nil {
+			continue
+		}
+		s.falhas = append(s.falhas, falhaEntrega{ganhador: ganhador, premio: Sorteado, err: err})
+
+		// Com o serviço fora do ar os próximos ganhadores também ficariam sem prêmio, e sem o diário não é
+		// possível garantir que cada prêmio seja entregue uma única vez
+		if errors.Is(err, pwapi.ErrCircuitOpen) || errors.Is(err, errDiario) {
+			return fmt.Errorf("Sorteio interrompido: %w", err)
+		}
+	}
+
+	if len(s.falhas) > 0 {
+		fmt.Printf("%d prêmios não foram entregues, veja o log\n", len(s.falhas))
+	}
+	return nil
+}
+
+// sortearUsuario sorteia um usuário aleatório que atenda aos critérios de level, cultivo, atributos e GM
+//
+// Parâmetros:
+//
+//	ctx: context.Context - Contexto das chamadas aos serviços do servidor
+//	onlineList: []pwapi.UserOnline - Usuários que ainda podem ser sorteados
+//
+// Retorno:
+//
+//	pwapi.UserOnline - Usuário sorteado, com a conta e o nome do personagem
+//	[]pwapi.UserOnline - Lista sem o usuário sorteado e sem os usuários descartados
+//	bool - false caso nenhum usuário restante atenda aos critérios
+//	error - Retorna um erro caso a comunicação com o servidor falhe
+//
+// Observações:
+//
+//	Caso o usuário não atenda aos critérios, ele é removido da lista de usuários online e um novo usuário é sorteado
+//	até que um usuário válido seja encontrado
+//	Personagens com o Property malformado também são descartados, registrando o motivo no log
+func (s *sorteio) sortearUsuario(ctx context.Context, onlineList []pwapi.UserOnline) (pwapi.UserOnline, []pwapi.UserOnline, bool, error) {
+	for len(onlineList) > 0 {
+
+		// Seleciona um usuário aleatório
+		key := s.rng.Intn(len(onlineList))
+		user := onlineList[key]
+		roleID := user.RoleID
+
+		// Remove o usuário da lista, seja ele sorteado ou descartado
+		onlineList = removeUser(onlineList, key)
+
+		// Uma entrada vazia na lista não pode resultar em um prêmio enviado ao personagem 0
+		if roleID.RoleID <= 0 || user.UserID <= 0 {
+			return user, onlineList, false, fmt.Errorf("Erro na lista de usuários online: personagem %d da conta %d", roleID.RoleID, user.UserID)
+		}
+
+		// Busca o level, o cultivo e, quando filtrados, os atributos do personagem (role)
+		role, atributos, err := s.status(ctx, roleID)
+		if errors.Is(err, errAtributos) {
+			// Sem os atributos os filtros não podem ser avaliados, apenas este personagem é descartado
+			fmt.Printf("Personagem descartado: %v\n", err)
+			s.log.Printf("Personagem %d da conta %d descartado do sorteio: %v", roleID.RoleID, user.UserID, err)
+			continue
+		}
+		if err != nil {
+			return user, onlineList, false, err
+		}
+		if s.cfg.Debug {
+			fmt.Printf("Usuário sorteado: %v\n", roleID)
+		}
+
+		// Verifica se o personagem possui o level mínimo
+		if role.Level < s.cfg.LevelMinimo {
+			if s.cfg.Debug {
+				fmt.Print("Personagem não possui o level mínimo\n\n")
+			}
+			continue
+		}
+
+		// Verifica se o personagem possui o cultivo mínimo
+		if role.Level2 < s.cfg.CultivoMinimo {
+			if s.cfg.Debug {
+				fmt.Print("Personagem não possui o cultivo mínimo\n\n")
+			}
+			continue
+		}
+
+		// Verifica se o personagem possui os atributos mínimos
+		if abaixo := s.cfg.AtributosMinimos.Verificar(atributos); abaixo != "" {
+			if s.cfg.Debug {
+				fmt.Printf("Personagem não possui o %s mínimo\n\n", abaixo)
+			}
+			continue
+		}
+
+		// Verifica se o usuário é um gm, a consulta ao banco só é necessária quando GMs não podem receber
+		if !s.cfg.GmReceber {
+			ehGm, err := s.client.IsGM(ctx, user.UserID)
+			if err != nil {
+				return user, onlineList, false, fmt.Errorf("Erro ao consultar o banco de dados: %w", err)
+			}
+			if ehGm {
+				if s.cfg.Debug {
+					fmt.Print("Usuário é um GM\n\n")
+				}
+				continue
+			}
+		}
+
+		return user, onlineList, true, nil
+	}
+
+	return pwapi.UserOnline{}, onlineList, false, nil
+}
+
+// errAtributos indica um Property que não pode ser decodificado, o personagem é descartado sem interromper o sorteio
+var errAtributos = errors.New("atributos inválidos")
+
+// status busca o level e o cultivo do personagem
+//
+// Retorno:
+//
+//	pwapi.RoleStatusLevel - Versão, level e cultivo do personagem
+//	pwapi.RoleProperty - Atributos do personagem, preenchidos apenas quando cfg.AtributosMinimos está ativo
+//	error - Retorna um erro caso a comunicação falhe, ou errAtributos caso o Property esteja malformado
+//
+// Observações:
+//
+//	Sem filtros de atributos apenas o início do RoleStatus é lido, veja pwapi.GetRoleLevel
+func (s *sorteio) status(ctx context.Context, roleID pwapi.RoleID) (pwapi.RoleStatusLevel, pwapi.RoleProperty, error) {
+	if !s.cfg.AtributosMinimos.Ativo() {
+		level, err := s.client.RoleLevel(ctx, roleID)
+		if err != nil {
+			return level, pwapi.RoleProperty{}, fmt.Errorf("Erro ao buscar o status do personagem %v: %w", roleID, err)
+		}
+		return level, pwapi.RoleProperty{}, nil
+	}
+
+	status, err := s.client.RoleStatus(ctx, roleID)
+	if err != nil {
+		return pwapi.RoleStatusLevel{}, pwapi.RoleProperty{}, fmt.Errorf("Erro ao buscar o status do personagem %v: %w", roleID, err)
+	}
+	level := pwapi.RoleStatusLevel{Sversion: status.Sversion, Level: status.Level, Level2: status.Level2}
+	atributos, err := status.DecodeProperty()
+	if err != nil {
+		return level, atributos, fmt.Errorf("%w do personagem %v: %w", errAtributos, roleID, err)
+	}
+	return level, atributos, nil
+}
+
+// premios monta a lista de prêmios a partir das moedas, golds, itens e prêmios configurados
+func (s *sorteio) premios() ([]pwapi.Sorteio, error) {
+
+	// Cria um slice de Sorteio com as moedas e golds a serem sorteados
+	var Sorteio []pwapi.Sorteio
+
+	// Adiciona as moedas ao sorteio
+	for _, moeda := range s.cfg.Moedas {
+		Sorteio = append(Sorteio, pwapi.Sorteio{
+			Tipo:       pwapi.RewardMailMoney,
+			Nome:       "Moedas",
+			Quantidade: moeda,
+		})
+	}
+
+	// Adiciona os golds ao sorteio, a entrega precisa ser conhecida antes de existir um ganhador
+	if len(s.cfg.Golds) > 0 {
+		if err := s.cfg.Gold.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, gold := range s.cfg.Golds {
+		Sorteio = append(Sorteio, pwapi.Sorteio{
+			Tipo:       pwapi.RewardCash,
+			Nome:       "Golds",
+			Quantidade: gold,
+		})
+	}
+
+	// Adiciona os itens ao sorteio
+	for _, item := range s.cfg.ItensSortear {
+		// Converte o Data do item de hexadecimal para []byte
+		Item, err := item.Item()
+		if err != nil {
+			return nil, fmt.Errorf("Erro no Data do item %s: %w", item.Nome, err)
+		}
+
+		Sorteio = append(Sorteio, pwapi.Sorteio{
+			Tipo:       pwapi.RewardMailItem,
+			Nome:       item.Nome,
+			Quantidade: item.Count,
+			Item:       Item,
+		})
+	}
+
+	// Adiciona os prêmios com entrega configurada, como SQL e comandos externos
+	for _, premio := range s.cfg.Premios {
+		sorteado, err := premio.Sorteio()
+		if err != nil {
+			return nil, err
+		}
+		if sorteado.Tipo == pwapi.RewardCash {
+			if err := s.cfg.Gold.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		Sorteio = append(Sorteio, sorteado)
+	}
+
+	return Sorteio, nil
+}
+
+// entregar envia o prêmio ao personagem sorteado pelo entregador do prêmio e anuncia o ganhador no chat do jogo
+//
+// Retorno:
+//
+//	error - Erro na entrega do prêmio, já registrado no log, falhas no anúncio apenas são exibidas
+func (s *sorteio) entregar(ctx context.Context, ganhador pwapi.UserOnline, Sorteado pwapi.Sorteio) error {
+
+	// Remove os caracteres indesejados do nome do personagem
+	roleName := removerCaracteresIndesejados(ganhador.Name)
+
+	// prepara a mensagem para exibir no chat do jogo e no log, prêmios sem quantidade exibem apenas o nome
+	mensagem := fmt.Sprintf("^ffffffO jogador &%s& acabou de ganhar ^33cc33 %d %s", roleName, Sorteado.Quantidade, Sorteado.Nome)
+	if Sorteado.Quantidade <= 0 {
+		mensagem = fmt.Sprintf("^ffffffO jogador &%s& acabou de ganhar ^33cc33 %s", roleName, Sorteado.Nome)
+	}
+
+	// Registra o prêmio no diário antes do envio e o entrega com o entregador validado ao montar a lista de prêmios
+	id, err := s.diario.novo(ganhador, Sorteado)
+	if err == nil {
+		err = s.enviar(ctx, id, ganhador, Sorteado)
+	}
+
+	// Verifica se o prêmio foi entregue, um e-mail recusado pelo gdeliveryd não é anunciado
+	if err != nil {
+		fmt.Printf("Erro ao entregar o prêmio: %v\n", err)
+		s.log.Printf("Prêmio não entregue: %d %s para %s (personagem %d, conta %d): %s", Sorteado.Quantidade, Sorteado.Nome,
+			roleName, ganhador.RoleID.RoleID, ganhador.UserID, motivoFalha(err))
+		return err
+	}
+
+	// Exibe a mensagem no chat do jogo
+	if err := s.client.Broadcast(ctx, s.cfg.CanalMensagem, mensagem); err != nil {
+		fmt.Printf("Erro ao enviar a mensagem: %v\n", err)
+	}
+	s.log.Println(mensagem)
+	return nil
+}
+
+// enviar entrega um prêmio registrado no diário, gravando o envio antes e o resultado depois
+//
+// Retorno:
+//
+//	error - Erro na entrega ou no diário, o prêmio não é enviado quando o registro do envio falha
+//
+// Observações:
+//
+//	Apenas erros que garantem a não entrega (pwapi.RewardNotDelivered) marcam o prêmio como falhou, os demais o
+//	mantêm como enviado para que ele não seja reenviado automaticamente
+func (s *sorteio) enviar(ctx context.Context, id string, ganhador pwapi.UserOnline, premio pwapi.Sorteio) error {
+	entregador, ok := pwapi.RewardDelivererFor(premio.Tipo)
+	if !ok {
+		err := fmt.Errorf("%w: entrega %q desconhecida", pwapi.ErrRewardRejected, premio.Tipo)
+		if jerr := s.diario.registrar(id, entregaFalhou, err); jerr != nil {
+			return jerr
+		}
+		return err
+	}
+
+	if err := s.diario.registrar(id, entregaEnviada, nil); err != nil {
+		return err
+	}
+	err := entregador.Deliver(ctx, s.client, ganhador, premio)
+
+	estado := entregaConfirmada
+	switch {
+	case pwapi.RewardNotDelivered(err):
+		estado = entregaFalhou
+	case err != nil:
+		estado = entregaEnviada
+	}
+	if jerr := s.diario.registrar(id, estado, err); jerr != nil {
+		fmt.Printf("Erro ao registrar a entrega %s como %s: %v\n", id, estado, jerr)
+		s.log.Printf("Entrega %s %s, mas o diário não foi atualizado: %v", id, estado, jerr)
+	}
+	return err
+}
+
+// reenviar entrega novamente os prêmios do diário que não foram entregues (pendente e falhou)
+//
+// Parâmetros:
+//
+//	ctx: context.Context - Contexto das chamadas aos serviços do servidor
+//	automatico: bool - true no início do sorteio, quando prêmios com reenviosAutomaticos tentativas são ignorados
+//
+// Retorno:
+//
+//	int - Quantidade de prêmios entregues
+//	error - Circuito aberto ou erro no diário, os demais erros são registrados no log e o próximo prêmio é reenviado
+//
+// Observações:
+//
+//	Prêmios enviados sem confirmação nunca são reenviados, eles podem ter sido entregues. Após verificá-los no
+//	servidor, marque-os com o subcomando deliveries mark
+func (s *sorteio) reenviar(ctx context.Context, automatico bool) (int, error) {
+	entregues := 0
+	for _, e := range s.diario.lista(entregaPendente, entregaFalhou) {
+		if automatico && e.Tentativas >= reenviosAutomaticos {
+			continue
+		}
+
+		err := s.enviar(ctx, e.ID, e.Ganhador, e.Premio)
+		if err == nil {
+			entregues++
+			s.log.Printf("Prêmio reenviado: %d %s para %s (personagem %d, conta %d), entrega %s", e.Premio.Quantidade,
+				e.Premio.Nome, e.Ganhador.Name, e.Ganhador.RoleID.RoleID, e.Ganhador.UserID, e.ID)
+			continue
+		}
+
+		fmt.Printf("Erro ao reenviar a entrega %s: %v\n", e.ID, err)
+		s.log.Printf("Prêmio não reenviado: entrega %s para %s (personagem %d, conta %d): %s", e.ID, e.Ganhador.Name,
+			e.Ganhador.RoleID.RoleID, e.Ganhador.UserID, motivoFalha(err))
+		if errors.Is(err, pwapi.ErrCircuitOpen) || errors.Is(err, errDiario) {
+			return entregues, err
+		}
+	}
+	return entregues, nil
+}
+
+// motivoFalha descreve o erro de entrega no log, com o motivo da recusa quando o código de retorno é conhecido
+func motivoFalha(err error) string {
+	switch {
+	case errors.Is(err, pwapi.ErrMailboxFull):
+		return fmt.Sprintf("caixa de correio cheia (%v)", err)
+	case errors.Is(err, pwapi.ErrInvalidAttachment):
+		return fmt.Sprintf("item inválido (%v)", err)
+	case errors.Is(err, pwapi.ErrNotFound):
+		return fmt.Sprintf("personagem não encontrado (%v)", err)
+	case errors.Is(err, pwapi.ErrGoldNotConfirmed):
+		return fmt.Sprintf("gold enviado sem confirmação, verifique a conta antes de reenviar (%v)", err)
+	}
+	return err.Error()
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pwapi/pwapi"
+	"pwapi/pwapi/pwtest"
+)
+
+func TestSorteioEntregaSomenteAElegiveis(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Baixo", UserID: 32}, pwapi.RoleStatus{Level: 10, Level2: 22})
+	srv.AddRole(pwapi.RoleBase{ID: 2048, Name: "Sem&Cultivo", UserID: 48}, pwapi.RoleStatus{Level: 105, Level2: 0})
+	srv.AddRole(pwapi.RoleBase{ID: 4096, Name: "Gan&hador", UserID: 64}, pwapi.RoleStatus{Level: 105, Level2: 22})
+
+	s := sorteio{
+		client: client,
+		cfg: pwapi.Config{
+			QuantidadeDeSorteados: 2,
+			GmReceber:             true,
+			LevelMinimo:           100,
+			CultivoMinimo:         20,
+			CanalMensagem:         9,
+			Moedas:                []int{500},
+		},
+		rng: rand.New(rand.NewSource(1)),
+		log: log.New(io.Discard, "", 0),
+	}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("executar: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Wait(ctx, func() bool { return len(srv.Mails()) >= 1 && len(srv.Broadcasts()) >= 1 }); err != nil {
+		t.Fatalf("prêmio não recebido: %v", err)
+	}
+
+	// Apenas um personagem é elegível, o segundo sorteio termina sem ganhador
+	mails := srv.Mails()
+	if len(mails) != 1 || mails[0].Receiver.RoleID != 4096 || mails[0].AttachMoney != 500 {
+		t.Fatalf("e-mails = %+v, esperado 500 moedas para 4096", mails)
+	}
+	if len(srv.CashAdds()) != 0 {
+		t.Errorf("cash inesperado: %+v", srv.CashAdds())
+	}
+
+	chats := srv.Broadcasts()
+	if len(chats) != 1 || chats[0].Channel != 9 || !strings.Contains(chats[0].Msg, "&Ganhador&") {
+		t.Errorf("anúncios = %+v", chats)
+	}
+}
+
+func TestSorteioReproduzCaptura(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	for i := 1; i <= 5; i++ {
+		srv.AddRole(pwapi.RoleBase{ID: i * 1024, Name: "Jogador", UserID: pwapi.UserID(i * 16)}, pwapi.RoleStatus{Level: 100 + i, Level2: 20})
+	}
+
+	cfg := pwapi.Config{
+		QuantidadeDeSorteados: 3,
+		GmReceber:             true,
+		LevelMinimo:           102,
+		Moedas:                []int{100, 200},
+		Golds:                 []int{5},
+	}
+
+	// Grava um sorteio contra o servidor falso
+	var captura bytes.Buffer
+	recorder := pwapi.NewRecorder(&captura)
+	gold := pwapi.WithGold(pwapi.GoldConfig{Intervalo: 10 * time.Millisecond})
+	client, err := srv.NewClient(pwapi.WithRecorder(recorder), gold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	s := sorteio{client: client, cfg: cfg, rng: rand.New(rand.NewSource(7)), log: log.New(io.Discard, "", 0)}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("executar: %v", err)
+	}
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reproduz o mesmo sorteio sem acessar a rede
+	replayer, err := pwapi.NewReplayer(&captura)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline, err := pwapi.NewClient(pwapi.WithReplayer(replayer), gold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saida bytes.Buffer
+	s = sorteio{client: offline, cfg: cfg, rng: rand.New(rand.NewSource(7)), log: log.New(&saida, "", 0)}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("reprodução: %v", err)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("frames da captura não reproduzidos: %+v", unused)
+	}
+	if n := strings.Count(saida.String(), "acabou de ganhar"); n != 3 {
+		t.Errorf("reprodução anunciou %d prêmios, esperado 3:\n%s", n, saida.String())
+	}
+}
+
+func TestSorteioInterrompeComGamedbdFora(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Level: 105, Level2: 22})
+
+	client, err := srv.NewClient(pwapi.WithRetry(pwapi.RetryConfig{Tentativas: 2, EsperaInicial: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// O gamedbd aceita conexões mas encerra todas sem responder, como durante um reinício
+	srv.FailNext("gamedbd", 100)
+
+	s := sorteio{
+		client: client,
+		cfg:    pwapi.Config{QuantidadeDeSorteados: 1, GmReceber: true, Moedas: []int{500}},
+		rng:    rand.New(rand.NewSource(1)),
+		log:    log.New(io.Discard, "", 0),
+	}
+	err = s.executar(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "gamedbd") {
+		t.Fatalf("executar = %v, esperado erro do gamedbd", err)
+	}
+	if len(srv.Mails()) != 0 || len(srv.CashAdds()) != 0 {
+		t.Errorf("prêmio entregue sem os dados do personagem: %+v %+v", srv.Mails(), srv.CashAdds())
+	}
+}
+
+func TestSorteioFiltraAtributos(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	fraco := pwapi.EncodeRoleProperty(pwapi.RoleProperty{MaxHP: 20000, AttackDegree: 2})
+	forte := pwapi.EncodeRoleProperty(pwapi.RoleProperty{MaxHP: 20000, AttackDegree: 10})
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fraco", UserID: 32}, pwapi.RoleStatus{Level: 105, Level2: 22, Property: fraco})
+	srv.AddRole(pwapi.RoleBase{ID: 2048, Name: "Forte", UserID: 48}, pwapi.RoleStatus{Level: 105, Level2: 22, Property: forte})
+
+	s := sorteio{
+		client: client,
+		cfg: pwapi.Config{
+			QuantidadeDeSorteados: 2,
+			GmReceber:             true,
+			AtributosMinimos:      pwapi.AtributosMinimos{HP: 15000, NivelAtaque: 8},
+			CanalMensagem:         9,
+			Moedas:                []int{500},
+		},
+		rng: rand.New(rand.NewSource(1)),
+		log: log.New(io.Discard, "", 0),
+	}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("executar: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Wait(ctx, func() bool { return len(srv.Mails()) >= 1 }); err != nil {
+		t.Fatalf("prêmio não recebido: %v", err)
+	}
+	if mails := srv.Mails(); len(mails) != 1 || mails[0].Receiver.RoleID != 2048 {
+		t.Fatalf("e-mails = %+v, esperado apenas 2048", mails)
+	}
+
+}
+
+func TestSorteioDescartaPropertyCurto(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Um Property menor que RolePropertyBaseSize torna apenas este personagem inelegível
+	forte := pwapi.EncodeRoleProperty(pwapi.RoleProperty{MaxHP: 20000, AttackDegree: 10})
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Curto", UserID: 32}, pwapi.RoleStatus{Level: 105, Level2: 22, Property: []byte{1, 2, 3}})
+	srv.AddRole(pwapi.RoleBase{ID: 2048, Name: "Forte", UserID: 48}, pwapi.RoleStatus{Level: 105, Level2: 22, Property: forte})
+
+	var registro strings.Builder
+	s := sorteio{
+		client: client,
+		cfg: pwapi.Config{
+			QuantidadeDeSorteados: 2,
+			GmReceber:             true,
+			AtributosMinimos:      pwapi.AtributosMinimos{HP: 15000},
+			CanalMensagem:         9,
+			Moedas:                []int{500},
+		},
+		rng: rand.New(rand.NewSource(1)),
+		log: log.New(&registro, "", 0),
+	}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("executar com Property curto: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Wait(ctx, func() bool { return len(srv.Mails()) >= 1 }); err != nil {
+		t.Fatalf("prêmio não recebido: %v", err)
+	}
+	if mails := srv.Mails(); len(mails) != 1 || mails[0].Receiver.RoleID != 2048 {
+		t.Errorf("e-mails = %+v, esperado apenas 2048", mails)
+	}
+	if !strings.Contains(registro.String(), "Personagem 1024 da conta 32 descartado") {
+		t.Errorf("log = %q, esperado o descarte do personagem 1024", registro.String())
+	}
+}
+
+func TestSorteioRegistraEntregaRecusada(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Cheio", UserID: 32}, pwapi.RoleStatus{Level: 105, Level2: 22})
+	srv.AddRole(pwapi.RoleBase{ID: 2048, Name: "Livre", UserID: 48}, pwapi.RoleStatus{Level: 105, Level2: 22})
+	srv.SetMailRetCode(pwapi.RoleID{RoleID: 1024}, pwapi.RetCodeMailboxFull)
+
+	var registro bytes.Buffer
+	s := sorteio{
+		client: client,
+		cfg: pwapi.Config{
+			QuantidadeDeSorteados: 2,
+			GmReceber:             true,
+			CanalMensagem:         9,
+			Moedas:                []int{500},
+		},
+		rng: rand.New(rand.NewSource(1)),
+		log: log.New(&registro, "", 0),
+	}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("executar: %v", err)
+	}
+
+	if len(s.falhas) != 1 || s.falhas[0].ganhador.RoleID.RoleID != 1024 || !errors.Is(s.falhas[0].err, pwapi.ErrMailboxFull) {
+		t.Fatalf("falhas = %+v, esperado a caixa cheia de 1024", s.falhas)
+	}
+	if !strings.Contains(registro.String(), "caixa de correio cheia") {
+		t.Errorf("log sem o motivo da falha:\n%s", registro.String())
+	}
+
+	// O ganhador sem a entrega confirmada não é anunciado
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Wait(ctx, func() bool { return len(srv.Broadcasts()) >= 1 }); err != nil {
+		t.Fatalf("anúncio não recebido: %v", err)
+	}
+	if chats := srv.Broadcasts(); len(chats) != 1 || strings.Contains(chats[0].Msg, "Cheio") {
+		t.Errorf("anúncios = %+v, esperado apenas o ganhador Livre", chats)
+	}
+}
+
+func TestSorteioPremioComEntregaConfigurada(t *testing.T) {
+	srv, err := pwtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	srv.AddRole(pwapi.RoleBase{ID: 1024, Name: "Fulano", UserID: 32}, pwapi.RoleStatus{Level: 105, Level2: 22})
+
+	saida := filepath.Join(t.TempDir(), "vip.json")
+	s := sorteio{
+		client: client,
+		cfg: pwapi.Config{
+			QuantidadeDeSorteados: 1,
+			GmReceber:             true,
+			CanalMensagem:         9,
+			Premios: []pwapi.PremioConfig{{
+				Nome:    "VIP de 7 dias",
+				Entrega: pwapi.RewardCommand,
+				Comando: []string{"sh", "-c", `cat > "$1"`, "sh", saida},
+			}},
+		},
+		rng: rand.New(rand.NewSource(1)),
+		log: log.New(io.Discard, "", 0),
+	}
+	if err := s.executar(context.Background()); err != nil {
+		t.Fatalf("executar: %v", err)
+	}
+
+	data, err := os.ReadFile(saida)
+	if err != nil {
+		t.Fatalf("comando do prêmio não executado: %v", err)
+	}
+	if !strings.Contains(string(data), `"roleid":1024`) || !strings.Contains(string(data), `"premio":"VIP de 7 dias"`) {
+		t.Errorf("entrada do comando = %s", data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Wait(ctx, func() bool { return len(srv.Broadcasts()) >= 1 }); err != nil {
+		t.Fatalf("anúncio não recebido: %v", err)
+	}
+	if chats := srv.Broadcasts(); !strings.HasSuffix(chats[0].Msg, "^33cc33 VIP de 7 dias") {
+		t.Errorf("anúncio = %q", chats[0].Msg)
+	}
+
+	// Um prêmio com entrega desconhecida interrompe o sorteio antes de existir um ganhador
+	s.cfg.Premios[0].Entrega = "vip"
+	if err := s.executar(context.Background()); err == nil || !strings.Contains(err.Error(), "vip") {
+		t.Errorf("executar com entrega desconhecida: %v", err)
+	}
+}